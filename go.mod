@@ -10,6 +10,9 @@ require (
 	github.com/spf13/cobra v1.8.0
 	github.com/stretchr/testify v1.7.1
 	github.com/yuin/goldmark v1.6.0
+	golang.org/x/sys v0.8.0
+	golang.org/x/term v0.8.0
+	gopkg.in/yaml.v3 v3.0.1
 	mvdan.cc/sh/v3 v3.7.0
 )
 
@@ -22,7 +25,4 @@ require (
 	github.com/russross/blackfriday/v2 v2.1.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 	golang.org/x/sync v0.2.0 // indirect
-	golang.org/x/sys v0.8.0 // indirect
-	golang.org/x/term v0.8.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/spf13/cobra"
+)
+
+//go:embed help/approve.md
+var approveHelp string
+
+const approvedFilename = ".mdcode-approved"
+
+func approveCmd(opts *options) *cobra.Command {
+	cmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "approve [flags] [filename]",
+		Short: "Record reviewed code blocks as approved for execution",
+		Long:  approveHelp,
+		Args:  checkargs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			opts.createStatus(cmd.ErrOrStderr())
+
+			return opts.allBlocksFilter(cmd)
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			files, err := sources(args, opts)
+			if err != nil {
+				return err
+			}
+
+			approved, err := loadExecCache(approvedFilename)
+			if err != nil {
+				return err
+			}
+
+			err = opts.eachSource(files, func(file string) error {
+				return approveRun(file, approved, opts)
+			})
+			if err != nil {
+				return err
+			}
+
+			return approved.save(approvedFilename)
+		},
+
+		DisableAutoGenTag: true,
+	}
+
+	quietFlag(cmd, opts)
+
+	return cmd
+}
+
+// approvalKey identifies the reviewed content of the code block: its code,
+// language, file and the command given in its metadata.
+func approvalKey(block *mdcode.Block) string {
+	return newExecCache().key(block.Meta.Get(metaCmd), block)
+}
+
+func approveRun(filename string, approved *execCache, opts *options) error {
+	opts.status("Approving code blocks of %s\n", filename)
+
+	src, _, err := opts.readDocument(filename)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = walk(src, func(block *mdcode.Block) error {
+		key := approvalKey(block)
+		if approved.has(key) {
+			return nil
+		}
+
+		approved.add(key)
+
+		opts.status("%s: %s code block approved\n", opts.location(filename, block.StartLine), langLabel(block.Lang))
+		opts.event("block approved", "document", filename, "line", block.StartLine, "hash", key)
+
+		return nil
+	}, opts.filter)
+
+	return err
+}
+
+// enforceApproval reports the code blocks to be executed (setup and teardown
+// code blocks included) missing from the approved file, and refuses to
+// execute the markdown document if there is any.
+func (e *execOptions) enforceApproval(filename string, src []byte, opts *options) error {
+	if e.approved == nil {
+		return nil
+	}
+
+	var count int
+
+	_, _, err := mdcode.Walk(src, func(block *mdcode.Block) error {
+		switch {
+		case len(block.Meta.Get(metaRole)) != 0:
+			// Like mdcode approve does, the included code is checked.
+			if err := opts.resolveRole(block); err != nil {
+				return err
+			}
+		case !opts.filter(block):
+			return nil
+		}
+
+		if e.approved.has(approvalKey(block)) {
+			return nil
+		}
+
+		count++
+
+		fmt.Fprintf(opts.stderr, "%s: %s code block not approved\n", opts.location(filename, block.StartLine), langLabel(block.Lang))
+		opts.event("block not approved", "document", filename, "line", block.StartLine)
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if count != 0 {
+		return fmt.Errorf("%w: %d code block(s), review them and run mdcode approve", errNotApproved, count)
+	}
+
+	return nil
+}
+
+var errNotApproved = errors.New("code blocks not approved")
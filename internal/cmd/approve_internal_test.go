@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_enforceApproval_role_include(t *testing.T) {
+	dir := t.TempDir()
+
+	common := "```sh name=setup\necho setup > \"$HOME/setup.txt\"\n```\n"
+	src := "```sh role=setup include=common.md#setup\n```\n\n```sh\necho step\n```\n"
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "common.md"), []byte(common), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "doc.md"), []byte(src), 0o600))
+
+	out, err := runMdcode(t, dir, "approve", "doc.md")
+	require.NoError(t, err, out)
+
+	out, err = runMdcode(t, dir, "exec", "--no-history", "--yes", "--only-approved", "doc.md")
+	require.NoError(t, err, out)
+	require.FileExists(t, filepath.Join(dir, "setup.txt"))
+
+	// Changing the included setup code invalidates the approval.
+	common = "```sh name=setup\necho changed > \"$HOME/setup.txt\"\n```\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "common.md"), []byte(common), 0o600))
+
+	out, err = runMdcode(t, dir, "exec", "--no-history", "--yes", "--only-approved", "doc.md")
+	require.ErrorIs(t, err, errNotApproved, out)
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+)
+
+// Metadata keys added to the listed code blocks by the --git flag.
+const (
+	metaGitCommit = "git-commit"
+	metaGitAuthor = "git-author"
+	metaGitDate   = "git-date"
+)
+
+const (
+	shortCommit = 7
+	hashLength  = 40
+)
+
+// blameCommit is the commit of a blamed line.
+type blameCommit struct {
+	hash   string
+	author string
+	time   int64
+}
+
+// blameLines returns the last commit of each line (starting from 1) of the
+// markdown document filename using git blame. Untracked documents have no
+// commits.
+func blameLines(filename string) ([]*blameCommit, error) {
+	untracked, err := gitOutput("ls-files", "--others", "--exclude-standard", "--", filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bytes.TrimSpace(untracked)) != 0 {
+		return nil, nil
+	}
+
+	out, err := gitOutput("blame", "--porcelain", "--", filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		lines   = []*blameCommit{nil}
+		commits = make(map[string]*blameCommit)
+		current *blameCommit
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(nil, len(out)+1)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case strings.HasPrefix(line, "\t"):
+			lines = append(lines, current)
+		case current != nil && strings.HasPrefix(line, "author "):
+			current.author = strings.TrimPrefix(line, "author ")
+		case current != nil && strings.HasPrefix(line, "author-time "):
+			current.time, _ = strconv.ParseInt(strings.TrimPrefix(line, "author-time "), 10, 64)
+		default:
+			fields := strings.Fields(line)
+			if len(fields) < 3 || len(fields[0]) < hashLength || !isHex(fields[0]) { //nolint:gomnd
+				continue
+			}
+
+			if commits[fields[0]] == nil {
+				commits[fields[0]] = &blameCommit{hash: fields[0]} //nolint:exhaustruct
+			}
+
+			current = commits[fields[0]]
+		}
+	}
+
+	return lines, scanner.Err()
+}
+
+func isHex(s string) bool {
+	return strings.Trim(s, "0123456789abcdef") == ""
+}
+
+// addBlame adds the most recent commit of the lines of the code block
+// (including the fences) to its metadata.
+func addBlame(block *mdcode.Block, lines []*blameCommit) {
+	var last *blameCommit
+
+	for line := block.StartLine; line <= block.EndLine+1 && line < len(lines); line++ {
+		if commit := lines[line]; commit != nil && (last == nil || commit.time > last.time) {
+			last = commit
+		}
+	}
+
+	if last == nil {
+		return
+	}
+
+	if block.Meta == nil {
+		block.Meta = make(mdcode.Meta)
+	}
+
+	block.Meta[metaGitCommit] = last.hash[:shortCommit]
+	block.Meta[metaGitAuthor] = last.author
+	block.Meta[metaGitDate] = time.Unix(last.time, 0).UTC().Format(time.DateOnly)
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+)
+
+const (
+	metaID = "id"
+
+	// idLength is the number of hex digits of a block identifier.
+	idLength = 6
+	// idPrefixSize is the size of the normalized code prefix hashed into the
+	// block identifier, edits below it don't change the identifier.
+	idPrefixSize = 128
+)
+
+// blockID returns the stable identifier of the code block: its id metadata,
+// or the hash of its heading path, its language and the start of its code
+// with normalized whitespace. Unlike the line numbers, it survives edits
+// elsewhere in the document.
+func blockID(block *mdcode.Block) string {
+	if id := block.Meta.Get(metaID); len(id) != 0 {
+		return id
+	}
+
+	hash := sha256.New()
+
+	for _, heading := range block.Headings {
+		hash.Write([]byte(heading))
+		hash.Write([]byte{0})
+	}
+
+	hash.Write([]byte{0})
+	hash.Write([]byte(strings.ToLower(block.Lang)))
+	hash.Write([]byte{0})
+	hash.Write(idPrefix(block.Code))
+
+	return hex.EncodeToString(hash.Sum(nil))[:idLength]
+}
+
+// idPrefix returns the start of the code with blank lines dropped and runs of
+// whitespace replaced by a single space, so reindenting or changing the line
+// endings keeps the identifier.
+func idPrefix(code []byte) []byte {
+	var buff bytes.Buffer
+
+	for _, line := range bytes.Split(code, []byte{'\n'}) {
+		fields := bytes.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		buff.Write(bytes.Join(fields, []byte{' '}))
+		buff.WriteByte('\n')
+
+		if buff.Len() >= idPrefixSize {
+			break
+		}
+	}
+
+	data := buff.Bytes()
+	if len(data) > idPrefixSize {
+		data = data[:idPrefixSize]
+	}
+
+	return data
+}
+
+// identified returns the filter restricting the code blocks to the ones with
+// an identifier starting with one of the --id values.
+func identified(filter filterFunc, ids []string) filterFunc {
+	if len(ids) == 0 {
+		return filter
+	}
+
+	return func(block *mdcode.Block) bool {
+		id := blockID(block)
+
+		for _, want := range ids {
+			if len(want) != 0 && strings.HasPrefix(id, want) {
+				return filter(block)
+			}
+		}
+
+		return false
+	}
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+)
+
+const cacheFilename = ".mdcode-cache"
+
+// execCache holds the hashes of the code block and command combinations
+// executed successfully.
+type execCache struct {
+	hashes map[string]struct{}
+	dirty  bool
+}
+
+func newExecCache() *execCache {
+	return &execCache{hashes: make(map[string]struct{}), dirty: false}
+}
+
+func loadExecCache(filename string) (*execCache, error) {
+	cache := newExecCache()
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cache, nil
+		}
+
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); len(line) != 0 {
+			cache.hashes[line] = struct{}{}
+		}
+	}
+
+	return cache, scanner.Err()
+}
+
+func (c *execCache) key(scr string, block *mdcode.Block) string {
+	hash := sha256.New()
+
+	hash.Write([]byte(scr))
+	hash.Write([]byte{0})
+	hash.Write([]byte(block.Lang))
+	hash.Write([]byte{0})
+	hash.Write([]byte(block.Meta.Get(metaFile)))
+	hash.Write([]byte{0})
+	hash.Write(block.Code)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (c *execCache) batchKey(scr string, keys []string) string {
+	hash := sha256.New()
+
+	hash.Write([]byte(scr))
+
+	for _, key := range keys {
+		hash.Write([]byte{0})
+		hash.Write([]byte(key))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (c *execCache) has(key string) bool {
+	_, ok := c.hashes[key]
+
+	return ok
+}
+
+func (c *execCache) add(key string) {
+	if !c.has(key) {
+		c.hashes[key] = struct{}{}
+		c.dirty = true
+	}
+}
+
+func (c *execCache) save(filename string) error {
+	if !c.dirty {
+		return nil
+	}
+
+	keys := make([]string, 0, len(c.hashes))
+	for key := range c.hashes {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return os.WriteFile(filename, []byte(strings.Join(keys, "\n")+"\n"), fileMode)
+}
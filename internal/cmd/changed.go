@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+)
+
+// changedDefault is the git revision compared to when --changed is given
+// without value.
+const changedDefault = "HEAD"
+
+// lineRange is an inclusive range of document lines.
+type lineRange struct {
+	from, to int
+}
+
+// changedLines are the lines of a markdown document changed since the
+// --changed git revision. A nil value means every line is considered changed
+// (the document is not known to git).
+type changedLines []lineRange
+
+// overlaps reports whether a changed line falls in the from-to range.
+func (c changedLines) overlaps(from, to int) bool {
+	if c == nil {
+		return true
+	}
+
+	for _, r := range c {
+		if r.from <= to && from <= r.to {
+			return true
+		}
+	}
+
+	return false
+}
+
+var reHunk = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+
+// gitChanges returns the lines of the markdown document filename changed
+// since the git revision ref, according to git diff. Untracked documents are
+// considered changed entirely.
+func gitChanges(filename string, ref string) (changedLines, error) {
+	out, err := gitOutput("diff", "--no-color", "--no-ext-diff", "--unified=0", ref, "--", filename)
+	if err != nil {
+		return nil, err
+	}
+
+	untracked, err := gitOutput("ls-files", "--others", "--exclude-standard", "--", filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bytes.TrimSpace(untracked)) != 0 {
+		return nil, nil
+	}
+
+	changed := changedLines{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		match := reHunk.FindSubmatch(scanner.Bytes())
+		if match == nil {
+			continue
+		}
+
+		start, _ := strconv.Atoi(string(match[1]))
+
+		count := 1
+		if len(match[2]) != 0 {
+			count, _ = strconv.Atoi(string(match[2]))
+		}
+
+		// A pure deletion touches the lines around the removed text.
+		if count == 0 {
+			changed = append(changed, lineRange{from: start, to: start + 1})
+
+			continue
+		}
+
+		changed = append(changed, lineRange{from: start, to: start + count - 1})
+	}
+
+	return changed, scanner.Err()
+}
+
+// loadChanges computes the changed lines of the markdown document filename
+// for the --changed filter. Remote documents are not restricted.
+func (o *options) loadChanges(filename string) error {
+	o.changes = nil
+
+	if len(o.changed) == 0 || isURL(filename) {
+		return nil
+	}
+
+	changes, err := gitChanges(filename, o.changed)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errChanged, err)
+	}
+
+	o.changes = changes
+
+	return nil
+}
+
+// changedOnly returns the filter dropping the code blocks not overlapping
+// the changed lines of the current document, if --changed is given.
+func changedOnly(filter filterFunc, opts *options) filterFunc {
+	if len(opts.changed) == 0 {
+		return filter
+	}
+
+	return func(block *mdcode.Block) bool {
+		return opts.changes.overlaps(block.StartLine, block.EndLine+1) && filter(block)
+	}
+}
+
+var errChanged = errors.New("cannot determine changed lines")
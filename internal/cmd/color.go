@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// palette colors status messages with ANSI escape sequences, if enabled.
+type palette struct {
+	enabled bool
+}
+
+// newPalette creates the palette for the --color mode. In auto mode, colors
+// are used when out is a terminal and the NO_COLOR environment variable is
+// not set.
+func newPalette(mode string, out io.Writer) (palette, error) {
+	switch mode {
+	case colorAlways:
+		return palette{enabled: true}, nil
+	case colorNever:
+		return palette{enabled: false}, nil
+	case colorAuto, "":
+		_, noColor := os.LookupEnv("NO_COLOR")
+
+		return palette{enabled: !noColor && isTerminal(out)}, nil
+	}
+
+	return palette{enabled: false}, fmt.Errorf("%w: %s", errColorMode, mode)
+}
+
+func (p palette) paint(code, text string) string {
+	if !p.enabled {
+		return text
+	}
+
+	return "\033[" + code + "m" + text + "\033[0m"
+}
+
+func (p palette) header(text string) string {
+	return p.paint("1;36", text)
+}
+
+func (p palette) ok(text string) string {
+	return p.paint("32", text)
+}
+
+func (p palette) fail(text string) string {
+	return p.paint("31", text)
+}
+
+func (p palette) warn(text string) string {
+	return p.paint("33", text)
+}
+
+var errColorMode = errors.New("invalid color mode (use auto, always or never)")
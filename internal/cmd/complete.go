@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/ezerfernandes/mdcode/internal/textenc"
+	"github.com/spf13/cobra"
+)
+
+type completeFunc func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+// registerCompletions registers the completion of the filter flag values
+// from the code blocks of the markdown document of the command line.
+func registerCompletions(cmd *cobra.Command) {
+	values := map[string]func(blocks mdcode.Blocks) []string{
+		"lang":    completeLangs,
+		"file":    metaValues(metaFile),
+		"group":   metaValues(metaGroup),
+		"tags":    completeTags,
+		"id":      completeIDs,
+		"heading": completeHeadings,
+	}
+
+	for name, fn := range values {
+		cobra.CheckErr(cmd.RegisterFlagCompletionFunc(name, completeList(fn)))
+	}
+
+	cobra.CheckErr(cmd.RegisterFlagCompletionFunc("skip-tags", completeList(completeTags)))
+	cobra.CheckErr(cmd.RegisterFlagCompletionFunc("meta", completeMeta))
+}
+
+// completeBlocks completes the (1-based) number of a code block of the
+// document, described by its language and first line.
+func completeBlocks(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	blocks := completionBlocks(cmd, args)
+	values := make([]string, 0, len(blocks))
+
+	for idx, block := range blocks {
+		if block.Virtual {
+			continue
+		}
+
+		values = append(values, fmt.Sprintf("%d\t%s: %s", idx+1, langLabel(block.Lang), codeSummary(block.Code)))
+	}
+
+	return values, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+}
+
+// completeList returns the completion of a comma separated list flag: the
+// values before the last comma are kept, and not offered again.
+func completeList(fn func(blocks mdcode.Blocks) []string) completeFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		prefix := toComplete[:strings.LastIndex(toComplete, ",")+1]
+
+		given := make(map[string]struct{})
+		for _, value := range strings.Split(prefix, ",") {
+			given[value] = struct{}{}
+		}
+
+		var values []string
+
+		for _, value := range fn(completionBlocks(cmd, args)) {
+			name, _, _ := strings.Cut(value, "\t")
+			if _, has := given[name]; !has {
+				values = append(values, prefix+value)
+			}
+		}
+
+		return values, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeMeta completes the metadata keys (followed by =), or the values of
+// the key before the =.
+func completeMeta(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	blocks := completionBlocks(cmd, args)
+
+	key, _, found := strings.Cut(toComplete, "=")
+	if !found {
+		keys := metaKeys(blocks)
+		for idx, key := range keys {
+			keys[idx] = key + "="
+		}
+
+		return keys, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+	}
+
+	values := metaValues(key)(blocks)
+	for idx, value := range values {
+		values[idx] = key + "=" + value
+	}
+
+	return values, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completionBlocks returns the code blocks of the document of the command
+// line: the first markdown file argument, or the default document. Errors
+// are ignored, there is nothing to complete then.
+func completionBlocks(cmd *cobra.Command, args []string) mdcode.Blocks {
+	document := ""
+
+	for _, arg := range args {
+		if info, err := os.Stat(arg); err == nil && info.Mode().IsRegular() && isMarkdown(arg) {
+			document = arg
+
+			break
+		}
+	}
+
+	if len(document) == 0 {
+		patterns, err := cmd.Flags().GetStringSlice("default-document")
+		if err != nil {
+			return nil
+		}
+
+		if document, err = defaultDocument(patterns); err != nil {
+			return nil
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Clean(document))
+	if err != nil {
+		return nil
+	}
+
+	src, _, err := textenc.Decode(data, "")
+	if err != nil {
+		return nil
+	}
+
+	var blocks mdcode.Blocks
+
+	_, _, _ = mdcode.Walk(src, func(block *mdcode.Block) error {
+		blocks = append(blocks, block)
+
+		return nil
+	})
+
+	return blocks
+}
+
+func isMarkdown(filename string) bool {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".md", ".markdown":
+		return true
+	default:
+		return false
+	}
+}
+
+func completeLangs(blocks mdcode.Blocks) []string {
+	return distinct(blocks, func(block *mdcode.Block) []string {
+		return []string{block.Lang}
+	})
+}
+
+func metaValues(key string) func(blocks mdcode.Blocks) []string {
+	return func(blocks mdcode.Blocks) []string {
+		return distinct(blocks, func(block *mdcode.Block) []string {
+			if _, has := block.Meta[key]; !has {
+				return nil
+			}
+
+			return []string{block.Meta.Get(key)}
+		})
+	}
+}
+
+func completeTags(blocks mdcode.Blocks) []string {
+	return distinct(blocks, func(block *mdcode.Block) []string {
+		return sortedSet(blockTags(block))
+	})
+}
+
+func completeIDs(blocks mdcode.Blocks) []string {
+	ids := make([]string, 0, len(blocks))
+
+	for _, block := range blocks {
+		if !block.Virtual {
+			ids = append(ids, blockID(block)+"\t"+langLabel(block.Lang)+": "+codeSummary(block.Code))
+		}
+	}
+
+	return ids
+}
+
+func completeHeadings(blocks mdcode.Blocks) []string {
+	return distinct(blocks, func(block *mdcode.Block) []string {
+		return block.Headings
+	})
+}
+
+// distinct returns the sorted, distinct, non-empty values of the code
+// blocks.
+func distinct(blocks mdcode.Blocks, fn func(block *mdcode.Block) []string) []string {
+	set := make(map[string]struct{})
+
+	for _, block := range blocks {
+		for _, value := range fn(block) {
+			if len(value) != 0 {
+				set[value] = struct{}{}
+			}
+		}
+	}
+
+	return sortedSet(set)
+}
+
+func sortedSet(set map[string]struct{}) []string {
+	values := make([]string, 0, len(set))
+
+	for value := range set {
+		values = append(values, value)
+	}
+
+	sort.Strings(values)
+
+	return values
+}
+
+// completeShowArgs completes the code block argument of the show command,
+// and the markdown document after it.
+func completeShowArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) != 0 {
+		return []string{"md", "markdown"}, cobra.ShellCompDirectiveFilterFileExt
+	}
+
+	if _, err := strconv.Atoi(toComplete); err == nil || len(toComplete) == 0 {
+		return completeBlocks(cmd, args, toComplete)
+	}
+
+	return completeIDs(completionBlocks(cmd, args)), cobra.ShellCompDirectiveNoFileComp
+}
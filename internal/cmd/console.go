@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+)
+
+const (
+	langConsole = "console"
+
+	promptCommand      = "$ "
+	promptContinuation = "> "
+)
+
+// transcriptStep is a command of a shell session transcript along with its
+// expected output. The line is relative to the start of the code block.
+type transcriptStep struct {
+	line    int
+	command string
+	output  []byte
+}
+
+// parseTranscript splits the code of a console code block into commands,
+// prefixed by a "$ " prompt (continued on lines prefixed by "> "), and their
+// expected output: the lines up to the next command.
+func parseTranscript(code []byte) []*transcriptStep {
+	var (
+		steps []*transcriptStep
+		step  *transcriptStep
+		cont  bool
+	)
+
+	for idx, line := range strings.SplitAfter(string(code), "\n") {
+		text := strings.TrimRight(line, "\r\n")
+
+		switch {
+		case strings.HasPrefix(text, promptCommand) || text == strings.TrimSpace(promptCommand):
+			step = &transcriptStep{line: idx + 1, command: strings.TrimPrefix(text, promptCommand), output: nil}
+			steps = append(steps, step)
+			cont = true
+		case cont && strings.HasPrefix(text, promptContinuation):
+			step.command += "\n" + strings.TrimPrefix(text, promptContinuation)
+		case step != nil:
+			step.output = append(step.output, line...)
+			cont = false
+		}
+	}
+
+	return steps
+}
+
+// runTranscript executes the commands of a console code block one after the
+// other and compares their output with the expected output of the
+// transcript. Commands without expected output are not verified.
+func (e *execOptions) runTranscript(info *blockInfo, status statusFunc) (int, error) {
+	code, err := os.ReadFile(info.tempPath)
+	if err != nil {
+		return -1, err
+	}
+
+	return e.limitOutput(status, func(stdout, stderr io.Writer) (int, error) {
+		for _, step := range parseTranscript(code) {
+			line := info.startLine + step.line
+
+			var output syncBuffer
+
+			exitCode, err := e.execute(step.command, info.dir, io.MultiWriter(stdout, &output), io.MultiWriter(stderr, &output))
+			if err != nil || exitCode != 0 {
+				if err == nil {
+					fmt.Fprintf(stderr, "line %d: command exited with %d\n", line, exitCode)
+				}
+
+				return exitCode, err
+			}
+
+			if len(step.output) != 0 && !sameCode(step.output, output.buff.Bytes(), consoleNormalize) {
+				fmt.Fprintf(stderr, "line %d: unexpected output of %s\n--- expected\n%s--- actual\n%s",
+					line, step.command, withNewline(step.output), withNewline(output.buff.Bytes()))
+
+				return exitMismatch, nil
+			}
+		}
+
+		return 0, nil
+	})
+}
+
+//nolint:gochecknoglobals
+var consoleNormalize = []string{normalizeEOL, normalizeSpace, normalizeNewline}
+
+// exitMismatch is the exit status of a console code block whose output
+// differs from the transcript.
+const exitMismatch = 1
+
+func withNewline(text []byte) []byte {
+	if len(text) != 0 && !bytes.HasSuffix(text, []byte("\n")) {
+		return append(bytes.Clone(text), '\n')
+	}
+
+	return text
+}
+
+func isConsole(lang string) bool {
+	return lang == langConsole
+}
+
+// consoleBlocks restricts the filter to the console code blocks and the code
+// blocks with their own command, when no command is given with --console.
+func (o *options) consoleBlocks() {
+	filter := o.filter
+
+	o.filter = func(block *mdcode.Block) bool {
+		return (isConsole(o.aliases.canonical(block.Lang)) || len(block.Meta.Get(metaCmd)) != 0) && filter(block)
+	}
+}
+
+var errConsole = errors.New("--console can't be used with --batch")
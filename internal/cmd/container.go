@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// containerAuto is the --container value picking the image of each code
+// block by its language.
+const containerAuto = "auto"
+
+const (
+	engineDocker = "docker"
+	enginePodman = "podman"
+)
+
+// containerFallback is the image of the languages without a default image.
+const containerFallback = "debian:stable-slim"
+
+// containerImages maps the canonical languages to their default container
+// images.
+//
+//nolint:gochecknoglobals
+var containerImages = map[string]string{
+	"sh":     "bash:5",
+	"go":     "golang:1.23",
+	"python": "python:3.12",
+	"js":     "node:22",
+	"ts":     "denoland/deno:2",
+	"ruby":   "ruby:3.3",
+	"perl":   "perl:5",
+	"php":    "php:8.3-cli",
+	"lua":    "nickblah/lua:5.4",
+	"rust":   "rust:1",
+	"java":   "eclipse-temurin:21",
+}
+
+// container runs the commands of the code blocks in containers of the image
+// of their language, mounting the current and the temporary directories at
+// the same paths.
+type container struct {
+	engine  string
+	image   string
+	images  map[string]string
+	offline bool
+}
+
+// newContainer returns the container of the --container flags, the images
+// override the default images of the (canonical) languages.
+func newContainer(engine, image string, images map[string]string, offline bool, aliases langAliases) (*container, error) {
+	if engine != engineDocker && engine != enginePodman {
+		return nil, fmt.Errorf("%w: unknown engine %s", errContainer, engine)
+	}
+
+	if len(image) == 0 {
+		return nil, fmt.Errorf("%w: empty image", errContainer)
+	}
+
+	merged := make(map[string]string, len(containerImages)+len(images))
+
+	for lang, img := range containerImages {
+		merged[lang] = img
+	}
+
+	for lang, img := range images {
+		if len(img) == 0 {
+			return nil, fmt.Errorf("%w: empty image of %s", errContainer, lang)
+		}
+
+		merged[aliases.canonical(lang)] = img
+	}
+
+	return &container{engine: engine, image: image, images: merged, offline: offline}, nil
+}
+
+// imageOf returns the image of the canonical language: the --container image,
+// or the image of the language with --container=auto.
+func (c *container) imageOf(lang string) string {
+	if c.image != containerAuto {
+		return c.image
+	}
+
+	if image, has := c.images[lang]; has {
+		return image
+	}
+
+	if image, has := c.images["*"]; has {
+		return image
+	}
+
+	return containerFallback
+}
+
+// args returns the command line running the command in dir in a container
+// of the image of the language. The files are created by the current user,
+// so they can be removed with the temporary directory.
+func (c *container) args(lang, command, dir string) ([]string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{c.engine, "run", "--rm", "-i", "-v", cwd + ":" + cwd}
+
+	if rel, err := filepath.Rel(cwd, dir); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		args = append(args, "-v", dir+":"+dir)
+	}
+
+	if c.offline {
+		args = append(args, "--network", "none")
+	}
+
+	if uid, gid := os.Getuid(), os.Getgid(); uid >= 0 && c.engine == engineDocker {
+		args = append(args, "--user", strconv.Itoa(uid)+":"+strconv.Itoa(gid), "-e", "HOME=/tmp")
+	}
+
+	return append(args, "-w", dir, c.imageOf(lang), "sh", "-c", command), nil
+}
+
+// run runs the command of the code block in its container.
+func (c *container) run(lang, command, dir string, stdout, stderr io.Writer) (int, error) {
+	args, err := c.args(lang, command, dir)
+	if err != nil {
+		return -1, err
+	}
+
+	return runProgram(args, dir, os.Stdin, stdout, stderr)
+}
+
+var errContainer = errors.New("invalid container")
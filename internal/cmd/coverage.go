@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/spf13/cobra"
+)
+
+//go:embed help/coverage.md
+var coverageHelp string
+
+const coverageFilename = ".mdcode-coverage"
+
+func coverageCmd(opts *options) *cobra.Command {
+	var minimum float64
+
+	cmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "coverage [flags] [filename]",
+		Short: "Report code blocks never executed by mdcode exec",
+		Long:  coverageHelp,
+		Args:  checkargs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			opts.createStatus(cmd.ErrOrStderr())
+
+			if err := opts.allBlocksFilter(cmd); err != nil {
+				return err
+			}
+
+			opts.excludeRoles()
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files, err := sources(args, opts)
+			if err != nil {
+				return err
+			}
+
+			return coverageRun(files, cmd.OutOrStdout(), opts, minimum)
+		},
+
+		DisableAutoGenTag: true,
+	}
+
+	quietFlag(cmd, opts)
+
+	cmd.Flags().Float64Var(&minimum, "min", 0, "minimum coverage percentage, lower coverage is an error")
+
+	return cmd
+}
+
+// coverageKey identifies the code block in the coverage file. Like the
+// execution cache, it only depends on the content of the code block, so
+// moving a code block doesn't lose its coverage.
+func coverageKey(block *mdcode.Block) string {
+	return newExecCache().key("", block)
+}
+
+// cover records the successful execution of the code block with --coverage.
+func (e *execOptions) cover(block *mdcode.Block) {
+	if e.coverage != nil {
+		e.coverage.add(coverageKey(block))
+	}
+}
+
+func coverageRun(files []string, out io.Writer, opts *options, minimum float64) error {
+	covered, err := loadExecCache(coverageFilename)
+	if err != nil {
+		return err
+	}
+
+	var total, executed int
+
+	for _, file := range files {
+		opts.status("Checking coverage of %s\n", file)
+
+		src, _, err := opts.readDocument(file)
+		if err != nil {
+			return err
+		}
+
+		_, _, err = walk(src, func(block *mdcode.Block) error {
+			total++
+
+			if covered.has(coverageKey(block)) {
+				executed++
+
+				return nil
+			}
+
+			fmt.Fprintf(out, "%s: %s code block never executed\n", opts.location(file, block.StartLine), langLabel(block.Lang))
+
+			return nil
+		}, opts.filter)
+		if err != nil {
+			return err
+		}
+	}
+
+	percent := 100.0
+	if total != 0 {
+		percent = 100 * float64(executed) / float64(total) //nolint:gomnd
+	}
+
+	fmt.Fprintf(out, "coverage: %d of %d code blocks executed (%.1f%%)\n", executed, total, percent)
+
+	if percent < minimum {
+		return fmt.Errorf("%w: %.1f%% < %.1f%%", errCoverage, percent, minimum)
+	}
+
+	return nil
+}
+
+func langLabel(lang string) string {
+	if len(lang) == 0 {
+		return "unnamed"
+	}
+
+	return lang
+}
+
+var errCoverage = errors.New("coverage below minimum")
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/rodaine/table"
+	"github.com/spf13/cobra"
+)
+
+//go:embed help/doctor.md
+var doctorHelp string
+
+func doctorCmd(opts *options) *cobra.Command {
+	cmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "doctor [flags] [filename]",
+		Short: "Check that the tools needed by the code blocks are installed",
+		Long:  doctorHelp,
+		Args:  checkargs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			opts.createStatus(cmd.ErrOrStderr())
+
+			return opts.allBlocksFilter(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files, err := sources(args, opts)
+			if err != nil {
+				return err
+			}
+
+			return doctorRun(files, cmd.OutOrStdout(), opts)
+		},
+
+		DisableAutoGenTag: true,
+	}
+
+	quietFlag(cmd, opts)
+
+	return cmd
+}
+
+// need is a tool needed by code blocks, with the version constraints of
+// their requires metadata.
+type need struct {
+	tool        string
+	constraints map[string]struct{}
+	blocks      int
+}
+
+func doctorRun(files []string, out io.Writer, opts *options) error {
+	needs := make(map[string]*need)
+
+	add := func(tool, constraint string) {
+		if needs[tool] == nil {
+			needs[tool] = &need{tool: tool, constraints: make(map[string]struct{})} //nolint:exhaustruct
+		}
+
+		needs[tool].blocks++
+
+		if len(constraint) != 0 {
+			needs[tool].constraints[constraint] = struct{}{}
+		}
+	}
+
+	for _, file := range files {
+		opts.status("Scanning code blocks in %s\n", file)
+
+		src, _, err := opts.readDocument(file)
+		if err != nil {
+			return err
+		}
+
+		_, _, err = walk(src, func(block *mdcode.Block) error {
+			if tool, has := langTools[opts.aliases.canonical(block.Lang)]; has {
+				add(tool, "")
+			}
+
+			for _, req := range strings.Fields(block.Meta.Get(metaRequires)) {
+				match := reRequirement.FindStringSubmatch(req)
+				if match == nil {
+					return fmt.Errorf("%s: %w: %s", opts.location(file, block.StartLine), errRequirement, req)
+				}
+
+				add(match[1], match[2]+match[3])
+			}
+
+			return nil
+		}, opts.filter)
+		if err != nil {
+			return err
+		}
+	}
+
+	tools := make([]string, 0, len(needs))
+	for tool := range needs {
+		tools = append(tools, tool)
+	}
+
+	sort.Strings(tools)
+
+	tbl := table.New("tool", "required", "blocks", "status").WithWriter(out)
+
+	tbl.WithHeaderFormatter(func(format string, vals ...interface{}) string {
+		return strings.ToUpper(fmt.Sprintf(format, vals...))
+	})
+
+	chain := newToolchain()
+	missing := 0
+
+	for _, tool := range tools {
+		n := needs[tool]
+
+		constraints := make([]string, 0, len(n.constraints))
+		for c := range n.constraints {
+			constraints = append(constraints, c)
+		}
+
+		sort.Strings(constraints)
+
+		status := doctorStatus(chain, tool, constraints)
+		if status != "ok" {
+			missing++
+		}
+
+		tbl.AddRow(tool, strings.Join(constraints, ","), n.blocks, status)
+	}
+
+	tbl.Print()
+
+	if missing != 0 {
+		return fmt.Errorf("%w: %d tool(s)", errDoctor, missing)
+	}
+
+	return nil
+}
+
+// doctorStatus checks the tool against all version constraints.
+func doctorStatus(chain *toolchain, tool string, constraints []string) string {
+	if _, err := exec.LookPath(tool); err != nil {
+		return "missing"
+	}
+
+	for _, constraint := range constraints {
+		if err := chain.check(tool + constraint); err != nil {
+			return fmt.Sprintf("version %s, %s required", chain.version(tool), constraint)
+		}
+	}
+
+	return "ok"
+}
+
+var errDoctor = errors.New("missing or outdated tools")
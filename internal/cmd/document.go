@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ezerfernandes/mdcode/internal/textenc"
+)
+
+// readDocument reads the markdown document filename and decodes it to UTF-8
+// using the --encoding flag (or the byte order mark). The returned format
+// restores the original encoding with writeDocument. If filename is an URL,
+// the document is downloaded. With the --changed flag, the changed lines of
+// the document are loaded for filtering. Documents exceeding the safety
+// limits are refused.
+func (o *options) readDocument(filename string) ([]byte, textenc.Format, error) {
+	read := os.ReadFile
+	if isURL(filename) {
+		read = o.fetch
+	}
+
+	data, err := read(filename)
+	if err != nil {
+		return nil, textenc.Format{}, err
+	}
+
+	if err = o.loadChanges(filename); err != nil {
+		return nil, textenc.Format{}, err
+	}
+
+	o.selectLines(filename)
+
+	o.document = filename
+
+	src, format, err := textenc.Decode(data, o.encoding)
+	if err != nil {
+		return nil, format, err
+	}
+
+	return src, format, o.checkLimits(filename, src)
+}
+
+// writeDocument encodes the UTF-8 text of a markdown document to format and
+// writes it to filename. The previous content is kept for mdcode undo.
+func writeDocument(filename string, text []byte, format textenc.Format) error {
+	if isURL(filename) {
+		return fmt.Errorf("%w: %s", errRemote, filename)
+	}
+
+	data, err := textenc.Encode(text, format)
+	if err != nil {
+		return err
+	}
+
+	return backupWrite(filename, data)
+}
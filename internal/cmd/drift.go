@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"bytes"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+)
+
+// drift describes a code block that differs from (or cannot be loaded from)
+// the file named in its metadata.
+type drift struct {
+	block *mdcode.Block
+	file  string
+	err   error
+}
+
+func (d *drift) message() string {
+	if d.err != nil {
+		return d.err.Error()
+	}
+
+	return "code block is out of sync with " + d.file
+}
+
+func checkDrift(src []byte, dir string, filter filterFunc, read readFunc) ([]*drift, error) {
+	var drifts []*drift
+
+	_, _, err := walk(src, func(block *mdcode.Block) error {
+		file := block.Meta.Get(metaFile)
+		if len(file) == 0 {
+			return nil
+		}
+
+		if isPatch(block.Lang) {
+			if err := checkPatch(block, dir, read); err != nil {
+				drifts = append(drifts, &drift{block: block, file: file, err: err})
+			}
+
+			return nil
+		}
+
+		probe := *block
+
+		if err := loadWith(&probe, dir, nostatus, read); err != nil {
+			drifts = append(drifts, &drift{block: block, file: file, err: err})
+
+			return nil
+		}
+
+		// The line endings of the document and of the file may differ.
+		if !bytes.Equal(convertEOL(probe.Code, lf), convertEOL(block.Code, lf)) {
+			drifts = append(drifts, &drift{block: block, file: file, err: nil})
+		}
+
+		return nil
+	}, filter)
+
+	return drifts, err
+}
+
+func anyBlock(*mdcode.Block) bool {
+	return true
+}
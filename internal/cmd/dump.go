@@ -2,11 +2,15 @@ package cmd
 
 import (
 	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
 	_ "embed"
+	"errors"
+	"fmt"
 	"io"
 	"io/fs"
-	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/ezerfernandes/mdcode/internal/mdcode"
 	"github.com/liamg/memoryfs"
@@ -22,7 +26,7 @@ func dumpCmd(opts *options) *cobra.Command {
 		Aliases: []string{"d"},
 		Short:   "Dump markdown code blocks",
 		Long:    dumpHelp,
-		Args:    cobra.MaximumNArgs(1),
+		Args:    checkargs,
 		PreRun: func(cmd *cobra.Command, _ []string) {
 			opts.createStatus(cmd.ErrOrStderr())
 		},
@@ -32,7 +36,12 @@ func dumpCmd(opts *options) *cobra.Command {
 				return err
 			}
 
-			if err = dumpRun(source(args), out, opts); err != nil {
+			files, err := sources(args, opts)
+			if err != nil {
+				return err
+			}
+
+			if err = dumpRun(cmd, files, out, opts); err != nil {
 				return err
 			}
 
@@ -49,21 +58,29 @@ func dumpCmd(opts *options) *cobra.Command {
 	return cmd
 }
 
-func dumpRun(filename string, out io.Writer, opts *options) error {
-	opts.status("Dumping code blocks from %s\n", filename)
+func dumpRun(cmd *cobra.Command, filenames []string, out io.Writer, opts *options) error {
+	mfs := memoryfs.New()
 
-	src, err := os.ReadFile(filename)
-	if err != nil {
-		return err
-	}
+	for _, filename := range filenames {
+		opts.status("Dumping code blocks from %s\n", filename)
 
-	mfs := memoryfs.New()
+		src, _, err := opts.readDocument(filename)
+		if err != nil {
+			return err
+		}
 
-	_, _, err = walk(src, func(block *mdcode.Block) error {
-		return dump(block, mfs, opts.dir, opts.status)
-	}, opts.filter)
-	if err != nil {
-		return err
+		documentDir(cmd, opts, filename)
+
+		_, _, err = walk(src, func(block *mdcode.Block) error {
+			if err := opts.confine(block); err != nil {
+				return err
+			}
+
+			return dump(block, mfs, opts.dir, opts.status)
+		}, opts.filter)
+		if err != nil {
+			return err
+		}
 	}
 
 	return archive(mfs, out)
@@ -94,7 +111,59 @@ func dump(block *mdcode.Block, mfs *memoryfs.FS, dir string, status statusFunc)
 func archive(mfs *memoryfs.FS, out io.Writer) error {
 	tarout := tar.NewWriter(out)
 
-	werr := fs.WalkDir(mfs, ".", func(path string, entry fs.DirEntry, err error) error {
+	err := archiveFiles(mfs, func(path string, info fs.FileInfo, body []byte) error {
+		hdr := &tar.Header{ //nolint:exhaustruct
+			Name:    path,
+			Mode:    int64(info.Mode()),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		}
+
+		if err := tarout.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		_, err := tarout.Write(body)
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return tarout.Close()
+}
+
+func zipArchive(mfs *memoryfs.FS, out io.Writer) error {
+	zipout := zip.NewWriter(out)
+
+	err := archiveFiles(mfs, func(path string, info fs.FileInfo, body []byte) error {
+		hdr, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+
+		hdr.Name, hdr.Method = path, zip.Deflate
+
+		w, err := zipout.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(body)
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return zipout.Close()
+}
+
+// archiveFiles calls fn for every regular file of the in-memory file system.
+func archiveFiles(mfs *memoryfs.FS, fn func(path string, info fs.FileInfo, body []byte) error) error {
+	return fs.WalkDir(mfs, ".", func(path string, entry fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
@@ -108,29 +177,52 @@ func archive(mfs *memoryfs.FS, out io.Writer) error {
 			return err
 		}
 
-		hdr := &tar.Header{ //nolint:exhaustruct
-			Name:    path,
-			Mode:    int64(info.Mode()),
-			Size:    info.Size(),
-			ModTime: info.ModTime(),
-		}
-
 		body, err := mfs.ReadFile(path)
 		if err != nil {
 			return err
 		}
 
-		if err = tarout.WriteHeader(hdr); err != nil {
+		return fn(path, info, body)
+	})
+}
+
+const (
+	archiveTar   = "tar"
+	archiveTarGz = "tar.gz"
+	archiveZip   = "zip"
+)
+
+// archiveFormat returns the archive format from the file name extension.
+func archiveFormat(filename string) (string, error) {
+	name := strings.ToLower(filename)
+
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return archiveTarGz, nil
+	case strings.HasSuffix(name, ".tar"):
+		return archiveTar, nil
+	case strings.HasSuffix(name, ".zip"):
+		return archiveZip, nil
+	}
+
+	return "", fmt.Errorf("%w: %s", errArchive, filename)
+}
+
+func writeArchive(mfs *memoryfs.FS, out io.Writer, format string) error {
+	switch format {
+	case archiveZip:
+		return zipArchive(mfs, out)
+	case archiveTarGz:
+		gzout := gzip.NewWriter(out)
+
+		if err := archive(mfs, gzout); err != nil {
 			return err
 		}
 
-		_, err = tarout.Write(body)
-
-		return err
-	})
-	if werr != nil {
-		return werr
+		return gzout.Close()
 	}
 
-	return tarout.Close()
+	return archive(mfs, out)
 }
+
+var errArchive = errors.New("unknown archive format (use .tar, .tar.gz, .tgz or .zip)")
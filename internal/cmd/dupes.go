@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/spf13/cobra"
+)
+
+//go:embed help/dupes.md
+var dupesHelp string
+
+const (
+	defaultSimilarity = 0.9
+	defaultMinLines   = 2
+)
+
+func dupesCmd(opts *options) *cobra.Command {
+	var (
+		similarity float64
+		minLines   int
+	)
+
+	cmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "dupes [flags] [filename]",
+		Short: "Find duplicate markdown code blocks",
+		Long:  dupesHelp,
+		Args:  checkargs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			opts.createStatus(cmd.ErrOrStderr())
+
+			if similarity <= 0 || similarity > 1 {
+				return fmt.Errorf("%w: %g", errSimilarity, similarity)
+			}
+
+			return opts.allBlocksFilter(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files, err := sources(args, opts)
+			if err != nil {
+				return err
+			}
+
+			return dupesRun(files, cmd.OutOrStdout(), opts, similarity, minLines)
+		},
+
+		DisableAutoGenTag: true,
+	}
+
+	quietFlag(cmd, opts)
+
+	cmd.Flags().Float64Var(&similarity, "similarity", defaultSimilarity,
+		"minimum similarity (0-1] of near-identical code blocks, 1 reports identical code blocks only")
+	cmd.Flags().IntVar(&minLines, "min-lines", defaultMinLines, "ignore code blocks shorter than this number of lines")
+
+	return cmd
+}
+
+// located is a code block along with the document it was found in.
+type located struct {
+	document string
+	line     int
+	lines    []string
+}
+
+func dupesRun(files []string, out io.Writer, opts *options, similarity float64, minLines int) error {
+	var blocks []*located
+
+	for _, file := range files {
+		opts.status("Scanning code blocks in %s\n", file)
+
+		src, _, err := opts.readDocument(file)
+		if err != nil {
+			return err
+		}
+
+		_, _, err = walk(src, func(block *mdcode.Block) error {
+			if lines := normalizeLines(block.Code); len(lines) >= minLines && len(lines) != 0 {
+				blocks = append(blocks, &located{document: file, line: block.StartLine, lines: lines})
+			}
+
+			return nil
+		}, opts.filter)
+		if err != nil {
+			return err
+		}
+	}
+
+	groups := groupSimilar(blocks, similarity)
+
+	for _, group := range groups {
+		if opts.compact() {
+			for _, block := range group {
+				fmt.Fprintf(out, "%s: duplicate code block (%d copies)\n", opts.location(block.document, block.line), len(group))
+			}
+
+			continue
+		}
+
+		fmt.Fprintf(out, "%d duplicate code blocks:\n", len(group))
+
+		for _, block := range group {
+			fmt.Fprintf(out, "  %s:%d\n", block.document, block.line)
+		}
+	}
+
+	if len(groups) != 0 {
+		return fmt.Errorf("%w: %d group(s)", errDuplicate, len(groups))
+	}
+
+	return nil
+}
+
+// normalizeLines returns the non-blank lines of code with insignificant
+// whitespace removed, so indentation and spacing changes do not count.
+func normalizeLines(code []byte) []string {
+	var lines []string
+
+	for _, line := range strings.Split(string(code), "\n") {
+		if fields := strings.Fields(line); len(fields) != 0 {
+			lines = append(lines, strings.Join(fields, " "))
+		}
+	}
+
+	return lines
+}
+
+// lineSimilarity returns the Dice coefficient of the two line multisets.
+func lineSimilarity(a, b []string) float64 {
+	counts := make(map[string]int, len(a))
+
+	for _, line := range a {
+		counts[line]++
+	}
+
+	common := 0
+
+	for _, line := range b {
+		if counts[line] > 0 {
+			counts[line]--
+			common++
+		}
+	}
+
+	return 2 * float64(common) / float64(len(a)+len(b)) //nolint:gomnd
+}
+
+// groupSimilar groups the code blocks transitively similar to each other,
+// keeping the order of their first occurrence. Single blocks are dropped.
+func groupSimilar(blocks []*located, similarity float64) [][]*located {
+	parent := make([]int, len(blocks))
+	for idx := range parent {
+		parent[idx] = idx
+	}
+
+	var find func(int) int
+
+	find = func(idx int) int {
+		if parent[idx] != idx {
+			parent[idx] = find(parent[idx])
+		}
+
+		return parent[idx]
+	}
+
+	for i := range blocks {
+		for j := i + 1; j < len(blocks); j++ {
+			if find(i) != find(j) && lineSimilarity(blocks[i].lines, blocks[j].lines) >= similarity {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+
+	var (
+		groups [][]*located
+		index  = make(map[int]int)
+	)
+
+	for idx, block := range blocks {
+		root := find(idx)
+
+		pos, has := index[root]
+		if !has {
+			pos = len(groups)
+			index[root] = pos
+			groups = append(groups, nil)
+		}
+
+		groups[pos] = append(groups[pos], block)
+	}
+
+	dupes := groups[:0]
+
+	for _, group := range groups {
+		if len(group) > 1 {
+			dupes = append(dupes, group)
+		}
+	}
+
+	return dupes
+}
+
+var (
+	errDuplicate  = errors.New("duplicate code blocks found")
+	errSimilarity = errors.New("similarity must be greater than 0 and at most 1")
+)
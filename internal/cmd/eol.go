@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"bytes"
+	"runtime"
+)
+
+const (
+	eolAuto   = ""
+	eolLF     = "lf"
+	eolCRLF   = "crlf"
+	eolNative = "native"
+)
+
+var (
+	lf   = []byte("\n")
+	crlf = []byte("\r\n")
+)
+
+// lineEnding returns the line ending of content written on behalf of the
+// document src: the one requested with --eol, or the dominant one of src.
+func (o *options) lineEnding(src []byte) []byte {
+	switch o.eol {
+	case eolLF:
+		return lf
+	case eolCRLF:
+		return crlf
+	case eolNative:
+		if runtime.GOOS == "windows" {
+			return crlf
+		}
+
+		return lf
+	}
+
+	return detectEOL(src)
+}
+
+// detectEOL returns CRLF if most lines of data end with it, LF otherwise.
+func detectEOL(data []byte) []byte {
+	total := bytes.Count(data, lf)
+	windows := bytes.Count(data, crlf)
+
+	if windows > total-windows {
+		return crlf
+	}
+
+	return lf
+}
+
+// convertEOL returns data with every line ending replaced by eol. It returns
+// data itself when no replacement is needed.
+func convertEOL(data []byte, eol []byte) []byte {
+	if bytes.Equal(eol, crlf) {
+		if bytes.Count(data, lf) == bytes.Count(data, crlf) {
+			return data
+		}
+
+		return bytes.ReplaceAll(bytes.ReplaceAll(data, crlf, lf), lf, crlf)
+	}
+
+	if !bytes.Contains(data, crlf) {
+		return data
+	}
+
+	return bytes.ReplaceAll(data, crlf, lf)
+}
+
+func validEOL(eol string) bool {
+	return eol == eolAuto || eol == eolLF || eol == eolCRLF || eol == eolNative
+}
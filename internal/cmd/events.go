@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Types of the events of the --events stream.
+const (
+	eventBlockStart = "block_start"
+	eventOutput     = "output"
+	eventBlockEnd   = "block_end"
+)
+
+// streamEvent is a JSON line of the --events stream.
+type streamEvent struct {
+	Type     string    `json:"type"`
+	Time     time.Time `json:"time"`
+	Document string    `json:"document"`
+	Block    int       `json:"block,omitempty"`
+	Blocks   int       `json:"blocks,omitempty"`
+	Line     int       `json:"line,omitempty"`
+	Lang     string    `json:"lang,omitempty"`
+	Command  string    `json:"command,omitempty"`
+	Stream   string    `json:"stream,omitempty"`
+	Data     string    `json:"data,omitempty"`
+	ExitCode *int      `json:"exit_code,omitempty"`
+	Duration int64     `json:"duration_ms,omitempty"`
+}
+
+// eventStream writes the execution events as JSON lines, so GUIs and CI
+// wrappers can display live progress. A nil stream discards the events.
+type eventStream struct {
+	mu     sync.Mutex
+	enc    *json.Encoder
+	file   *os.File
+	stdout bool
+
+	document string
+	block    int
+}
+
+// openEvents opens the event stream, "-" is the standard output.
+func openEvents(name string) (*eventStream, error) {
+	stream := new(eventStream)
+
+	if name == "-" {
+		stream.enc, stream.stdout = json.NewEncoder(os.Stdout), true
+	} else {
+		file, err := os.Create(name)
+		if err != nil {
+			return nil, err
+		}
+
+		stream.enc, stream.file = json.NewEncoder(file), file
+	}
+
+	stream.enc.SetEscapeHTML(false)
+
+	return stream, nil
+}
+
+func (s *eventStream) emit(event *streamEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event.Time = time.Now()
+	event.Document = s.document
+
+	if event.Block == 0 && event.Blocks == 0 {
+		event.Block = s.block
+	}
+
+	_ = s.enc.Encode(event)
+}
+
+// start emits the start of a code block, the output is attributed to it
+// until end.
+func (s *eventStream) start(filename string, info *blockInfo, command string) {
+	if s == nil {
+		return
+	}
+
+	s.document, s.block = filename, info.index
+
+	s.emit(&streamEvent{Type: eventBlockStart, Line: info.startLine, Lang: info.lang, Command: command}) //nolint:exhaustruct
+}
+
+// startBatch emits the start of a batch of code blocks.
+func (s *eventStream) startBatch(filename string, blocks int, command string) {
+	if s == nil {
+		return
+	}
+
+	s.document, s.block = filename, 0
+
+	s.emit(&streamEvent{Type: eventBlockStart, Blocks: blocks, Command: command}) //nolint:exhaustruct
+}
+
+func (s *eventStream) end(exitCode int, duration time.Duration) {
+	if s == nil {
+		return
+	}
+
+	s.emit(&streamEvent{Type: eventBlockEnd, ExitCode: &exitCode, Duration: duration.Milliseconds()}) //nolint:exhaustruct
+
+	s.block = 0
+}
+
+// writer returns the writer copying the output written to w as output
+// events of the stream (stdout or stderr).
+func (s *eventStream) writer(stream string, w io.Writer) io.Writer {
+	if s == nil {
+		return w
+	}
+
+	return io.MultiWriter(w, &eventWriter{stream: s, name: stream})
+}
+
+func (s *eventStream) close() error {
+	if s == nil || s.file == nil {
+		return nil
+	}
+
+	return s.file.Close()
+}
+
+type eventWriter struct {
+	stream *eventStream
+	name   string
+}
+
+func (w *eventWriter) Write(data []byte) (int, error) {
+	w.stream.emit(&streamEvent{Type: eventOutput, Stream: w.name, Data: string(data)}) //nolint:exhaustruct
+
+	return len(data), nil
+}
@@ -1,14 +1,20 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
 	_ "embed"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"time"
 
 	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/ezerfernandes/mdcode/internal/textenc"
 	"github.com/spf13/cobra"
 	"mvdan.cc/sh/v3/interp"
 	"mvdan.cc/sh/v3/syntax"
@@ -17,22 +23,102 @@ import (
 //go:embed help/exec.md
 var execHelp string
 
+const (
+	batchFiles  = ".mdcode-files"
+	batchFiles0 = ".mdcode-files0"
+)
+
 type blockInfo struct {
 	index     int
+	id        string
 	lang      string
+	canonical string
 	file      string
+	group     string
+	dir       string
 	tempPath  string
 	startLine int
 	endLine   int
+	mappings  []lineMapping
+}
+
+type execOptions struct {
+	update  bool
+	batch   bool
+	cache   bool
+	isolate bool
+	limits  limits
+	shell   string
+	step    bool
+	stepper *stepper
+
+	setup    string
+	teardown string
+
+	session bool
+	sess    *session
+	console bool
+
+	isolation isolation
+
+	coverage  *execCache
+	report    *report
+	sourceMap *sourceMap
+	history   *historyRecord
+
+	remapErrors bool
+	remap       *remapper
+
+	workspace string
+
+	policy   *policy
+	approved *execCache
+	trust    *trust
+
+	outputDir string
+	logName   string
+	recording *recording
+	tail      *tailOutput
+	silent    bool
+
+	failures []failure
+
+	profile string
+	events  *eventStream
+
+	missing   string
+	toolchain *toolchain
+	timings   []timing
+
+	retries    int
+	retryDelay time.Duration
+
+	normalize []string
+
+	// extractFailed is set if a code block could not be written to the
+	// temporary directory, which is kept for inspection.
+	extractFailed bool
 }
 
 func execCmd(opts *options) *cobra.Command {
 	var (
-		update  bool
-		batch   bool
-		verbose bool
+		coverage    bool
+		reportValue string
+		mapValue    string
+		policyFile  string
+		approved    bool
+		trusted     bool
+		eventsFile  string
+		tempDir     string
+		noHistory   bool
+		record      string
+		replay      string
+		tailLines   int
+		isolation   isolationFlags
 	)
 
+	eopts := new(execOptions)
+
 	cmd := &cobra.Command{ //nolint:exhaustruct
 		Use:     "exec [flags] [filename] [-- command]",
 		Aliases: []string{"e"},
@@ -42,52 +128,219 @@ func execCmd(opts *options) *cobra.Command {
 		PreRunE: func(cmd *cobra.Command, _ []string) error {
 			opts.createStatus(cmd.ErrOrStderr())
 
-			fileChanged := cmd.Flag("file").Changed
-			langChanged := cmd.Flag("lang").Changed
+			if err := opts.allBlocksFilter(cmd); err != nil {
+				return err
+			}
 
-			if !fileChanged || !langChanged {
-				meta := make(map[string]string)
+			opts.excludeRoles()
 
-				for k, v := range opts.meta {
-					if k != metaFile || fileChanged {
-						meta[k] = v
-					}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			scr, args := script(cmd, args)
+
+			if eopts.session {
+				if eopts.batch || eopts.cache || eopts.shell != shellBuiltin {
+					return errSession
 				}
 
-				lang := opts.lang
-				if !langChanged {
-					lang = []string{"*"}
+				if len(scr) == 0 {
+					scr = sessionCommand
+					opts.sessionBlocks(eopts.console)
 				}
+			}
+
+			if eopts.console && eopts.batch {
+				return errConsole
+			}
 
-				var err error
+			if err := eopts.setIsolation(&isolation, opts.aliases); err != nil {
+				return err
+			}
+
+			switch {
+			case len(scr) != 0:
+			case eopts.batch:
+				return errMissingCommand
+			case eopts.console:
+				opts.consoleBlocks()
+			default:
+				opts.commandBlocks()
+			}
+
+			if err := checkShell(eopts.shell); err != nil {
+				return err
+			}
+
+			if err := checkNameTemplate(opts.nameTemplate); err != nil {
+				return err
+			}
+
+			if err := checkNormalize(eopts.normalize); err != nil {
+				return err
+			}
+
+			if eopts.missing != missingFail && eopts.missing != missingSkip {
+				return fmt.Errorf("%w: %s", errMissing, eopts.missing)
+			}
+
+			eopts.toolchain = newToolchain()
+
+			if p := eopts.profile; len(p) != 0 && p != profileDuration && p != profileOrder {
+				return fmt.Errorf("%w: %s", errProfile, p)
+			}
+
+			pol, err := loadPolicy(policyFile)
+			if err != nil {
+				return err
+			}
 
-				if opts.filter, err = filter(lang, meta); err != nil {
+			eopts.policy = pol
+
+			opts.executableBlocks(pol)
+
+			if len(eventsFile) != 0 {
+				if eopts.events, err = openEvents(eventsFile); err != nil {
 					return err
 				}
+
+				defer eopts.events.close()
 			}
 
-			return nil
-		},
-		RunE: func(cmd *cobra.Command, args []string) error {
-			scr, args := script(cmd, args)
-			if len(scr) == 0 {
-				return errMissingCommand
+			if approved {
+				if eopts.approved, err = loadExecCache(approvedFilename); err != nil {
+					return err
+				}
+			} else if eopts.trust, err = loadTrust(trusted, cmd.InOrStdin(), cmd.ErrOrStderr()); err != nil {
+				return err
 			}
 
-			if !cmd.Flag("dir").Changed {
+			if eopts.step {
+				if eopts.batch {
+					return errStepBatch
+				}
+
+				eopts.stepper = newStepper(cmd.InOrStdin(), cmd.ErrOrStderr())
+			}
+
+			if len(eopts.workspace) != 0 {
+				if cmd.Flag("dir").Changed {
+					return errWorkspace
+				}
+
+				if err := os.MkdirAll(eopts.workspace, dirMode); err != nil {
+					return err
+				}
+
+				opts.dir = eopts.workspace
+			} else if !cmd.Flag("dir").Changed {
 				dir, err := os.MkdirTemp(".", "mdcode-exec-")
 				if err != nil {
 					return err
 				}
 
-				opts.dir = dir
+				opts.dir, tempDir = dir, dir
+
+				defer func() {
+					if !opts.keep && !eopts.extractFailed {
+						os.RemoveAll(dir)
+					}
+				}()
+			}
+
+			files, err := sources(args, opts)
+			if err != nil {
+				return err
+			}
+
+			if coverage {
+				if eopts.coverage, err = loadExecCache(coverageFilename); err != nil {
+					return err
+				}
+			}
+
+			if len(reportValue) != 0 {
+				if eopts.report, err = newReport(reportValue); err != nil {
+					return err
+				}
+			}
+
+			if eopts.recording, err = newRecording(record, replay); err != nil {
+				return err
+			}
+
+			switch {
+			case eopts.silent:
+			case eopts.events != nil && eopts.events.stdout:
+				eopts.tail = newTailOutput(tailLines, os.Stderr)
+			default:
+				eopts.tail = newTailOutput(tailLines, os.Stdout)
+			}
+
+			if eopts.silent && len(eopts.outputDir) == 0 {
+				eopts.outputDir = tailLogDir
+			}
+
+			if !noHistory {
+				eopts.history = newHistoryRecord(scr)
+			}
+
+			if eopts.report == nil && (eopts.history != nil || eopts.silent) {
+				eopts.report = newCollector()
+			}
+
+			if len(mapValue) != 0 {
+				eopts.sourceMap = &sourceMap{filename: mapValue} //nolint:exhaustruct
+			}
+
+			var errs []error
+
+			for _, file := range files {
+				if err = execRun(file, opts, eopts, scr); err != nil {
+					if opts.recursive {
+						err = fmt.Errorf("%s: %w", file, err)
+					}
+
+					errs = append(errs, err)
+				}
+			}
+
+			if !opts.quiet {
+				printFailures(opts.stderr, eopts.failures, opts.color)
+			}
+
+			if eopts.silent {
+				printSilentSummary(opts.stderr, eopts.report, eopts.outputDir)
+			}
+
+			printProfile(opts.stderr, eopts.timings, eopts.profile)
+
+			if coverage {
+				errs = append(errs, eopts.coverage.save(coverageFilename))
+			}
 
-				if !opts.keep {
-					defer os.RemoveAll(dir)
+			if len(tempDir) != 0 && (opts.keep || eopts.extractFailed) {
+				if abs, err := filepath.Abs(tempDir); err == nil {
+					tempDir = abs
 				}
+
+				eopts.report.retain(tempDir)
+				opts.status("temporary directory kept: %s\n", tempDir)
+			}
+
+			if eopts.report != nil {
+				errs = append(errs, eopts.report.write())
 			}
 
-			return execRun(source(args), opts, scr, update, batch, verbose)
+			if eopts.sourceMap != nil {
+				errs = append(errs, eopts.sourceMap.write())
+			}
+
+			if eopts.history != nil {
+				errs = append(errs, eopts.history.record(historyFilename, eopts.report.entries))
+			}
+
+			return errors.Join(errs...)
 		},
 
 		DisableAutoGenTag: true,
@@ -95,76 +348,312 @@ func execCmd(opts *options) *cobra.Command {
 
 	dirFlag(cmd, opts)
 	quietFlag(cmd, opts)
+	stampFlag(cmd, opts)
 
-	cmd.Flags().BoolVar(&update, "update", false, "update markdown code blocks with modified files")
-	cmd.Flags().BoolVar(&batch, "batch", false, "run command once for all files instead of once per block")
+	cmd.Flags().BoolVar(&eopts.update, "update", false, "update markdown code blocks with modified files")
+	cmd.Flags().StringSliceVar(&eopts.normalize, "normalize", []string{normalizeEOL},
+		"differences ignored by --update: eol (line endings), space (trailing whitespace), newline (trailing blank lines)")
+	cmd.Flags().BoolVar(&eopts.batch, "batch", false, "run command once for all files instead of once per block")
 	cmd.Flags().BoolVarP(&opts.keep, "keep", "k", false, "don't remove temporary directory")
-	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "show the command being executed for each block")
+	cmd.Flags().StringVar(&opts.nameTemplate, "name-template", "", "name of the temporary files, e.g. {index:03d}_{lang}{ext} or {file} (default: {index}_{base})")
+	cmd.Flags().StringVar(&eopts.workspace, "workspace", "", "reuse the directory across runs, rewriting only the changed code blocks")
+	cobra.CheckErr(cmd.MarkFlagDirname("workspace"))
+
+	cmd.Flags().StringVar(&eopts.outputDir, "output-dir", "", "write the output of each block to block_N.out and block_N.err files in the directory")
+	cobra.CheckErr(cmd.MarkFlagDirname("output-dir"))
+
+	cmd.Flags().StringVar(&record, "record", "", "record the output and exit code of each command into fixtures in the directory")
+	cmd.Flags().Lookup("record").NoOptDefVal = defaultFixtureDir
+	cmd.Flags().StringVar(&replay, "replay", "", "verify that each command reproduces its recorded fixtures byte for byte")
+	cmd.Flags().Lookup("replay").NoOptDefVal = defaultFixtureDir
+	cobra.CheckErr(cmd.MarkFlagDirname("record"))
+	cobra.CheckErr(cmd.MarkFlagDirname("replay"))
+
+	cmd.Flags().BoolVar(&eopts.silent, "silent", false, "don't show the output of the blocks (written to --output-dir, "+tailLogDir+" by default), only their status and a summary")
+	cmd.Flags().IntVar(&tailLines, "tail", 0, "show only the last lines of the output of failed blocks, writing the full output to a log file")
+
+	cmd.Flags().IntVar(&eopts.retries, "retries", 0, "re-run a failing block up to the given number of times")
+	cmd.Flags().DurationVar(&eopts.retryDelay, "retry-delay", time.Second, "delay before the first retry, doubled after each retry")
+	cmd.Flags().BoolVar(&eopts.isolate, "isolate", false, "give each block its own subdirectory of the temporary directory")
+	cmd.Flags().BoolVar(&eopts.step, "step", false, "ask before executing each block (run, skip, edit or abort)")
+	cmd.Flags().StringVar(&eopts.shell, "shell", shellBuiltin, "shell executing the command (sh, bash, pwsh, powershell or cmd)")
+	cmd.Flags().Int64Var(&eopts.limits.maxOutput, "max-output-bytes", 0, "truncate the output of a command after the given number of bytes")
+	cmd.Flags().StringVar(&eopts.limits.maxMemory, "max-memory", "", "virtual memory limit of executed programs (e.g. 512M)")
+	cmd.Flags().IntVar(&eopts.limits.nice, "nice", 0, "niceness adjustment of executed programs")
+	cmd.Flags().BoolVar(&eopts.session, "session", false, "run the commands of a document in one persistent shell (default command: "+sessionCommand+")")
+	cmd.Flags().StringVar(&isolation.image, "container", "", "run the command of each block in a container of the image ("+containerAuto+": the image of the block language)")
+	cmd.Flags().Lookup("container").NoOptDefVal = containerAuto
+	cmd.Flags().StringToStringVar(&isolation.images, "container-image", nil, "image of a language with --container="+containerAuto+" (e.g. go=golang:1.22, * for the other languages)")
+	cmd.Flags().StringVar(&isolation.engine, "container-engine", engineDocker, "container engine running the containers (docker or podman)")
+	cmd.Flags().StringVar(&isolation.sandbox, "sandbox", "", "run the command of each block in a sandbox without network and with a read-only file system (nsjail, bwrap or firejail)")
+	cmd.Flags().BoolVar(&isolation.noNetwork, "no-network", false, "deny network access to the blocks (by a sandbox if available, by proxy environment variables otherwise)")
+	cmd.Flags().BoolVar(&eopts.console, "console", false, "run console code blocks as shell session transcripts, verifying the output of the $ prompt commands")
+	cmd.Flags().StringVar(&eopts.setup, "setup", "", "shell command to run in the temporary directory before the code blocks")
+	cmd.Flags().StringVar(&eopts.teardown, "teardown", "", "shell command to run in the temporary directory after the code blocks")
+	cmd.Flags().StringVar(&reportValue, "report", "", "write an execution report (html=filename or json=filename)")
+	cmd.Flags().StringVar(&mapValue, "source-map", "", "write a JSON source map relating the lines of the temporary files to the markdown document")
+	cobra.CheckErr(cmd.MarkFlagFilename("source-map", "json"))
+	cmd.Flags().BoolVar(&eopts.remapErrors, "remap-errors", false, "rewrite temporary file positions in the error output to markdown document positions")
+	cmd.Flags().StringVar(&policyFile, "policy", "", "policy file restricting the executed languages and commands (default policy.json of the mdcode user configuration directory, if it exists)")
+	cmd.Flags().BoolVarP(&trusted, "yes", "y", false, "execute documents not run before (or modified since) without confirmation, and trust them")
+	cmd.Flags().BoolVar(&approved, "only-approved", false, "refuse to execute code blocks not recorded by mdcode approve in "+approvedFilename)
+	cmd.Flags().StringVar(&eopts.profile, "profile", "", "print the duration of each command, sorted by duration or in execution order (duration or order)")
+	cmd.Flags().Lookup("profile").NoOptDefVal = profileDuration
+	cmd.Flags().StringVar(&eventsFile, "events", "", "write block start, output and end events as JSON lines to the file (- for standard output)")
+	cmd.Flags().StringVar(&eopts.missing, "missing", missingFail, "what to do with code blocks whose requires metadata is not met (fail or skip)")
+	cmd.Flags().BoolVar(&coverage, "coverage", false, "record successfully executed blocks in "+coverageFilename)
+	cmd.Flags().BoolVar(&noHistory, "no-history", false, "don't record the run in the "+historyFilename+" history journal")
+	cmd.Flags().BoolVar(&eopts.cache, "cache", false, "skip blocks unchanged since their last successful run (uses "+cacheFilename+")")
 
 	return cmd
 }
 
-func execRun(filename string, opts *options, scr string, update, batch, verbose bool) error {
-	src, err := os.ReadFile(filename)
+func execRun(filename string, opts *options, eopts *execOptions, scr string) (err error) {
+	if eopts.update {
+		lock, lerr := lockDocument(filename)
+		if lerr != nil {
+			return lerr
+		}
+
+		defer func() {
+			err = errors.Join(err, lock.unlock())
+		}()
+	}
+
+	src, format, err := opts.readDocument(filename)
 	if err != nil {
 		return err
 	}
 
+	if err = eopts.enforcePolicy(filename, src, scr, opts); err != nil {
+		return err
+	}
+
+	if err = eopts.enforceApproval(filename, src, opts); err != nil {
+		return err
+	}
+
+	if err = eopts.enforceTrust(filename, src); err != nil {
+		return err
+	}
+
+	if err = eopts.enforceRequirements(filename, src, opts); err != nil {
+		return err
+	}
+
+	eopts.history.document(filename, src)
+
+	if eopts.remapErrors {
+		eopts.remap = newRemapper(filename)
+	}
+
 	absDir, err := filepath.Abs(opts.dir)
 	if err != nil {
 		return err
 	}
 
-	if batch {
-		return execBatch(filename, src, absDir, opts, scr, update)
+	if !eopts.limits.supported() && (len(eopts.limits.maxMemory) != 0 || eopts.limits.nice != 0) {
+		opts.status("warning: --max-memory and --nice are not supported on %s\n", runtime.GOOS)
 	}
 
-	return execPerBlock(filename, src, absDir, opts, scr, update, verbose)
+	cache := newExecCache()
+
+	if eopts.cache {
+		if cache, err = loadExecCache(cacheFilename); err != nil {
+			return err
+		}
+	}
+
+	setup, teardown, err := eopts.roleScripts(src, absDir, opts)
+	if err != nil {
+		return err
+	}
+
+	if eopts.session {
+		if eopts.sess, err = newSession(absDir, eopts.limits); err != nil {
+			return err
+		}
+	}
+
+	err = eopts.runRole(roleSetup, setup, filename, absDir, opts)
+
+	switch {
+	case err != nil:
+	case eopts.batch:
+		err = execBatch(filename, src, format, absDir, opts, eopts, scr, cache)
+	default:
+		err = execPerBlock(filename, src, format, absDir, opts, eopts, scr, cache)
+	}
+
+	if terr := eopts.runRole(roleTeardown, teardown, filename, absDir, opts); terr != nil && err == nil {
+		err = terr
+	}
+
+	if eopts.sess != nil {
+		if serr := eopts.sess.close(); serr != nil && err == nil {
+			err = serr
+		}
+
+		eopts.sess = nil
+	}
+
+	if eopts.cache {
+		if serr := cache.save(cacheFilename); serr != nil && err == nil {
+			err = serr
+		}
+	}
+
+	return err
 }
 
-func execPerBlock(filename string, src []byte, dir string, opts *options, scr string, update, verbose bool) error {
+func execPerBlock(filename string, src []byte, format textenc.Format, dir string, opts *options, eopts *execOptions, scr string, cache *execCache) error {
 	index := 1
 	var failures int
 
 	modified, result, err := walk(src, func(block *mdcode.Block) error {
-		info := writeBlockToTemp(block, index, dir, opts.status)
+		info := newBlockInfo(block, index, blockDir(dir, index, eopts), opts)
 		index++
 
 		if info == nil {
 			return nil
 		}
 
-		expanded := expandCommand(scr, info, dir)
+		command := blockCommand(scr, block)
+		expanded := expandCommand(command, info, info.dir, eopts.path)
+
+		opts.event("block discovered", info.attrs(filename)...)
 
-		opts.status("--- block %d (%s%s) : L%d-%d : %s ---\n", info.index, info.lang, fileLabel(info.file), info.startLine, info.endLine, filepath.Base(filename))
+		if reason := platformMismatch(block); len(reason) != 0 {
+			eopts.report.add(blockEntry(filename, info, block.Code, reportSkipped))
+			opts.status("%s\n\n", opts.color.header(blockHeader(info, filename, " : skipped on "+runtime.GOOS+"/"+runtime.GOARCH+" ("+reason+")")))
+			opts.event("block skipped", append(info.attrs(filename), "reason", "platform")...)
 
-		if verbose {
-			opts.status("%s\n", expanded)
+			return nil
 		}
 
-		exitCode, execErr := runCommand(expanded, dir, os.Stdout, os.Stderr)
+		if reqErr := eopts.toolchain.check(block.Meta.Get(metaRequires)); reqErr != nil {
+			if !errors.Is(reqErr, errUnmet) {
+				return fmt.Errorf("%s: %w", opts.location(filename, block.StartLine), reqErr)
+			}
+
+			eopts.report.add(blockEntry(filename, info, block.Code, reportSkipped))
+			opts.status("%s\n\n", opts.color.header(blockHeader(info, filename, " : skipped ("+reqErr.Error()+")")))
+			opts.event("block skipped", append(info.attrs(filename), "reason", "requirement", "requirement", reqErr.Error())...)
+
+			return nil
+		}
+
+		if !eopts.writeBlock(filename, block, info, opts) {
+			return nil
+		}
+
+		key := cache.key(command, block)
+		if cache.has(key) {
+			eopts.cover(block)
+			eopts.report.add(blockEntry(filename, info, block.Code, reportCached))
+			opts.status("%s\n\n", opts.color.header(blockHeader(info, filename, " : cached")))
+			opts.event("block skipped", append(info.attrs(filename), "reason", "cached")...)
+
+			return nil
+		}
+
+		opts.status("%s\n", opts.color.header(blockHeader(info, filename, "")))
+
+		opts.verbose(1, "%s\n", expanded)
+
+		if eopts.stepper != nil {
+			action, stepErr := eopts.stepper.ask(info, expanded)
+			if stepErr != nil {
+				return stepErr
+			}
+
+			switch action {
+			case stepSkip:
+				eopts.report.add(blockEntry(filename, info, block.Code, reportSkipped))
+				opts.status("%s\n\n", opts.color.warn("skipped"))
+				opts.event("block skipped", append(info.attrs(filename), "reason", "step")...)
+
+				return nil
+			case stepAbort:
+				return errAborted
+			case stepRun:
+			}
+		}
+
+		start := time.Now()
+
+		retries, retryErr := eopts.blockRetries(block)
+		if retryErr != nil {
+			return retryErr
+		}
+
+		eopts.setLogName(eopts.logDir(filename, opts), "block_%d", info.index)
+		eopts.recording.fixture(filename, fixtureName(block, info.index))
+		eopts.tail.start(eopts.logName, filename, opts, fmt.Sprintf("block_%d", info.index))
+		eopts.events.start(filename, info, expanded)
+
+		exitCode, execErr := eopts.retry(retries, opts, func() (int, error) {
+			return eopts.runBlock(command, expanded, info, opts.status)
+		})
+
+		eopts.logName = ""
 		if execErr != nil {
 			return execErr
 		}
 
+		if exitCode, execErr = eopts.recording.finish(exitCode, opts); execErr != nil {
+			return execErr
+		}
+
+		if execErr = eopts.tail.finish(exitCode); execErr != nil {
+			return execErr
+		}
+
+		eopts.events.end(exitCode, time.Since(start))
+
+		eopts.blockTimed(filename, info, time.Since(start))
+
+		opts.verbose(2, "block %d finished in %s\n", info.index, time.Since(start).Round(time.Millisecond))
+		opts.event("block executed", append(info.attrs(filename), "command", expanded, "exit_code", exitCode,
+			"duration", time.Since(start))...)
+
+		entry := blockEntry(filename, info, block.Code, reportStatus(exitCode))
+		entry.Command, entry.ExitCode, entry.Duration = expanded, exitCode, time.Since(start).Round(time.Millisecond)
+		eopts.report.add(entry)
+
+		opts.status("%s\n", exitStatus(exitCode, opts.color))
+
 		if exitCode != 0 {
 			failures++
 
-			if update {
-				opts.status("\nwarning: block %d exited with %d, skipping update\n", info.index, exitCode)
+			eopts.blockFailed(filename, info, exitCode)
+
+			if eopts.update {
+				opts.status("%s\n\n", opts.color.warn(fmt.Sprintf("warning: block %d exited with %d, skipping update", info.index, exitCode)))
+				opts.event("block skipped", append(info.attrs(filename), "reason", "failed")...)
 
 				return nil
 			}
+		} else {
+			cache.add(key)
+			eopts.cover(block)
 		}
 
 		opts.status("\n")
 
-		if update {
+		if eopts.update {
 			newCode, readErr := os.ReadFile(info.tempPath)
 			if readErr != nil {
 				return readErr
 			}
 
+			newCode = convertEOL(opts.unstampCode(filename, block, newCode), opts.lineEnding(src))
+			if sameCode(block.Code, newCode, eopts.normalize) {
+				newCode = block.Code
+			}
+
+			opts.event("block updated", append(info.attrs(filename), "modified", !bytes.Equal(block.Code, newCode))...)
+
 			block.Code = newCode
 		}
 
@@ -175,30 +664,62 @@ func execPerBlock(filename string, src []byte, dir string, opts *options, scr st
 		return err
 	}
 
-	if update && modified {
-		if err := os.WriteFile(filename, result, fileMode); err != nil {
+	if eopts.update && modified {
+		if err := writeDocument(filename, result, format); err != nil {
 			return err
 		}
 	}
 
 	if failures > 0 {
-		return fmt.Errorf("%d block(s) failed", failures)
+		return fmt.Errorf("%d %w", failures, errBlocksFailed)
 	}
 
 	return nil
 }
 
-func execBatch(filename string, src []byte, dir string, opts *options, scr string, update bool) error {
-	var entries []*blockInfo
+func execBatch(filename string, src []byte, format textenc.Format, dir string, opts *options, eopts *execOptions, scr string, cache *execCache) error {
+	var (
+		entries []*blockInfo
+		keys    []string
+		blocks  []*mdcode.Block
+	)
+
+	// The extracted code blocks by walk index: skipped code blocks are
+	// missing, so the update walk doesn't rely on the position in entries.
+	extracted := make(map[int]*blockInfo)
 
 	index := 1
 
 	_, _, err := walk(src, func(block *mdcode.Block) error {
-		info := writeBlockToTemp(block, index, dir, opts.status)
+		if reason := platformMismatch(block); len(reason) != 0 {
+			opts.status("skipping block %d on %s/%s (%s)\n", index, runtime.GOOS, runtime.GOARCH, reason)
+			opts.event("block skipped", "document", filename, "block", index, "line", block.StartLine, "reason", "platform")
+			index++
+
+			return nil
+		}
+
+		if reqErr := eopts.toolchain.check(block.Meta.Get(metaRequires)); reqErr != nil {
+			if !errors.Is(reqErr, errUnmet) {
+				return fmt.Errorf("%s: %w", opts.location(filename, block.StartLine), reqErr)
+			}
+
+			opts.status("skipping block %d (%s)\n", index, reqErr)
+			opts.event("block skipped", "document", filename, "block", index, "line", block.StartLine, "reason", "requirement",
+				"requirement", reqErr.Error())
+			index++
+
+			return nil
+		}
+
+		info := eopts.writeBlockToTemp(filename, block, index, blockDir(dir, index, eopts), opts)
 		index++
 
 		if info != nil {
+			extracted[info.index] = info
 			entries = append(entries, info)
+			keys = append(keys, cache.key(scr, block))
+			blocks = append(blocks, block)
 		}
 
 		return nil
@@ -214,42 +735,110 @@ func execBatch(filename string, src []byte, dir string, opts *options, scr strin
 
 	paths := make([]string, len(entries))
 	for i, e := range entries {
-		paths[i] = e.tempPath
+		paths[i] = eopts.path(e.tempPath)
 	}
 
 	expanded := strings.ReplaceAll(scr, "{}", strings.Join(paths, " "))
-	expanded = strings.ReplaceAll(expanded, "{dir}", dir)
+	expanded = strings.ReplaceAll(expanded, "{dir}", eopts.path(dir))
 
-	opts.status("--- batch (%d blocks) ---\n", len(entries))
+	expanded, err = eopts.expandFileList(expanded, dir, paths)
+	if err != nil {
+		return err
+	}
 
-	exitCode, execErr := runCommand(expanded, dir, os.Stdout, os.Stderr)
+	key := cache.batchKey(scr, keys)
+	if cache.has(key) {
+		for _, block := range blocks {
+			eopts.cover(block)
+		}
+
+		eopts.report.add(&reportEntry{ //nolint:exhaustruct
+			Document: filename, Title: fmt.Sprintf("batch (%d blocks)", len(entries)), Command: expanded, Status: reportCached,
+		})
+		opts.status("%s\n", opts.color.header(fmt.Sprintf("--- batch (%d blocks) : cached ---", len(entries))))
+
+		return nil
+	}
+
+	opts.status("%s\n", opts.color.header(fmt.Sprintf("--- batch (%d blocks) ---", len(entries))))
+
+	start := time.Now()
+
+	eopts.setLogName(eopts.logDir(filename, opts), "batch")
+	eopts.recording.fixture(filename, "batch")
+	eopts.tail.start(eopts.logName, filename, opts, "batch")
+	eopts.events.startBatch(filename, len(entries), expanded)
+
+	exitCode, execErr := eopts.retry(eopts.retries, opts, func() (int, error) {
+		return eopts.run(expanded, dir, opts.status)
+	})
+
+	eopts.logName = ""
 	if execErr != nil {
 		return execErr
 	}
 
-	if update {
+	if exitCode, execErr = eopts.recording.finish(exitCode, opts); execErr != nil {
+		return execErr
+	}
+
+	if execErr = eopts.tail.finish(exitCode); execErr != nil {
+		return execErr
+	}
+
+	eopts.events.end(exitCode, time.Since(start))
+
+	eopts.batchTimed(filename, entries, time.Since(start))
+
+	opts.verbose(2, "batch finished in %s\n", time.Since(start).Round(time.Millisecond))
+	opts.event("batch executed", "document", filename, "blocks", len(entries), "command", expanded,
+		"exit_code", exitCode, "duration", time.Since(start))
+
+	eopts.report.add(&reportEntry{ //nolint:exhaustruct
+		Document: filename, Title: fmt.Sprintf("batch (%d blocks)", len(entries)), Command: expanded,
+		ExitCode: exitCode, Duration: time.Since(start).Round(time.Millisecond), Status: reportStatus(exitCode),
+	})
+
+	opts.status("%s\n", exitStatus(exitCode, opts.color))
+
+	if exitCode != 0 {
+		eopts.batchFailed(filename, entries, exitCode)
+	}
+
+	if exitCode == 0 {
+		cache.add(key)
+
+		for _, block := range blocks {
+			eopts.cover(block)
+		}
+	}
+
+	if eopts.update {
 		if exitCode != 0 {
-			opts.status("warning: command exited with %d, skipping update\n", exitCode)
+			opts.status("%s\n", opts.color.warn(fmt.Sprintf("warning: command exited with %d, skipping update", exitCode)))
 
-			return nil
+			return fmt.Errorf("%w: command exited with %d", errBlocksFailed, exitCode)
 		}
 
-		index = 0
+		index = 1
 
 		modified, result, walkErr := walk(src, func(block *mdcode.Block) error {
-			if index >= len(entries) {
+			entry, has := extracted[index]
+			index++
+
+			if !has {
 				return nil
 			}
 
-			entry := entries[index]
-			index++
-
 			newCode, readErr := os.ReadFile(entry.tempPath)
 			if readErr != nil {
 				return readErr
 			}
 
-			block.Code = newCode
+			newCode = convertEOL(opts.unstampCode(filename, block, newCode), opts.lineEnding(src))
+			if !sameCode(block.Code, newCode, eopts.normalize) {
+				block.Code = newCode
+			}
 
 			return nil
 		}, opts.filter)
@@ -259,77 +848,328 @@ func execBatch(filename string, src []byte, dir string, opts *options, scr strin
 		}
 
 		if modified {
-			return os.WriteFile(filename, result, fileMode)
+			return writeDocument(filename, result, format)
 		}
 	}
 
 	if exitCode != 0 {
-		return fmt.Errorf("command exited with %d", exitCode)
+		return fmt.Errorf("%w: command exited with %d", errBlocksFailed, exitCode)
 	}
 
 	return nil
 }
 
-func writeBlockToTemp(block *mdcode.Block, index int, dir string, status statusFunc) *blockInfo {
+func blockDir(dir string, index int, eopts *execOptions) string {
+	if eopts.isolate {
+		return filepath.Join(dir, fmt.Sprintf("block_%d", index))
+	}
+
+	return dir
+}
+
+func (e *execOptions) writeBlockToTemp(filename string, block *mdcode.Block, index int, dir string, opts *options) *blockInfo {
+	info := newBlockInfo(block, index, dir, opts)
+	if info == nil || !e.writeBlock(filename, block, info, opts) {
+		return nil
+	}
+
+	return info
+}
+
+// newBlockInfo returns the description of the code block and of its
+// temporary file, or nil (with a warning) if the file would be outside of the
+// temporary directory. The file is not written.
+func newBlockInfo(block *mdcode.Block, index int, dir string, opts *options) *blockInfo {
+	if sub := block.Meta.Get(metaDir); len(sub) != 0 {
+		if !filepath.IsLocal(filepath.FromSlash(sub)) {
+			opts.status("warning: skipping block %d, %s is outside of the temporary directory\n", index, sub)
+
+			return nil
+		}
+
+		dir = filepath.Join(dir, filepath.FromSlash(sub))
+	}
+
 	info := &blockInfo{
 		index:     index,
+		id:        blockID(block),
 		lang:      block.Lang,
+		canonical: opts.aliases.canonical(block.Lang),
 		file:      block.Meta.Get(metaFile),
+		group:     block.Meta.Get(metaGroup),
+		dir:       dir,
 		startLine: block.StartLine,
 		endLine:   block.EndLine,
 	}
 
-	info.tempPath = filepath.Join(dir, tempFilename(block, index))
+	name, err := tempFilename(block, info.canonical, index, opts.nameTemplate)
+	if err != nil || !filepath.IsLocal(name) {
+		opts.status("warning: skipping block %d, invalid temporary file name %s\n", index, name)
+
+		return nil
+	}
+
+	info.tempPath = filepath.Join(dir, name)
+
+	return info
+}
+
+// writeBlock writes the code block to its temporary file, and reports whether
+// it succeeded (failures are warnings).
+func (e *execOptions) writeBlock(filename string, block *mdcode.Block, info *blockInfo, opts *options) bool {
+	index := info.index
 
 	if err := os.MkdirAll(filepath.Dir(info.tempPath), dirMode); err != nil {
-		status("warning: failed to create directory for block %d: %v\n", index, err)
+		opts.status("warning: failed to create directory for block %d: %v\n", index, err)
+		e.extractFailed = true
 
-		return nil
+		return false
 	}
 
-	if err := os.WriteFile(info.tempPath, block.Code, fileMode); err != nil {
-		status("warning: failed to write block %d: %v\n", index, err)
+	code := block.Code
+	if opts.eol != eolAuto {
+		code = convertEOL(code, opts.lineEnding(code))
+	}
 
-		return nil
+	written := opts.stampCode(filename, block, code)
+
+	if err := e.writeFile(info.tempPath, written); err != nil {
+		opts.status("warning: failed to write block %d: %v\n", index, err)
+		e.extractFailed = true
+
+		return false
 	}
 
-	return info
+	info.mappings = lineMappings(block.StartLine, code, written)
+
+	if root, err := filepath.Abs(opts.dir); err == nil {
+		e.sourceMap.add(root, filename, info)
+	}
+
+	e.remap.add(info)
+
+	return true
 }
 
-func tempFilename(block *mdcode.Block, index int) string {
+// writeFile writes the file of a code block. In a --workspace, unchanged
+// files are not rewritten, so their modification time is kept for the build
+// caches of the tools.
+func (e *execOptions) writeFile(filename string, code []byte) error {
+	if len(e.workspace) != 0 {
+		if old, err := os.ReadFile(filename); err == nil && bytes.Equal(old, code) {
+			return nil
+		}
+	}
+
+	return os.WriteFile(filename, code, fileMode)
+}
+
+// tempFilename returns the name of the temporary file of the code block,
+// relative to the temporary directory, formatted with the --name-template.
+func tempFilename(block *mdcode.Block, lang string, index int, template string) (string, error) {
+	if len(template) != 0 {
+		name, err := expandName(template, nameValues(block, lang, index))
+
+		return filepath.FromSlash(name), err
+	}
+
 	if file := block.Meta.Get(metaFile); len(file) != 0 {
-		return fmt.Sprintf("%d_%s", index, filepath.Base(filepath.FromSlash(file)))
+		return fmt.Sprintf("%d_%s", index, filepath.Base(filepath.FromSlash(file))), nil
 	}
 
-	ext := langExtension(block.Lang)
+	ext := langExtension(lang)
 
-	return fmt.Sprintf("block_%d%s", index, ext)
+	return fmt.Sprintf("block_%d%s", index, ext), nil
 }
 
-func langExtension(lang string) string {
-	if len(lang) > 0 {
-		return "." + strings.ToLower(lang)
+// blockCommand returns the command of the code block given with the cmd
+// metadata (or an <!-- mdcode:cmd --> directive), or scr by default.
+func blockCommand(scr string, block *mdcode.Block) string {
+	if command := block.Meta.Get(metaCmd); len(command) != 0 {
+		return command
 	}
 
-	return ".txt"
+	return scr
 }
 
-func expandCommand(scr string, info *blockInfo, dir string) string {
-	expanded := strings.ReplaceAll(scr, "{}", info.tempPath)
+// commandBlocks restricts the filter to the code blocks having their own
+// command, used when no command is given after '--'.
+func (o *options) commandBlocks() {
+	filter := o.filter
+
+	o.filter = func(block *mdcode.Block) bool {
+		return len(block.Meta.Get(metaCmd)) != 0 && filter(block)
+	}
+}
+
+// expandFileList expands the {files} and {files0} placeholders of the batch
+// command to the path of a file listing the temporary file paths, separated
+// by newline and NUL characters respectively. Unlike the space separated {}
+// list, the list files are safe for paths containing spaces, e.g. with
+// xargs -0 < {files0}.
+func (e *execOptions) expandFileList(command, dir string, paths []string) (string, error) {
+	lists := []struct {
+		placeholder string
+		name        string
+		separator   string
+	}{
+		{"{files}", batchFiles, "\n"},
+		{"{files0}", batchFiles0, "\x00"},
+	}
+
+	for _, list := range lists {
+		if !strings.Contains(command, list.placeholder) {
+			continue
+		}
+
+		var buff bytes.Buffer
+
+		for _, path := range paths {
+			buff.WriteString(path)
+			buff.WriteString(list.separator)
+		}
+
+		name := filepath.Join(dir, list.name)
+		if err := os.WriteFile(name, buff.Bytes(), fileMode); err != nil {
+			return "", err
+		}
+
+		command = strings.ReplaceAll(command, list.placeholder, e.path(name))
+	}
+
+	return command, nil
+}
+
+func expandCommand(scr string, info *blockInfo, dir string, path func(string) string) string {
+	expanded := strings.ReplaceAll(scr, "{}", path(info.tempPath))
 	expanded = strings.ReplaceAll(expanded, "{lang}", info.lang)
 	expanded = strings.ReplaceAll(expanded, "{index}", fmt.Sprint(info.index))
-	expanded = strings.ReplaceAll(expanded, "{dir}", dir)
+	expanded = strings.ReplaceAll(expanded, "{group}", info.group)
+	expanded = strings.ReplaceAll(expanded, "{dir}", path(dir))
 
 	return expanded
 }
 
-func runCommand(command, dir string, stdout, stderr *os.File) (int, error) {
+// run executes the command with the selected shell applying the resource limits.
+func (e *execOptions) run(command, dir string, status statusFunc) (int, error) {
+	return e.limitOutput(status, func(stdout, stderr io.Writer) (int, error) {
+		return e.execute(command, dir, stdout, stderr)
+	})
+}
+
+// execute runs the command in the session, isolated by the container or the
+// sandbox, by the built-in shell or by the --shell program. Commands not
+// belonging to a code block (like setup scripts and transcript steps) are
+// shell commands, so they get the image of sh.
+func (e *execOptions) execute(command, dir string, stdout, stderr io.Writer) (int, error) {
+	switch {
+	case e.sess != nil:
+		return e.sess.run(command, stdout, stderr)
+	case e.isolation != nil:
+		return e.isolation.run(shellBuiltin, command, dir, stdout, stderr)
+	case e.shell == shellBuiltin:
+		options, err := e.limits.runnerOptions()
+		if err != nil {
+			return -1, err
+		}
+
+		return runCommand(command, dir, os.Stdin, stdout, stderr, options...)
+	default:
+		prefix, err := e.limits.prefix()
+		if err != nil {
+			return -1, err
+		}
+
+		return runProgram(append(prefix, shellArgs(e.shell, command)...), dir, os.Stdin, stdout, stderr)
+	}
+}
+
+// runBlock executes the command of a code block. With --console, console
+// code blocks are run as transcripts instead. With the default session
+// command, code blocks with an interpreter are evaluated by it instead.
+func (e *execOptions) runBlock(scr, command string, info *blockInfo, status statusFunc) (int, error) {
+	if e.console && isConsole(info.canonical) {
+		return e.runTranscript(info, status)
+	}
+
+	if e.isolation != nil {
+		return e.limitOutput(status, func(stdout, stderr io.Writer) (int, error) {
+			return e.isolation.run(info.canonical, command, info.dir, stdout, stderr)
+		})
+	}
+
+	if e.sess == nil || scr != sessionCommand || !hasREPL(info.canonical) {
+		return e.run(command, info.dir, status)
+	}
+
+	return e.limitOutput(status, func(stdout, stderr io.Writer) (int, error) {
+		return e.sess.eval(info.canonical, info.tempPath, stdout, stderr)
+	})
+}
+
+// limitOutput calls fn with the standard output and error truncated after
+// --max-output-bytes.
+func (e *execOptions) limitOutput(status statusFunc, fn func(stdout, stderr io.Writer) (int, error)) (int, error) {
+	limit := newOutputLimit(e.limits.maxOutput)
+	stdout, stderr := e.tail.writers(os.Stdout, os.Stderr)
+
+	if e.silent {
+		stdout, stderr = io.Discard, io.Discard
+	}
+
+	// The event stream replaces the output of the commands on the standard output.
+	if e.events != nil && e.events.stdout {
+		stdout = io.Discard
+	}
+
+	outLog, errLog, err := e.openLogs()
+	if err != nil {
+		return -1, err
+	}
+
+	if outLog != nil {
+		defer outLog.Close()
+		defer errLog.Close()
+
+		stdout, stderr = io.MultiWriter(stdout, outLog), io.MultiWriter(stderr, errLog)
+	}
+
+	stdout, stderr = e.recording.writers(stdout, stderr)
+
+	stdout, stderr = limit.wrap(stdout), limit.wrap(stderr)
+
+	if e.report.captures() {
+		e.report.output.Reset()
+		stdout, stderr = io.MultiWriter(stdout, &e.report.output), io.MultiWriter(stderr, &e.report.output)
+	}
+
+	stdout, stderr = e.events.writer("stdout", stdout), e.events.writer("stderr", stderr)
+
+	if e.remap != nil {
+		var flush func()
+
+		stderr, flush = e.remap.writer(stderr)
+		defer flush()
+	}
+
+	exitCode, err := fn(stdout, stderr)
+
+	if limit.truncated {
+		status("\nwarning: output truncated after %d bytes\n", limit.max)
+	}
+
+	return exitCode, err
+}
+
+func runCommand(command, dir string, stdin io.Reader, stdout, stderr io.Writer, options ...interp.RunnerOption) (int, error) {
 	file, err := syntax.NewParser().Parse(strings.NewReader(command), "")
 	if err != nil {
 		return -1, err
 	}
 
-	runner, err := interp.New(interp.Dir(dir), interp.StdIO(os.Stdin, stdout, stderr))
+	options = append([]interp.RunnerOption{interp.Dir(dir), interp.StdIO(stdin, stdout, stderr)}, options...)
+
+	runner, err := interp.New(options...)
 	if err != nil {
 		return -1, err
 	}
@@ -346,6 +1186,24 @@ func runCommand(command, dir string, stdout, stderr *os.File) (int, error) {
 	return 0, nil
 }
 
+// attrs returns the structured logging attributes of the block.
+func (b *blockInfo) attrs(filename string) []any {
+	return []any{"document", filename, "block", b.index, "id", b.id, "lang", b.lang, "file", b.file, "line", b.startLine}
+}
+
+func blockHeader(info *blockInfo, filename, note string) string {
+	return fmt.Sprintf("--- block %d (%s%s) : L%d-%d : %s%s ---",
+		info.index, info.lang, fileLabel(info.file), info.startLine, info.endLine, filepath.Base(filename), note)
+}
+
+func exitStatus(exitCode int, color palette) string {
+	if exitCode == 0 {
+		return color.ok("ok")
+	}
+
+	return color.fail(fmt.Sprintf("exit status %d", exitCode))
+}
+
 func fileLabel(file string) string {
 	if len(file) != 0 {
 		return ", file=" + file
@@ -354,4 +1212,9 @@ func fileLabel(file string) string {
 	return ""
 }
 
-var errMissingCommand = fmt.Errorf("command is required after '--'")
+var (
+	errMissingCommand = fmt.Errorf("command is required after '--'")
+	errStepBatch      = errors.New("--step can't be used with --batch")
+	errWorkspace      = errors.New("--workspace can't be used with --dir")
+	errProfile        = errors.New("invalid profile order")
+)
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// runMdcode runs the mdcode command line in dir, with a private user
+// configuration directory, and returns its output (standard output and
+// error). The tests calling it can't be parallel, they change the current
+// directory.
+func runMdcode(t *testing.T, dir string, args ...string) (string, error) {
+	t.Helper()
+
+	return runMdcodeInput(t, dir, "", args...)
+}
+
+// runMdcodeInput is runMdcode with the given standard input.
+func runMdcodeInput(t *testing.T, dir string, input string, args ...string) (string, error) {
+	t.Helper()
+
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, ".config"))
+	t.Setenv("HOME", dir)
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+
+	t.Cleanup(func() {
+		require.NoError(t, os.Chdir(wd))
+	})
+
+	var out bytes.Buffer
+
+	root := RootCmd()
+	root.SetArgs(args)
+	root.SetIn(strings.NewReader(input))
+	root.SetOut(&out)
+	root.SetErr(&out)
+
+	err = root.Execute()
+
+	return out.String(), err
+}
+
+func Test_execBatch_update_skipped(t *testing.T) {
+	dir := t.TempDir()
+
+	src := "```sh os=!" + runtime.GOOS + "\necho other platform\n```\n\n" +
+		"```sh requires=mdcode-missing-tool\necho missing tool\n```\n\n" +
+		"```sh dir=../outside\necho outside\n```\n\n" +
+		"```sh\necho two\n```\n\n" +
+		"```sh\necho three\n```\n"
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "doc.md"), []byte(src), 0o600))
+
+	out, err := runMdcode(t, dir, "exec", "--no-history", "--yes", "--batch", "--update", "--missing", "skip",
+		"doc.md", "--", "for f in {}; do echo '# touched' >> $f; done")
+	require.NoError(t, err, out)
+
+	data, err := os.ReadFile(filepath.Join(dir, "doc.md"))
+	require.NoError(t, err)
+
+	expected := "```sh os=!" + runtime.GOOS + "\necho other platform\n```\n\n" +
+		"```sh requires=mdcode-missing-tool\necho missing tool\n```\n\n" +
+		"```sh dir=../outside\necho outside\n```\n\n" +
+		"```sh\necho two\n# touched\n```\n\n" +
+		"```sh\necho three\n# touched\n```\n"
+
+	require.Equal(t, expected, string(data))
+}
+
+func Test_execPerBlock_skipped_not_written(t *testing.T) {
+	dir := t.TempDir()
+	tmp := filepath.Join(dir, "tmp")
+
+	src := "```sh os=!" + runtime.GOOS + "\necho other platform\n```\n\n" +
+		"```sh requires=mdcode-missing-tool\necho missing tool\n```\n\n" +
+		"```sh\necho run\n```\n"
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "doc.md"), []byte(src), 0o600))
+
+	out, err := runMdcode(t, dir, "exec", "--no-history", "--yes", "--missing", "skip", "--keep", "--dir", tmp,
+		"doc.md", "--", "cat {}")
+	require.NoError(t, err, out)
+
+	entries, err := os.ReadDir(tmp)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "block_3.sh", entries[0].Name())
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+)
+
+// Exit codes of the mdcode command, documented in the exit-codes help topic.
+const (
+	exitOK = iota
+	exitError
+	exitUsage
+	exitFailed
+	exitParse
+	exitDrift
+	exitNoMatch
+)
+
+// exitCodeOf returns the exit code for the error returned by the command.
+// With exitZero, failed code blocks, drift and empty selections are not
+// treated as errors.
+func exitCodeOf(err error, exitZero bool) int {
+	code := exitError
+
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errUsage), errors.Is(err, errTooManyArg),
+		errors.Is(err, errMissingArg), errors.Is(err, errAmbiguousArg):
+		return exitUsage
+	case errors.Is(err, mdcode.ErrParse):
+		return exitParse
+	case errors.Is(err, errBlocksFailed), errors.Is(err, errRole):
+		code = exitFailed
+	case errors.Is(err, errDrift):
+		code = exitDrift
+	case errors.Is(err, errNoMatch):
+		code = exitNoMatch
+	default:
+		return exitError
+	}
+
+	if exitZero {
+		return exitOK
+	}
+
+	return code
+}
+
+var (
+	errUsage        = errors.New("invalid usage")
+	errBlocksFailed = errors.New("code block(s) failed")
+	errNoMatch      = errors.New("no code block matches the filter")
+)
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/ezerfernandes/mdcode/internal/textenc"
+	"github.com/spf13/cobra"
+)
+
+//go:embed help/expand.md
+var expandHelp string
+
+func expandCmd(opts *options) *cobra.Command {
+	cmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "expand [flags] [filename]",
+		Short: "Generate a standalone markdown document with includes and noweb references resolved",
+		Long:  expandHelp,
+		Args:  checkargs,
+		PreRun: func(cmd *cobra.Command, _ []string) {
+			opts.createStatus(cmd.ErrOrStderr())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out, err := openOutput(opts.out, cmd)
+			if err != nil {
+				return err
+			}
+
+			if err = expandRun(opts.source(args), out, opts); err != nil {
+				return err
+			}
+
+			return closeOutput(out)
+		},
+
+		DisableAutoGenTag: true,
+	}
+
+	outputFlag(cmd, opts)
+
+	return cmd
+}
+
+func expandRun(filename string, out io.Writer, opts *options) error {
+	src, format, err := opts.readDocument(filename)
+	if err != nil {
+		return err
+	}
+
+	names := make(map[string][]byte)
+
+	_, _, err = mdcode.Walk(src, func(block *mdcode.Block) error {
+		if err := opts.resolveInclude(block); err != nil {
+			return fmt.Errorf("%s: %w", opts.location(filename, block.StartLine), err)
+		}
+
+		if name := block.Meta.Get(metaName); len(name) != 0 {
+			if _, has := names[name]; !has {
+				names[name] = block.Code
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	modified, res, err := mdcode.Walk(src, func(block *mdcode.Block) error {
+		if err := opts.resolveInclude(block); err != nil {
+			return err
+		}
+
+		code, err := expandNoweb(block.Code, names, nil)
+		if err != nil {
+			return fmt.Errorf("%s: %w", opts.location(filename, block.StartLine), err)
+		}
+
+		if len(block.Meta.Get(mdcode.MetaInclude)) == 0 {
+			block.Code = code
+
+			return nil
+		}
+
+		meta := make(mdcode.Meta, len(block.Meta))
+
+		for key, value := range block.Meta {
+			if key != mdcode.MetaInclude {
+				meta[key] = value
+			}
+		}
+
+		var buff bytes.Buffer
+
+		writeFenced(&buff, formatInfo(block.Lang, meta), code)
+
+		block.Remove()
+		block.InsertAfter(buff.Bytes())
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !modified {
+		res = src
+	}
+
+	data, err := textenc.Encode(res, format)
+	if err != nil {
+		return err
+	}
+
+	_, err = out.Write(data)
+
+	return err
+}
+
+// expandNoweb replaces the <<name>> noweb references of the code with the
+// code of the named code blocks, recursively. The text before the reference
+// is repeated on each inserted line, like the indentation. References to
+// unknown names are kept.
+func expandNoweb(code []byte, names map[string][]byte, stack []string) ([]byte, error) {
+	if !reNowebRef.Match(code) {
+		return code, nil
+	}
+
+	var buff bytes.Buffer
+
+	for _, line := range bytes.SplitAfter(code, []byte("\n")) {
+		match := reNowebRef.FindSubmatchIndex(line)
+		if match == nil {
+			buff.Write(line)
+
+			continue
+		}
+
+		name := string(line[match[2]:match[3]])
+
+		body, has := names[name]
+		if !has {
+			buff.Write(line)
+
+			continue
+		}
+
+		if slices.Contains(stack, name) {
+			return nil, fmt.Errorf("%w: %s", errNowebCycle, strings.Join(append(stack, name), " -> "))
+		}
+
+		body, err := expandNoweb(body, names, append(slices.Clone(stack), name))
+		if err != nil {
+			return nil, err
+		}
+
+		prefix := line[:match[0]]
+
+		for idx, bodyLine := range bytes.Split(bytes.TrimSuffix(body, []byte("\n")), []byte("\n")) {
+			if idx != 0 {
+				buff.WriteByte('\n')
+			}
+
+			buff.Write(prefix)
+			buff.Write(bodyLine)
+		}
+
+		buff.Write(line[match[1]:])
+	}
+
+	return buff.Bytes(), nil
+}
+
+var errNowebCycle = errors.New("noweb reference cycle")
@@ -10,6 +10,7 @@ import (
 
 	"github.com/ezerfernandes/mdcode/internal/mdcode"
 	"github.com/ezerfernandes/mdcode/internal/region"
+	"github.com/liamg/memoryfs"
 	"github.com/spf13/cobra"
 )
 
@@ -27,7 +28,22 @@ func extractCmd(opts *options) *cobra.Command {
 			opts.createStatus(cmd.ErrOrStderr())
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return extractRun(source(args), opts)
+			files, err := sources(args, opts)
+			if err != nil {
+				return err
+			}
+
+			if len(opts.archive) != 0 {
+				return extractArchive(cmd, files, opts)
+			}
+
+			return opts.withState(func() error {
+				return opts.eachSource(files, func(file string) error {
+					documentDir(cmd, opts, file)
+
+					return extractRun(file, opts)
+				})
+			})
 		},
 
 		DisableAutoGenTag: true,
@@ -35,6 +51,10 @@ func extractCmd(opts *options) *cobra.Command {
 
 	dirFlag(cmd, opts)
 	quietFlag(cmd, opts)
+	stampFlag(cmd, opts)
+
+	cmd.Flags().StringVar(&opts.archive, "archive", "", "package the files into an archive (.tar, .tar.gz, .tgz or .zip) instead of extracting them")
+	cobra.CheckErr(cmd.MarkFlagFilename("archive", "tar", "gz", "tgz", "zip"))
 
 	return cmd
 }
@@ -42,16 +62,102 @@ func extractCmd(opts *options) *cobra.Command {
 func extractRun(filename string, opts *options) error {
 	opts.status("Extracting code blocks from %s\n", filename)
 
-	src, err := os.ReadFile(filename)
+	src, _, err := opts.readDocument(filename)
 	if err != nil {
 		return err
 	}
 
+	eol := opts.lineEnding(src)
+
+	// The merged codes, written back to the document.
+	merged := make(map[int][]byte)
+
 	_, _, err = walk(src, func(block *mdcode.Block) error {
-		return save(block, opts.dir, opts.status)
+		if err := opts.confine(block); err != nil {
+			return err
+		}
+
+		block.Code = convertEOL(block.Code, eol)
+
+		if len(block.Meta.Get(metaRegion)) == 0 {
+			block.Code = opts.stampCode(filename, block, block.Code)
+		}
+
+		if opts.reconcileFile(filename, block, eol) {
+			merged[block.StartLine] = opts.unstampCode(filename, block, block.Code)
+		}
+
+		if err := save(block, opts.dir, opts.status); err != nil {
+			return err
+		}
+
+		if file := block.Meta.Get(metaFile); len(file) != 0 {
+			opts.event("block extracted", "document", filename, "line", block.StartLine, "file", file)
+		}
+
+		return nil
 	}, opts.filter)
+	if err != nil || len(merged) == 0 {
+		return err
+	}
 
-	return err
+	return opts.updateMerged(filename, merged)
+}
+
+// extractArchive packages the files of the code blocks from all markdown
+// documents into a single archive. The paths in the archive are the file
+// metadata, under the --dir directory if it is set.
+func extractArchive(cmd *cobra.Command, filenames []string, opts *options) error {
+	format, err := archiveFormat(opts.archive)
+	if err != nil {
+		return err
+	}
+
+	dir := ""
+	if cmd.Flag("dir").Changed {
+		dir = opts.dir
+	}
+
+	mfs := memoryfs.New()
+
+	for _, filename := range filenames {
+		opts.status("Archiving code blocks from %s\n", filename)
+
+		src, _, err := opts.readDocument(filename)
+		if err != nil {
+			return err
+		}
+
+		eol := opts.lineEnding(src)
+
+		_, _, err = walk(src, func(block *mdcode.Block) error {
+			if err := opts.confine(block); err != nil {
+				return err
+			}
+
+			block.Code = convertEOL(block.Code, eol)
+
+			if len(block.Meta.Get(metaRegion)) == 0 {
+				block.Code = opts.stampCode(filename, block, block.Code)
+			}
+
+			return dump(block, mfs, dir, opts.status)
+		}, opts.filter)
+		if err != nil {
+			return err
+		}
+	}
+
+	out, err := os.Create(opts.archive)
+	if err != nil {
+		return err
+	}
+
+	if err = writeArchive(mfs, out, format); err != nil {
+		return errors.Join(err, out.Close())
+	}
+
+	return out.Close()
 }
 
 func save(block *mdcode.Block, dir string, status statusFunc) error {
@@ -77,6 +183,10 @@ func save(block *mdcode.Block, dir string, status statusFunc) error {
 }
 
 func saveTransform(filename string, block *mdcode.Block, fsys fs.FS, status statusFunc) ([]byte, bool, error) {
+	if isPatch(block.Lang) {
+		return patchTransform(filename, block, fsys, status)
+	}
+
 	regionname := block.Meta.Get(metaRegion)
 	if len(regionname) == 0 {
 		status("%s\n", filename)
@@ -91,7 +201,7 @@ func saveTransform(filename string, block *mdcode.Block, fsys fs.FS, status stat
 		return nil, false, err
 	}
 
-	data, mod, err := region.Replace(orig, regionname, block.Code)
+	data, mod, err := region.Replace(orig, regionname, convertEOL(block.Code, detectEOL(orig)))
 	if err != nil {
 		return nil, false, err
 	}
@@ -111,4 +221,131 @@ func rel(basedir string, filename string) string {
 	return filepath.Join(basedir, filename)
 }
 
-var errMissingRegion = errors.New("missing region")
+// reconcileFile merges the code block with the changes of its file since
+// the last sync, and reports whether the document must get the merged code
+// too. Patches and outlines are not merged.
+func (o *options) reconcileFile(filename string, block *mdcode.Block, eol []byte) bool {
+	file := block.Meta.Get(metaFile)
+	if len(file) == 0 || isPatch(block.Lang) || block.Meta.Get(metaOutline) == "true" {
+		return false
+	}
+
+	var current []byte
+
+	probe := *block
+
+	if err := load(&probe, o.dir, nostatus); err == nil {
+		current = convertEOL(probe.Code, eol)
+	}
+
+	var merged bool
+
+	block.Code, merged = o.reconcile(filename, block, rel(o.dir, filepath.FromSlash(file)), current, block.Code, file, filename)
+
+	return merged
+}
+
+// updateMerged writes the merged codes (by start line) into the code blocks
+// of the document.
+func (o *options) updateMerged(filename string, codes map[int][]byte) (err error) {
+	lock, err := lockDocument(filename)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err = errors.Join(err, lock.unlock())
+	}()
+
+	src, format, err := o.readDocument(filename)
+	if err != nil {
+		return err
+	}
+
+	modified, res, err := walk(src, func(block *mdcode.Block) error {
+		if code, has := codes[block.StartLine]; has {
+			block.Code = code
+		}
+
+		return nil
+	}, o.filter)
+	if err != nil || !modified {
+		return err
+	}
+
+	return writeDocument(filename, res, format)
+}
+
+// confine checks that the file metadata of the code block is a local path,
+// so a malicious document can't access files outside of the base directory
+// (like ../../.bashrc or /etc/passwd), unless --allow-outside is given. The
+// check is not only lexical: a symbolic link (like out/link -> /tmp) can't
+// lead outside of the base directory either.
+func (o *options) confine(block *mdcode.Block) error {
+	return o.confineTo(o.dir, block)
+}
+
+// confineTo is confine with the files of the code block relative to the
+// base directory.
+func (o *options) confineTo(basedir string, block *mdcode.Block) error {
+	file := block.Meta.Get(metaFile)
+	if len(file) == 0 || o.allowOutside {
+		return nil
+	}
+
+	if filepath.IsLocal(filepath.FromSlash(file)) && !escapes(basedir, filepath.FromSlash(file)) {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s (line %d)", errOutside, file, block.StartLine)
+}
+
+// escapes reports whether the local filename leads outside of the base
+// directory through symbolic links. The longest existing part of the path is
+// resolved, the rest is created by mdcode as directories and files. Dangling
+// symbolic links are refused, writing through them could create the target.
+func escapes(basedir string, filename string) bool {
+	basedir = filepath.Clean(basedir)
+
+	root, err := filepath.EvalSymlinks(basedir)
+	if err != nil {
+		// The base directory doesn't exist yet, nor anything inside it.
+		return false
+	}
+
+	for path := filepath.Join(basedir, filename); ; path = filepath.Dir(path) {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return !within(root, resolved)
+		}
+
+		if !errors.Is(err, fs.ErrNotExist) || path == basedir {
+			return true
+		}
+
+		if _, err := os.Lstat(path); err == nil {
+			return true
+		}
+	}
+}
+
+// within reports whether the path is the root directory or inside it.
+func within(root string, path string) bool {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return false
+	}
+
+	if path, err = filepath.Abs(path); err != nil {
+		return false
+	}
+
+	relpath, err := filepath.Rel(root, path)
+
+	return err == nil && (relpath == "." || filepath.IsLocal(relpath))
+}
+
+var (
+	errMissingRegion = errors.New("missing region")
+	errOutside       = errors.New("file outside of the base directory (use --allow-outside to permit)")
+)
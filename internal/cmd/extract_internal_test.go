@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_options_confine(t *testing.T) {
+	t.Parallel()
+
+	outside := t.TempDir()
+	dir := filepath.Join(t.TempDir(), "out")
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0o750))
+	require.NoError(t, os.Symlink(outside, filepath.Join(dir, "link")))
+	require.NoError(t, os.Symlink("sub", filepath.Join(dir, "inside")))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "missing"), filepath.Join(dir, "dangling")))
+
+	opts := &options{dir: dir} //nolint:exhaustruct
+
+	confine := func(file string) error {
+		return opts.confine(&mdcode.Block{Meta: mdcode.Meta{metaFile: file}}) //nolint:exhaustruct
+	}
+
+	require.NoError(t, confine("main.go"))
+	require.NoError(t, confine("sub/main.go"))
+	require.NoError(t, confine("new/dir/main.go"))
+	require.NoError(t, confine("inside/main.go"))
+
+	require.ErrorIs(t, confine("../main.go"), errOutside)
+	require.ErrorIs(t, confine("/etc/passwd"), errOutside)
+	require.ErrorIs(t, confine("link/pwned.go"), errOutside)
+	require.ErrorIs(t, confine("link/new/pwned.go"), errOutside)
+	require.ErrorIs(t, confine("link"), errOutside)
+	require.ErrorIs(t, confine("dangling"), errOutside)
+	require.ErrorIs(t, confine("dangling/pwned.go"), errOutside)
+
+	opts.allowOutside = true
+
+	require.NoError(t, confine("link/pwned.go"))
+}
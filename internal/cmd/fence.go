@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/spf13/cobra"
+)
+
+//go:embed help/fence.md
+var fenceHelp string
+
+func fenceCmd(opts *options) *cobra.Command {
+	var heading bool
+
+	cmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "fence [flags] filename...",
+		Short: "Generate a markdown document from source files",
+		Long:  fenceHelp,
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out, err := openOutput(opts.out, cmd)
+			if err != nil {
+				return err
+			}
+
+			if err = fenceRun(args, out, heading); err != nil {
+				return err
+			}
+
+			return closeOutput(out)
+		},
+
+		DisableAutoGenTag: true,
+	}
+
+	outputFlag(cmd, opts)
+
+	cmd.Flags().BoolVar(&heading, "heading", false, "precede each code block with a heading containing the file name")
+
+	return cmd
+}
+
+func fenceRun(filenames []string, out io.Writer, heading bool) error {
+	var buff bytes.Buffer
+
+	for idx, filename := range filenames {
+		code, err := os.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+
+		if idx != 0 {
+			buff.WriteByte('\n')
+		}
+
+		name := filepath.ToSlash(filepath.Clean(filename))
+
+		if heading {
+			fmt.Fprintf(&buff, "## %s\n\n", name)
+		}
+
+		writeFenced(&buff, formatInfo(langFromFilename(filename), mdcode.Meta{metaFile: name}), code)
+	}
+
+	_, err := out.Write(buff.Bytes())
+
+	return err
+}
+
+// writeFenced writes a fenced code block. The fence is longer than any
+// backtick sequence in the code, so the code can't terminate the block.
+func writeFenced(buff *bytes.Buffer, info string, code []byte) {
+	fence := fenceFor(code)
+
+	buff.WriteString(fence + info + "\n")
+	buff.Write(code)
+
+	if len(code) != 0 && code[len(code)-1] != '\n' {
+		buff.WriteByte('\n')
+	}
+
+	buff.WriteString(fence + "\n")
+}
+
+func fenceFor(code []byte) string {
+	return fenceOf('`', code)
+}
+
+// fenceOf returns a fence of char longer than any sequence of char in code.
+func fenceOf(char byte, code []byte) string {
+	const minFence = 3
+
+	longest, run := 0, 0
+
+	for _, c := range code {
+		if c == char {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+
+	return strings.Repeat(string(char), max(minFence, longest+1))
+}
+
+// quoteMeta quotes a metadata value if it contains characters that would
+// split it.
+func quoteMeta(value string) string {
+	if len(value) != 0 && !strings.ContainsAny(value, " \t\"'\\") {
+		return value
+	}
+
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// formatInfo formats the info string of a code block from its language and
+// metadata, using the name="value" metadata format.
+func formatInfo(lang string, meta mdcode.Meta) string {
+	parts := []string{lang}
+
+	for _, key := range sortedKeys(meta) {
+		parts = append(parts, key+"="+quoteMeta(meta.Get(key)))
+	}
+
+	return strings.TrimSpace(strings.Join(parts, " "))
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+)
+
+// metaTitle is the metadata key of the code block title, which may be a file
+// name or a caption.
+const metaTitle = "title"
+
+// defaultFileKeys are the metadata keys used by documentation generators for
+// the file name of a code block, recognized when file metadata is missing.
+//
+//nolint:gochecknoglobals
+var defaultFileKeys = []string{"filename", metaTitle}
+
+// fileKeyed returns the filter completing the missing file metadata of the
+// code blocks from the first of the keys present before filtering.
+func fileKeyed(filter filterFunc, keys []string) filterFunc {
+	if len(keys) == 0 {
+		return filter
+	}
+
+	return func(block *mdcode.Block) bool {
+		if len(block.Meta.Get(metaFile)) == 0 {
+			for _, key := range keys {
+				if value := block.Meta.Get(key); isFileName(key, value) {
+					block.Meta[metaFile] = value
+
+					break
+				}
+			}
+		}
+
+		return filter(block)
+	}
+}
+
+// isFileName reports whether the value of the metadata key is a file name.
+// Values containing whitespace (like a title sentence) are not, and titles
+// without extension (like title=Setup) are captions rather than file names.
+func isFileName(key, value string) bool {
+	if len(value) == 0 || strings.ContainsAny(value, " \t") {
+		return false
+	}
+
+	return key != metaTitle || len(filepath.Ext(value)) > 1
+}
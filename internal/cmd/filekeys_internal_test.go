@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_fileKeyed(t *testing.T) {
+	t.Parallel()
+
+	filter := fileKeyed(func(*mdcode.Block) bool { return true }, defaultFileKeys)
+
+	tests := []struct {
+		meta mdcode.Meta
+		file string
+	}{
+		{meta: mdcode.Meta{"title": "hello.go"}, file: "hello.go"},
+		{meta: mdcode.Meta{"title": "Setup"}, file: ""},
+		{meta: mdcode.Meta{"title": "Hello world.go"}, file: ""},
+		{meta: mdcode.Meta{"filename": "Makefile"}, file: "Makefile"},
+		{meta: mdcode.Meta{"title": "Setup", "filename": "main.go"}, file: "main.go"},
+		{meta: mdcode.Meta{"file": "a.go", "title": "b.go"}, file: "a.go"},
+	}
+
+	for _, test := range tests {
+		block := &mdcode.Block{Meta: test.meta} //nolint:exhaustruct
+
+		require.True(t, filter(block))
+		require.Equal(t, test.file, block.Meta.Get(metaFile), test.meta)
+	}
+}
@@ -1,72 +1,90 @@
 package cmd
 
 import (
-	"fmt"
 	"strings"
 
 	"github.com/ezerfernandes/mdcode/internal/mdcode"
-	"github.com/gobwas/glob"
 )
 
-type filterFunc func(string, mdcode.Meta) bool
+type filterFunc = mdcode.BlockPredicate
 
-func filter(langs []string, metas map[string]string) (filterFunc, error) {
-	var (
-		langGlob glob.Glob
-		metaGlob map[string]glob.Glob
-	)
+// anyValue is the metadata filter pattern matching any value, including file
+// names containing path separators.
+const anyValue = "*"
 
-	comp, err := src2glob("", langs...)
+// filter returns the filter of the code blocks. A language pattern matches
+// the language of the code block or its canonical name, language aliases
+// given as pattern are replaced by the canonical name. A heading pattern
+// matches the heading of a section containing the code block.
+func filter(langs []string, metas map[string]string, headings []string, hidden bool, aliases langAliases) (filterFunc, error) {
+	preds := []mdcode.BlockPredicate{mdcode.Not(mdcode.IsSkipped)}
+
+	if !hidden {
+		preds = append(preds, mdcode.Not(mdcode.IsHidden))
+	}
+
+	patterns := make([]string, 0, len(langs))
+
+	for _, lang := range langs {
+		if canonical := aliases.canonical(lang); canonical != strings.ToLower(lang) {
+			lang = canonical
+		}
+
+		patterns = append(patterns, lang)
+	}
+
+	byLang, err := mdcode.ByLang(patterns...)
 	if err != nil {
 		return nil, err
 	}
 
-	langGlob = comp
-
-	metaGlob = make(map[string]glob.Glob)
+	preds = append(preds, mdcode.Or(byLang, canonicalLang(byLang, aliases)))
 
 	for key, value := range metas {
-		if len(value) != 0 {
-			comp, err = src2glob(key, value)
-			if err != nil {
-				return nil, err
-			}
-
-			metaGlob[key] = comp
+		if value == anyValue {
+			value = "**"
 		}
-	}
 
-	return func(lang string, meta mdcode.Meta) bool {
-		if langGlob != nil && !langGlob.Match(lang) {
-			return false
+		if len(value) == 0 {
+			continue
 		}
 
-		for k, g := range metaGlob {
-			v, has := meta[k]
-			if !has || !g.Match(fmt.Sprint(v)) {
-				return false
-			}
+		byMeta, err := mdcode.ByMeta(key, value)
+		if err != nil {
+			return nil, err
 		}
 
-		return true
-	}, nil
-}
+		preds = append(preds, byMeta)
+	}
 
-func src2glob(key string, src ...string) (glob.Glob, error) { //nolint:ireturn
-	if len(src) == 0 {
-		return nil, nil
+	byHeading, err := mdcode.ByHeading(headings...)
+	if err != nil {
+		return nil, err
 	}
 
-	var separators []rune
+	return mdcode.And(append(preds, byHeading)...), nil
+}
 
-	if key == metaFile {
-		separators = append(separators, '/', '\\')
-	}
+// canonicalLang returns the language predicate applied to the canonical name
+// of the language of the code blocks.
+func canonicalLang(pred mdcode.BlockPredicate, aliases langAliases) mdcode.BlockPredicate {
+	return func(block *mdcode.Block) bool {
+		canonical := *block
+		canonical.Lang = aliases.canonical(block.Lang)
 
-	g, err := glob.Compile(fmt.Sprintf("{%s}", strings.Join(src, ",")), separators...)
-	if err != nil {
-		return nil, err
+		return pred(&canonical)
 	}
+}
+
+// counted returns the filter counting the matching code blocks in matched.
+func counted(filter filterFunc, matched *int) filterFunc {
+	return func(block *mdcode.Block) bool {
+		if filter(block) {
+			*matched++
 
-	return g, nil
+			return true
+		}
+
+		return false
+	}
 }
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+)
+
+const (
+	formatText    = "text"
+	formatCompact = "compact"
+	formatCSV     = "csv"
+	formatTSV     = "tsv"
+	formatPick    = "pick"
+)
+
+func validFormat(format string) bool {
+	return format == formatText || format == formatCompact || format == formatCSV || format == formatTSV ||
+		format == formatPick
+}
+
+// delimited reports whether the listing is printed as comma or tab separated
+// values.
+func (o *options) delimited() bool {
+	return o.format == formatCSV || o.format == formatTSV
+}
+
+func (o *options) compact() bool {
+	return o.format == formatCompact
+}
+
+// location formats a position in a markdown document. In compact format the
+// column is added too, as expected by editors and quickfix lists.
+func (o *options) location(filename string, line int) string {
+	if o.compact() {
+		return fmt.Sprintf("%s:%d:1", filename, line)
+	}
+
+	return fmt.Sprintf("%s:%d", filename, line)
+}
+
+// listCompact prints one file:line:column line per block, followed by the
+// info string of the block.
+func listCompact(out io.Writer, blocks []*mdcode.Block, documents []string, opts *options) {
+	for idx, block := range blocks {
+		fmt.Fprintf(out, "%s: %s\n", opts.location(documents[idx], block.StartLine), formatInfo(block.Lang, block.Meta))
+	}
+}
+
+// listDelimited prints the blocks as comma (or tab) separated values with a
+// header row: the markdown document, the line, the identifier, the language and a column per
+// metadata key.
+func listDelimited(out io.Writer, blocks []*mdcode.Block, documents []string, opts *options) error {
+	writer := csv.NewWriter(out)
+	if opts.format == formatTSV {
+		writer.Comma = '\t'
+	}
+
+	keys := listedKeys(blocks)
+
+	if err := writer.Write(append([]string{"document", "line", metaID, "lang"}, keys...)); err != nil {
+		return err
+	}
+
+	for idx, block := range blocks {
+		record := []string{documents[idx], strconv.Itoa(block.StartLine), blockID(block), block.Lang}
+
+		for _, key := range keys {
+			record = append(record, block.Meta.Get(key))
+		}
+
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
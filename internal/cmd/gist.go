@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/liamg/memoryfs"
+	"github.com/spf13/cobra"
+)
+
+//go:embed help/gist.md
+var gistHelp string
+
+const (
+	gistAPI      = "https://api.github.com"
+	gistTokenEnv = "GITHUB_TOKEN"
+)
+
+type gistOptions struct {
+	description string
+	public      bool
+	api         string
+}
+
+func gistCmd(opts *options) *cobra.Command {
+	gopts := new(gistOptions)
+
+	cmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "gist [flags] [filename]",
+		Short: "Publish markdown code blocks as a GitHub gist",
+		Long:  gistHelp,
+		Args:  checkargs,
+		PreRun: func(cmd *cobra.Command, _ []string) {
+			opts.createStatus(cmd.ErrOrStderr())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files, err := sources(args, opts)
+			if err != nil {
+				return err
+			}
+
+			return gistRun(files, cmd.OutOrStdout(), opts, gopts)
+		},
+
+		DisableAutoGenTag: true,
+	}
+
+	quietFlag(cmd, opts)
+
+	cmd.Flags().StringVar(&gopts.description, "description", "", "description of the gist")
+	cmd.Flags().BoolVar(&gopts.public, "public", false, "create a public gist (default: secret)")
+	cmd.Flags().StringVar(&gopts.api, "api-url", gistAPI, "GitHub API base URL (for GitHub Enterprise)")
+
+	return cmd
+}
+
+// gistFile is a file of the gist creation request.
+type gistFile struct {
+	Content string `json:"content"`
+}
+
+func gistRun(filenames []string, out io.Writer, opts *options, gopts *gistOptions) error {
+	token := os.Getenv(gistTokenEnv)
+	if len(token) == 0 {
+		return errGistToken
+	}
+
+	files, err := gistFiles(filenames, opts)
+	if err != nil {
+		return err
+	}
+
+	if len(files) == 0 {
+		return errGistEmpty
+	}
+
+	description := gopts.description
+	if len(description) == 0 {
+		description = "Code blocks of " + strings.Join(filenames, ", ")
+	}
+
+	body, err := json.Marshal(map[string]any{"description": description, "public": gopts.public, "files": files})
+	if err != nil {
+		return err
+	}
+
+	url, err := createGist(strings.TrimSuffix(gopts.api, "/")+"/gists", token, body)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, url)
+
+	return nil
+}
+
+// gistFiles assembles the files of the code blocks (including regions) like
+// mdcode dump does, and returns them by base name, as gists are flat.
+func gistFiles(filenames []string, opts *options) (map[string]gistFile, error) {
+	mfs := memoryfs.New()
+
+	for _, filename := range filenames {
+		opts.status("Collecting code blocks from %s\n", filename)
+
+		src, _, err := opts.readDocument(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		_, _, err = walk(src, func(block *mdcode.Block) error {
+			return dump(block, mfs, "", opts.status)
+		}, opts.filter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	files := make(map[string]gistFile)
+	paths := make(map[string]string)
+
+	err := archiveFiles(mfs, func(name string, _ fs.FileInfo, body []byte) error {
+		base := path.Base(name)
+
+		if other, has := paths[base]; has {
+			return fmt.Errorf("%w: %s and %s", errGistName, other, name)
+		}
+
+		paths[base] = name
+		files[base] = gistFile{Content: string(body)}
+
+		return nil
+	})
+
+	return files, err
+}
+
+func createGist(url, token string, body []byte) (string, error) {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: fetchTimeout} //nolint:exhaustruct
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024)) //nolint:gomnd
+
+		return "", fmt.Errorf("%w: %s: %s", errGist, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var created struct {
+		HTMLURL string `json:"html_url"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return "", err
+	}
+
+	return created.HTMLURL, nil
+}
+
+var (
+	errGist      = errors.New("failed to create gist")
+	errGistToken = errors.New("the " + gistTokenEnv + " environment variable is not set")
+	errGistEmpty = errors.New("no code blocks with file metadata to publish")
+	errGistName  = errors.New("gist files must have different base names")
+)
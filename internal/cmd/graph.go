@@ -0,0 +1,270 @@
+package cmd
+
+import (
+	_ "embed"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/spf13/cobra"
+)
+
+//go:embed help/graph.md
+var graphHelp string
+
+const metaNeeds = "needs"
+
+var reNowebRef = regexp.MustCompile(`<<([\w.-]+)>>`)
+
+func graphCmd(opts *options) *cobra.Command {
+	var dot bool
+
+	cmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "graph [flags] [filename]",
+		Short: "Show the dependency graph of markdown code blocks",
+		Long:  graphHelp,
+		Args:  checkargs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			opts.createStatus(cmd.ErrOrStderr())
+
+			return opts.allBlocksFilter(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out, err := openOutput(opts.out, cmd)
+			if err != nil {
+				return err
+			}
+
+			files, err := sources(args, opts)
+			if err != nil {
+				return err
+			}
+
+			graph, err := buildGraph(files, opts)
+			if err != nil {
+				return err
+			}
+
+			if dot {
+				graph.writeDot(out)
+			} else {
+				graph.writeText(out)
+			}
+
+			return closeOutput(out)
+		},
+
+		DisableAutoGenTag: true,
+	}
+
+	quietFlag(cmd, opts)
+	outputFlag(cmd, opts)
+
+	cmd.Flags().BoolVar(&dot, "dot", false, "generate a Graphviz (DOT) graph")
+
+	return cmd
+}
+
+// graphNode is a code block or a file in the dependency graph.
+type graphNode struct {
+	id       string
+	label    string
+	document string
+	file     bool
+	missing  bool
+}
+
+// graphEdge is a dependency between two nodes: needs, ref (noweb reference)
+// or file (the file the code block is written to).
+type graphEdge struct {
+	from *graphNode
+	to   *graphNode
+	kind string
+}
+
+type blockGraph struct {
+	nodes     []*graphNode
+	index     map[string]*graphNode
+	edges     []graphEdge
+	documents []string
+}
+
+func (g *blockGraph) node(id, label string) *graphNode {
+	if node, has := g.index[id]; has {
+		return node
+	}
+
+	node := &graphNode{id: id, label: label} //nolint:exhaustruct
+
+	g.nodes = append(g.nodes, node)
+	g.index[id] = node
+
+	return node
+}
+
+// buildGraph collects the named code blocks, their needs metadata and noweb
+// references and their file metadata from the markdown documents. Code
+// blocks without any of these are left out of the graph.
+func buildGraph(files []string, opts *options) (*blockGraph, error) {
+	graph := &blockGraph{index: make(map[string]*graphNode)} //nolint:exhaustruct
+
+	type pending struct {
+		from *graphNode
+		name string
+		kind string
+	}
+
+	var refs []pending
+
+	for _, file := range files {
+		opts.status("Scanning code blocks in %s\n", file)
+
+		src, _, err := opts.readDocument(file)
+		if err != nil {
+			return nil, err
+		}
+
+		graph.documents = append(graph.documents, file)
+
+		_, _, err = walk(src, func(block *mdcode.Block) error {
+			name := block.Meta.Get(metaName)
+			needs := splitList(block.Meta.Get(metaNeeds))
+			target := block.Meta.Get(metaFile)
+
+			var nowebs []string
+
+			for _, match := range reNowebRef.FindAllSubmatch(block.Code, -1) {
+				nowebs = append(nowebs, string(match[1]))
+			}
+
+			if len(name) == 0 && len(needs) == 0 && len(nowebs) == 0 && len(target) == 0 {
+				return nil
+			}
+
+			var node *graphNode
+
+			if len(name) != 0 {
+				node = graph.node("name:"+name, name)
+			} else {
+				location := fmt.Sprintf("%s:%d", file, block.StartLine)
+				node = graph.node(location, location)
+			}
+
+			node.document, node.missing = file, false
+
+			for _, need := range needs {
+				refs = append(refs, pending{from: node, name: need, kind: metaNeeds})
+			}
+
+			for _, noweb := range nowebs {
+				refs = append(refs, pending{from: node, name: noweb, kind: "ref"})
+			}
+
+			if len(target) != 0 {
+				target = path.Clean(target)
+				to := graph.node("file:"+target, target)
+				to.file = true
+
+				graph.edges = append(graph.edges, graphEdge{from: node, to: to, kind: metaFile})
+			}
+
+			return nil
+		}, opts.filter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, ref := range refs {
+		to, has := graph.index["name:"+ref.name]
+		if !has {
+			to = graph.node("name:"+ref.name, ref.name)
+			to.missing = true
+		}
+
+		graph.edges = append(graph.edges, graphEdge{from: ref.from, to: to, kind: ref.kind})
+	}
+
+	return graph, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); len(item) != 0 {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// writeText prints one line per dependency.
+func (g *blockGraph) writeText(out io.Writer) {
+	for _, edge := range g.edges {
+		to := edge.to.label
+		if edge.to.missing {
+			to += " (missing)"
+		}
+
+		fmt.Fprintf(out, "%s -> %s [%s]\n", edge.from.label, to, edge.kind)
+	}
+}
+
+// writeDot prints the graph in Graphviz DOT format. The code blocks are
+// grouped into a cluster per markdown document, files are drawn as notes and
+// referenced but undefined code blocks with a dashed border.
+func (g *blockGraph) writeDot(out io.Writer) {
+	fmt.Fprintln(out, "digraph mdcode {")
+	fmt.Fprintln(out, "  rankdir=LR;")
+	fmt.Fprintln(out, "  node [shape=box];")
+
+	for idx, document := range g.documents {
+		var members []*graphNode
+
+		for _, node := range g.nodes {
+			if node.document == document && !node.file {
+				members = append(members, node)
+			}
+		}
+
+		if len(members) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(out, "  subgraph cluster_%d {\n", idx)
+		fmt.Fprintf(out, "    label=%s;\n", strconv.Quote(document))
+
+		for _, node := range members {
+			fmt.Fprintf(out, "    %s [label=%s];\n", strconv.Quote(node.id), strconv.Quote(node.label))
+		}
+
+		fmt.Fprintln(out, "  }")
+	}
+
+	for _, node := range g.nodes {
+		switch {
+		case node.file:
+			fmt.Fprintf(out, "  %s [label=%s, shape=note];\n", strconv.Quote(node.id), strconv.Quote(node.label))
+		case node.missing:
+			fmt.Fprintf(out, "  %s [label=%s, style=dashed];\n", strconv.Quote(node.id), strconv.Quote(node.label))
+		}
+	}
+
+	for _, edge := range g.edges {
+		style := ""
+		if edge.kind == metaFile {
+			style = ", style=dotted"
+		}
+
+		fmt.Fprintf(out, "  %s -> %s [label=%s%s];\n",
+			strconv.Quote(edge.from.id), strconv.Quote(edge.to.id), strconv.Quote(edge.kind), style)
+	}
+
+	fmt.Fprintln(out, "}")
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+)
+
+// parseMaxBlockSize parses the --max-block-size flag, 0 disables the limit.
+func (o *options) parseMaxBlockSize() error {
+	if o.maxBlockSize == "0" {
+		o.maxBlockBytes = 0
+
+		return nil
+	}
+
+	size, err := parseSize(o.maxBlockSize)
+	if err != nil {
+		return err
+	}
+
+	o.maxBlockBytes = size
+
+	return nil
+}
+
+// checkLimits refuses pathological markdown documents: documents having more
+// code blocks than --max-blocks, or a code block larger than
+// --max-block-size. The limits protect machines processing untrusted
+// documents (like CI runners) from hostile input, they are off by default.
+func (o *options) checkLimits(filename string, src []byte) error {
+	if o.maxBlocks <= 0 && o.maxBlockBytes <= 0 {
+		return nil
+	}
+
+	count := 0
+
+	_, _, err := mdcode.Walk(src, func(block *mdcode.Block) error {
+		count++
+
+		if o.maxBlocks > 0 && count > o.maxBlocks {
+			return fmt.Errorf("%w: %s has more than %d code blocks (use --max-blocks to raise the limit)",
+				errLimitExceeded, filename, o.maxBlocks)
+		}
+
+		if o.maxBlockBytes > 0 && int64(len(block.Code)) > o.maxBlockBytes {
+			return fmt.Errorf("%w: %s:%d code block of %d bytes is larger than %s (use --max-block-size to raise the limit)",
+				errLimitExceeded, filename, block.StartLine, len(block.Code), o.maxBlockSize)
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+var errLimitExceeded = errors.New("safety limit exceeded")
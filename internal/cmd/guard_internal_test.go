@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_options_checkLimits(t *testing.T) {
+	dir := t.TempDir()
+
+	src := strings.Repeat("```sh\necho hello\n```\n\n", 3)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "doc.md"), []byte(src), 0o600))
+
+	// The limits are off by default.
+	out, err := runMdcode(t, dir, "dump", "doc.md")
+	require.NoError(t, err, out)
+
+	out, err = runMdcode(t, dir, "dump", "--max-blocks", "2", "doc.md")
+	require.ErrorIs(t, err, errLimitExceeded, out)
+
+	out, err = runMdcode(t, dir, "dump", "--max-block-size", "5", "doc.md")
+	require.ErrorIs(t, err, errLimitExceeded, out)
+}
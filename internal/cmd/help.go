@@ -60,3 +60,36 @@ func invisibleTopic() *cobra.Command {
 		Long:  "Invisible code blocks\n\n" + invisibleHelp,
 	}
 }
+
+//go:embed help/recursive.md
+var recursiveHelp string
+
+func recursiveTopic() *cobra.Command {
+	return &cobra.Command{ //nolint:exhaustruct
+		Use:   "recursive",
+		Short: "Processing directory trees",
+		Long:  "Processing directory trees\n\n" + recursiveHelp,
+	}
+}
+
+//go:embed help/exitcodes.md
+var exitCodesHelp string
+
+func exitCodesTopic() *cobra.Command {
+	return &cobra.Command{ //nolint:exhaustruct
+		Use:   "exit-codes",
+		Short: "Exit codes",
+		Long:  "Exit codes\n\n" + exitCodesHelp,
+	}
+}
+
+//go:embed help/status.md
+var statusHelp string
+
+func statusTopic() *cobra.Command {
+	return &cobra.Command{ //nolint:exhaustruct
+		Use:   "status",
+		Short: "Status output and verbosity",
+		Long:  "Status output and verbosity\n\n" + statusHelp,
+	}
+}
@@ -0,0 +1,378 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rodaine/table"
+	"github.com/spf13/cobra"
+)
+
+//go:embed help/history.md
+var historyHelp string
+
+//nolint:gochecknoglobals
+var historyFilename = filepath.Join(stateDir, "history.jsonl")
+
+const defaultHistoryLimit = 10
+
+// historyRecord is an exec run recorded in the history journal.
+type historyRecord struct {
+	Time      time.Time         `json:"time"`
+	Commit    string            `json:"commit,omitempty"`
+	Dirty     bool              `json:"dirty,omitempty"`
+	Command   string            `json:"command,omitempty"`
+	Documents map[string]string `json:"documents"`
+	Passed    int               `json:"passed"`
+	Failed    int               `json:"failed"`
+	Skipped   int               `json:"skipped"`
+	Blocks    []*historyBlock   `json:"blocks"`
+}
+
+// historyBlock is the result of a code block (or a batch) in a recorded run.
+type historyBlock struct {
+	Document string        `json:"document"`
+	Line     int           `json:"line,omitempty"`
+	Title    string        `json:"title"`
+	Status   string        `json:"status"`
+	ExitCode int           `json:"exit_code"`
+	Duration time.Duration `json:"duration_ns,omitempty"`
+}
+
+func (b *historyBlock) key() string {
+	return b.Document + "\x00" + b.Title
+}
+
+// executed reports whether a code block (or a batch) of the report entries
+// was executed, not only skipped or taken from the cache.
+func executed(entries []*reportEntry) bool {
+	for _, entry := range entries {
+		if entry.Status == reportPassed || entry.Status == reportFailed {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newHistoryRecord(command string) *historyRecord {
+	run := &historyRecord{Time: time.Now().UTC().Truncate(time.Second), Command: command, Documents: make(map[string]string)} //nolint:exhaustruct
+
+	if out, err := gitOutput("rev-parse", "--short", "HEAD"); err == nil {
+		run.Commit = strings.TrimSpace(string(out))
+
+		if out, err := gitOutput("status", "--porcelain", "--untracked-files=no"); err == nil {
+			run.Dirty = len(strings.TrimSpace(string(out))) != 0
+		}
+	}
+
+	return run
+}
+
+// document records the hash of the executed markdown document.
+func (h *historyRecord) document(filename string, src []byte) {
+	if h == nil {
+		return
+	}
+
+	sum := sha256.Sum256(src)
+
+	h.Documents[filepath.ToSlash(filename)] = hex.EncodeToString(sum[:])
+}
+
+// record appends the run with the results of the report entries to the
+// history journal. Runs without executed code blocks (like refused untrusted
+// documents) are not recorded.
+func (h *historyRecord) record(filename string, entries []*reportEntry) error {
+	if h == nil || len(h.Documents) == 0 || !executed(entries) {
+		return nil
+	}
+
+	for _, entry := range entries {
+		switch entry.Status {
+		case reportPassed:
+			h.Passed++
+		case reportFailed:
+			h.Failed++
+		default:
+			h.Skipped++
+		}
+
+		h.Blocks = append(h.Blocks, &historyBlock{
+			Document: filepath.ToSlash(entry.Document),
+			Line:     entry.StartLine,
+			Title:    entry.Title,
+			Status:   entry.Status,
+			ExitCode: entry.ExitCode,
+			Duration: entry.Duration,
+		})
+	}
+
+	data, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), dirMode); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, fileMode)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(append(data, '\n'))
+
+	return errors.Join(err, file.Close())
+}
+
+func loadHistory(filename string) ([]*historyRecord, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	defer file.Close()
+
+	var runs []*historyRecord
+
+	dec := json.NewDecoder(file)
+
+	for {
+		run := new(historyRecord)
+
+		if err := dec.Decode(run); err != nil {
+			if errors.Is(err, io.EOF) {
+				return runs, nil
+			}
+
+			return nil, fmt.Errorf("%w: %s: %w", errHistory, filename, err)
+		}
+
+		runs = append(runs, run)
+	}
+}
+
+type historyOptions struct {
+	limit       int
+	failed      bool
+	regressions bool
+}
+
+func historyCmd(opts *options) *cobra.Command {
+	hopts := new(historyOptions)
+
+	cmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "history [flags] [filename]",
+		Short: "Query the execution history of the code blocks",
+		Long:  historyHelp,
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			runs, err := loadHistory(historyFilename)
+			if err != nil {
+				return err
+			}
+
+			if len(args) != 0 {
+				runs = documentRuns(runs, filepath.ToSlash(filepath.Clean(args[0])))
+			}
+
+			return historyRun(runs, cmd.OutOrStdout(), opts, hopts)
+		},
+
+		DisableAutoGenTag: true,
+	}
+
+	cmd.Flags().IntVarP(&hopts.limit, "limit", "n", defaultHistoryLimit, "number of most recent runs shown (0 for all)")
+	cmd.Flags().BoolVar(&hopts.failed, "failed", false, "show only the runs having failed code blocks")
+	cmd.Flags().BoolVar(&hopts.regressions, "regressions", false, "show the code blocks failing after passing in the previous run")
+	cmd.Flags().BoolVar(&opts.json, "json", false, "generate JSON output")
+
+	return cmd
+}
+
+// documentRuns returns the runs of the markdown document, with its code
+// blocks only.
+func documentRuns(runs []*historyRecord, document string) []*historyRecord {
+	var found []*historyRecord
+
+	for _, run := range runs {
+		if _, has := run.Documents[document]; !has {
+			continue
+		}
+
+		filtered := *run
+		filtered.Documents = map[string]string{document: run.Documents[document]}
+		filtered.Blocks = nil
+		filtered.Passed, filtered.Failed, filtered.Skipped = 0, 0, 0
+
+		for _, block := range run.Blocks {
+			if block.Document != document {
+				continue
+			}
+
+			filtered.Blocks = append(filtered.Blocks, block)
+
+			switch block.Status {
+			case reportPassed:
+				filtered.Passed++
+			case reportFailed:
+				filtered.Failed++
+			default:
+				filtered.Skipped++
+			}
+		}
+
+		found = append(found, &filtered)
+	}
+
+	return found
+}
+
+// regression is a code block failing after passing in the previous run.
+type regression struct {
+	run      *historyRecord
+	previous *historyRecord
+	block    *historyBlock
+}
+
+func findRegressions(runs []*historyRecord) []*regression {
+	var (
+		found []*regression
+		last  = make(map[string]*historyRecord)
+		state = make(map[string]string)
+	)
+
+	for _, run := range runs {
+		for _, block := range run.Blocks {
+			key := block.key()
+
+			if block.Status == reportFailed && state[key] == reportPassed {
+				found = append(found, &regression{run: run, previous: last[key], block: block})
+			}
+
+			if block.Status == reportPassed || block.Status == reportFailed {
+				state[key], last[key] = block.Status, run
+			}
+		}
+	}
+
+	return found
+}
+
+func historyRun(runs []*historyRecord, out io.Writer, opts *options, hopts *historyOptions) error {
+	if hopts.regressions {
+		return printRegressions(out, findRegressions(runs), opts, hopts.limit)
+	}
+
+	if hopts.failed {
+		var failed []*historyRecord
+
+		for _, run := range runs {
+			if run.Failed != 0 {
+				failed = append(failed, run)
+			}
+		}
+
+		runs = failed
+	}
+
+	if hopts.limit > 0 && len(runs) > hopts.limit {
+		runs = runs[len(runs)-hopts.limit:]
+	}
+
+	if opts.json {
+		enc := json.NewEncoder(out)
+
+		for _, run := range runs {
+			if err := enc.Encode(run); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	tbl := newHistoryTable(out, "time", "commit", "documents", "passed", "failed", "skipped")
+
+	for _, run := range runs {
+		tbl.AddRow(run.Time.Local().Format(time.DateTime), commitLabel(run), len(run.Documents), run.Passed, run.Failed, run.Skipped)
+	}
+
+	tbl.Print()
+
+	return nil
+}
+
+func printRegressions(out io.Writer, regressions []*regression, opts *options, limit int) error {
+	if limit > 0 && len(regressions) > limit {
+		regressions = regressions[len(regressions)-limit:]
+	}
+
+	if opts.json {
+		enc := json.NewEncoder(out)
+
+		for _, r := range regressions {
+			err := enc.Encode(map[string]any{
+				"time": r.run.Time, "commit": r.run.Commit, "previous_time": r.previous.Time,
+				"previous_commit": r.previous.Commit, "block": r.block,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	tbl := newHistoryTable(out, "time", "commit", "passed at", "location", "block")
+
+	for _, r := range regressions {
+		tbl.AddRow(r.run.Time.Local().Format(time.DateTime), commitLabel(r.run), commitLabel(r.previous),
+			opts.location(r.block.Document, r.block.Line), r.block.Title)
+	}
+
+	tbl.Print()
+
+	return nil
+}
+
+func newHistoryTable(out io.Writer, columns ...interface{}) table.Table {
+	tbl := table.New(columns...).WithWriter(out)
+
+	tbl.WithHeaderFormatter(func(format string, vals ...interface{}) string {
+		return strings.ToUpper(fmt.Sprintf(format, vals...))
+	})
+
+	return tbl
+}
+
+// commitLabel returns the git commit of the run, marked with a + if the
+// working tree had uncommitted changes.
+func commitLabel(run *historyRecord) string {
+	if len(run.Commit) == 0 {
+		return "-"
+	}
+
+	if run.Dirty {
+		return run.Commit + "+"
+	}
+
+	return run.Commit
+}
+
+var errHistory = errors.New("invalid history journal")
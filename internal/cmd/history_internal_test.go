@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_historyRecord_not_executed(t *testing.T) {
+	dir := t.TempDir()
+	src := "```sh\necho run\n```\n"
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "doc.md"), []byte(src), 0o600))
+
+	out, err := runMdcode(t, dir, "exec", "doc.md", "--", "true")
+	require.ErrorIs(t, err, errUntrusted, out)
+	require.NoFileExists(t, filepath.Join(dir, historyFilename))
+
+	out, err = runMdcode(t, dir, "exec", "--yes", "--lang", "go", "doc.md", "--", "true")
+	require.NoError(t, err, out)
+	require.NoFileExists(t, filepath.Join(dir, historyFilename))
+
+	out, err = runMdcode(t, dir, "exec", "--yes", "doc.md", "--", "true")
+	require.NoError(t, err, out)
+	require.FileExists(t, filepath.Join(dir, historyFilename))
+}
@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/ezerfernandes/mdcode/internal/textenc"
+	"github.com/spf13/cobra"
+)
+
+//go:embed help/hook.md
+var hookHelp string
+
+func hookCmd(opts *options) *cobra.Command {
+	cmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "hook",
+		Short: "Git pre-commit hook integration",
+		Long:  hookHelp,
+
+		DisableAutoGenTag: true,
+	}
+
+	cmd.AddCommand(hookInstallCmd(), hookRunCmd(opts))
+
+	return cmd
+}
+
+func hookInstallCmd() *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "install [flags]",
+		Short: "Install mdcode as git pre-commit hook",
+		Long: "Install mdcode as git pre-commit hook\n\n" +
+			"Creates a `pre-commit` hook script in the git repository of the current directory, " +
+			"which runs `mdcode hook run`. An existing hook is only overwritten with the `--force` flag.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return hookInstall(cmd.ErrOrStderr(), force)
+		},
+
+		DisableAutoGenTag: true,
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "overwrite existing pre-commit hook")
+
+	return cmd
+}
+
+func hookRunCmd(opts *options) *cobra.Command {
+	cmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "run [flags] [filename...]",
+		Short: "Check markdown files for drift and formatting",
+		Long: "Check markdown files for drift and formatting\n\n" +
+			"Checks that the code blocks of the markdown files are in sync with the files named in their `file` metadata, " +
+			"and that their fences are in the canonical style of `mdcode normalize`. " +
+			"Each out of sync or not normalized code block is reported in a `filename:line: message` line " +
+			"and the command exits with a non-zero status.\n\n" +
+			"Without arguments, the markdown files staged for commit are checked, " +
+			"using the staged content of the documents and of the files named in their metadata. " +
+			"Otherwise the named files are checked, which is how pre-commit frameworks pass the changed files.",
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			return opts.allBlocksFilter(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return hookRun(args, cmd.OutOrStdout(), opts)
+		},
+
+		DisableAutoGenTag: true,
+	}
+
+	return cmd
+}
+
+const preCommitScript = `#!/bin/sh
+# Installed by mdcode hook install.
+exec mdcode hook run
+`
+
+func hookInstall(stderr io.Writer, force bool) error {
+	out, err := gitOutput("rev-parse", "--git-path", "hooks/pre-commit")
+	if err != nil {
+		return err
+	}
+
+	filename := strings.TrimSpace(string(out))
+
+	if _, err = os.Stat(filename); err == nil && !force {
+		return fmt.Errorf("%w: %s", errHookExists, filename)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(filename), dirMode); err != nil {
+		return err
+	}
+
+	if err = os.WriteFile(filename, []byte(preCommitScript), execMode); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stderr, "pre-commit hook installed to %s\n", filename)
+
+	return nil
+}
+
+func hookRun(files []string, out io.Writer, opts *options) error {
+	read := os.ReadFile
+
+	if len(files) == 0 {
+		staged, err := stagedMarkdown()
+		if err != nil {
+			return err
+		}
+
+		files, read = staged, stagedContent
+	}
+
+	var drifted, unnormalized int
+
+	for _, file := range files {
+		data, err := read(file)
+		if err != nil {
+			return err
+		}
+
+		src, _, err := textenc.Decode(data, opts.encoding)
+		if err != nil {
+			return err
+		}
+
+		// The includes of the code blocks are relative to the document.
+		opts.document = file
+
+		drifts, err := checkDrift(src, filepath.Dir(file), opts.filter, read)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+
+		for _, d := range drifts {
+			fmt.Fprintf(out, "%s: %s\n", opts.location(file, d.block.StartLine), d.message())
+		}
+
+		drifted += len(drifts)
+
+		_, _, err = walk(src, func(block *mdcode.Block) error {
+			if !isCanonical(block, '`', opts.aliases) {
+				fmt.Fprintf(out, "%s: code block not in canonical style\n", opts.location(file, block.StartLine))
+
+				unnormalized++
+			}
+
+			return nil
+		}, opts.filter)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+	}
+
+	var errs []error
+
+	if drifted != 0 {
+		errs = append(errs, fmt.Errorf("%w: %d code block(s), run mdcode update", errDrift, drifted))
+	}
+
+	if unnormalized != 0 {
+		errs = append(errs, fmt.Errorf("%w: %d code block(s), run mdcode normalize", errNotNormalized, unnormalized))
+	}
+
+	return errors.Join(errs...)
+}
+
+func gitOutput(args ...string) ([]byte, error) {
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) != 0 {
+			return nil, fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// stagedMarkdown returns the markdown files staged for commit, relative to
+// the current directory.
+func stagedMarkdown() ([]string, error) {
+	out, err := gitOutput("diff", "--cached", "--name-only", "--relative", "--diff-filter=ACMR", "--", "*.md")
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.Fields(string(out)), nil
+}
+
+// stagedContent returns the content of the file (relative to the current
+// directory) in the git index. Files missing from the index are reported as
+// not existing.
+func stagedContent(filename string) ([]byte, error) {
+	path := filepath.ToSlash(filepath.Clean(filename))
+	if !strings.HasPrefix(path, "../") {
+		path = "./" + path
+	}
+
+	if _, err := gitOutput("ls-files", "--error-unmatch", "--", path); err != nil {
+		return nil, fmt.Errorf("%w: %s", fs.ErrNotExist, filename)
+	}
+
+	return gitOutput("show", ":"+path)
+}
+
+var (
+	errHookExists = errors.New("pre-commit hook already exists (use --force to overwrite)")
+	errDrift      = errors.New("drift detected")
+)
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_hookRun_eol(t *testing.T) {
+	dir := t.TempDir()
+
+	src := "```go file=main.go\r\npackage main\r\n```\r\n"
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "doc.md"), []byte(src), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o600))
+
+	out, err := runMdcode(t, dir, "hook", "run", "doc.md")
+	require.NoError(t, err, out)
+}
+
+func Test_hookRun_include(t *testing.T) {
+	dir := t.TempDir()
+	docs := filepath.Join(dir, "docs")
+
+	require.NoError(t, os.Mkdir(docs, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(docs, "common.md"), []byte("```go name=setup file=main.go\npackage other\n```\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(docs, "doc.md"), []byte("<!-- mdcode:include common.md#setup -->\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(docs, "main.go"), []byte("package main\n"), 0o600))
+
+	out, err := runMdcode(t, dir, "hook", "run", "docs/doc.md")
+	require.ErrorIs(t, err, errDrift)
+	require.Contains(t, out, "code block is out of sync with main.go")
+}
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/ezerfernandes/mdcode/internal/textenc"
+)
+
+// metaIncludeError holds the reason a code block could not be included. The
+// walk fails on such code blocks, if they are selected.
+const metaIncludeError = "include-error"
+
+// including returns the filter resolving the include metadata of the code
+// blocks before filtering: the code (and missing language and metadata) of
+// the named code block of the referenced document is taken.
+func including(filter filterFunc, opts *options) filterFunc {
+	return func(block *mdcode.Block) bool {
+		if err := opts.resolveInclude(block); err != nil {
+			block.Meta[metaIncludeError] = err.Error()
+		}
+
+		return filter(block)
+	}
+}
+
+// resolveInclude takes the code of the code block referenced by the include
+// metadata of the block, if any.
+func (o *options) resolveInclude(block *mdcode.Block) error {
+	ref := block.Meta.Get(mdcode.MetaInclude)
+	if len(ref) == 0 {
+		return nil
+	}
+
+	included, err := o.includedBlock(o.document, ref, make(map[string]bool))
+	if err != nil {
+		return err
+	}
+
+	applyInclude(block, included)
+
+	return nil
+}
+
+// includeFailure returns the error of a code block which could not be
+// included.
+func includeFailure(block *mdcode.Block) error {
+	if msg := block.Meta.Get(metaIncludeError); len(msg) != 0 {
+		return fmt.Errorf("line %d: %w: %s", block.StartLine, errInclude, msg)
+	}
+
+	return nil
+}
+
+func applyInclude(block, included *mdcode.Block) {
+	block.Code = bytes.Clone(included.Code)
+
+	if len(block.Lang) == 0 {
+		block.Lang = included.Lang
+	}
+
+	if block.Meta == nil {
+		block.Meta = make(mdcode.Meta)
+	}
+
+	for key, value := range included.Meta {
+		if _, has := block.Meta[key]; !has && key != mdcode.MetaInclude {
+			block.Meta[key] = value
+		}
+	}
+}
+
+// includedBlock returns the code block referenced as document#name from the
+// markdown document, following the includes of the referenced code block.
+func (o *options) includedBlock(document, ref string, seen map[string]bool) (*mdcode.Block, error) {
+	path, name, _ := strings.Cut(ref, "#")
+	if len(name) == 0 {
+		return nil, fmt.Errorf("%w: %s", errIncludeRef, ref)
+	}
+
+	target, err := includePath(document, path)
+	if err != nil {
+		return nil, err
+	}
+
+	key := target + "#" + name
+	if seen[key] {
+		return nil, fmt.Errorf("%w: %s", errIncludeCycle, key)
+	}
+
+	seen[key] = true
+
+	blocks, err := o.includeBlocks(target)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, block := range blocks {
+		if block.Virtual || block.Meta.Get(metaName) != name {
+			continue
+		}
+
+		if inner := block.Meta.Get(mdcode.MetaInclude); len(inner) != 0 {
+			included, err := o.includedBlock(target, inner, seen)
+			if err != nil {
+				return nil, err
+			}
+
+			resolved := *block
+			applyInclude(&resolved, included)
+
+			return &resolved, nil
+		}
+
+		return block, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", errIncludeMissing, key)
+}
+
+// includePath returns the path of the referenced document, relative to the
+// directory of the including document (or URL). An empty path is the
+// including document itself.
+func includePath(document, path string) (string, error) {
+	switch {
+	case len(path) == 0:
+		return document, nil
+	case isURL(path):
+		return path, nil
+	case isURL(document):
+		base, err := url.Parse(document)
+		if err != nil {
+			return "", err
+		}
+
+		rel, err := url.Parse(path)
+		if err != nil {
+			return "", err
+		}
+
+		return base.ResolveReference(rel).String(), nil
+	case filepath.IsAbs(path):
+		return path, nil
+	default:
+		return filepath.Join(filepath.Dir(document), filepath.FromSlash(path)), nil
+	}
+}
+
+// includeBlocks returns the code blocks of the referenced document, parsed
+// once per run.
+func (o *options) includeBlocks(filename string) (mdcode.Blocks, error) {
+	if blocks, has := o.includes[filename]; has {
+		return blocks, nil
+	}
+
+	read := os.ReadFile
+	if isURL(filename) {
+		read = o.fetch
+	}
+
+	data, err := read(filename)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInclude, err)
+	}
+
+	src, _, err := textenc.Decode(data, o.encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks, err := unfence(src, anyBlock)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	if o.includes == nil {
+		o.includes = make(map[string]mdcode.Blocks)
+	}
+
+	o.includes[filename] = blocks
+
+	return blocks, nil
+}
+
+var (
+	errInclude        = errors.New("can't include code block")
+	errIncludeRef     = errors.New("invalid include reference (use document#name)")
+	errIncludeCycle   = errors.New("include cycle")
+	errIncludeMissing = errors.New("no code block to include")
+)
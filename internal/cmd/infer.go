@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+)
+
+// keywordLangs are the patterns recognizing the language of unlabeled code
+// blocks, tried in order.
+//
+//nolint:gochecknoglobals
+var keywordLangs = []struct {
+	lang    string
+	pattern *regexp.Regexp
+}{
+	{"go", regexp.MustCompile(`(?m)^package \w+\s*$|^func \w+\(.*\) .*\{\s*$`)},
+	{"rust", regexp.MustCompile(`(?m)^\s*(pub )?fn \w+\(.*\).*\{\s*$|^\s*let mut \w+|^use \w+::`)},
+	{"python", regexp.MustCompile(`(?m)^\s*def \w+\(.*\):\s*$|^from [\w.]+ import |^import \w+\s*$|^\s*print\(`)},
+	{"js", regexp.MustCompile(`(?m)\bconsole\.log\(|\brequire\(['"]|^\s*(const|let) \w+ = |^\s*function \w+\(|=> \{`)},
+	{"html", regexp.MustCompile(`(?i)^\s*(<!doctype html|<html)`)},
+	{"sql", regexp.MustCompile(`(?i)^\s*(select .+ from |create table |insert into |update \w+ set |delete from )`)},
+	{"sh", regexp.MustCompile(`(?m)^\s*(\$ )?(echo|cd|export|ls|mkdir|rm|cp|mv|curl|wget|git|npm|npx|go|pip|pip3|docker|make|sudo|apt|apt-get|brew|cat)( |$)`)},
+	{"yaml", regexp.MustCompile(`\A(---\n)?[\w-]+:( .*)?\n[\w-]+:( .*)?(\n|\z)`)},
+}
+
+// interpreterLangs maps the interpreters of shebang lines to languages.
+//
+//nolint:gochecknoglobals
+var interpreterLangs = map[string]string{
+	"sh":      "sh",
+	"bash":    "sh",
+	"zsh":     "sh",
+	"dash":    "sh",
+	"python":  "python",
+	"python3": "python",
+	"node":    "js",
+	"deno":    "ts",
+	"ruby":    "ruby",
+	"perl":    "perl",
+	"php":     "php",
+	"lua":     "lua",
+	"pwsh":    "powershell",
+}
+
+// inferLang guesses the language of a code block from the extension of its
+// file metadata, its shebang line or characteristic keywords. It returns an
+// empty string if the language is not recognized.
+func inferLang(block *mdcode.Block) string {
+	if lang := langFromFilename(block.Meta.Get(metaFile)); len(lang) != 0 {
+		return lang
+	}
+
+	code := bytes.TrimSpace(block.Code)
+
+	if line, found := bytes.CutPrefix(code, []byte("#!")); found {
+		if idx := bytes.IndexByte(line, '\n'); idx >= 0 {
+			line = line[:idx]
+		}
+
+		fields := strings.Fields(string(line))
+		if len(fields) > 1 && path.Base(fields[0]) == "env" {
+			fields = fields[1:]
+		}
+
+		if len(fields) != 0 {
+			if lang, ok := interpreterLangs[path.Base(fields[0])]; ok {
+				return lang
+			}
+		}
+	}
+
+	if (bytes.HasPrefix(code, []byte("{")) || bytes.HasPrefix(code, []byte("["))) && json.Valid(code) {
+		return "json"
+	}
+
+	for _, keyword := range keywordLangs {
+		if keyword.pattern.Match(code) {
+			return keyword.lang
+		}
+	}
+
+	return ""
+}
+
+// inferring returns the filter completing the language of unlabeled code
+// blocks with the inferred language before filtering, if enabled.
+func inferring(filter filterFunc, enabled bool) filterFunc {
+	if !enabled {
+		return filter
+	}
+
+	return func(block *mdcode.Block) bool {
+		if len(block.Lang) == 0 {
+			block.Lang = inferLang(block)
+		}
+
+		return filter(block)
+	}
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	_ "embed"
+	"errors"
+	"strings"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/spf13/cobra"
+)
+
+//go:embed help/label.md
+var labelHelp string
+
+func labelCmd(opts *options) *cobra.Command {
+	cmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "label [flags] [filename]",
+		Short: "Write the inferred language into unlabeled code fences",
+		Long:  labelHelp,
+		Args:  checkargs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			opts.createStatus(cmd.ErrOrStderr())
+
+			// The filter must see the code blocks unlabeled.
+			opts.inferLang = false
+
+			if err := opts.createFilter(cmd); err != nil {
+				return err
+			}
+
+			return opts.allBlocksFilter(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files, err := sources(args, opts)
+			if err != nil {
+				return err
+			}
+
+			return opts.eachSource(files, func(file string) error {
+				return labelRun(file, opts)
+			})
+		},
+
+		DisableAutoGenTag: true,
+	}
+
+	quietFlag(cmd, opts)
+
+	return cmd
+}
+
+func labelRun(filename string, opts *options) (err error) {
+	opts.status("Labeling code blocks in %s\n", filename)
+
+	lock, err := lockDocument(filename)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err = errors.Join(err, lock.unlock())
+	}()
+
+	src, format, err := opts.readDocument(filename)
+	if err != nil {
+		return err
+	}
+
+	modified, res, err := walk(src, func(block *mdcode.Block) error {
+		if len(block.Lang) != 0 || len(block.Fence) == 0 {
+			return nil
+		}
+
+		lang := inferLang(block)
+		if len(lang) == 0 {
+			opts.status("%s: language not recognized\n", opts.location(filename, block.StartLine))
+
+			return nil
+		}
+
+		opts.status("%s: %s\n", opts.location(filename, block.StartLine), lang)
+		opts.event("block labeled", "document", filename, "line", block.StartLine, "lang", lang)
+
+		block.Info = strings.TrimSpace(lang + " " + block.Info)
+
+		return nil
+	}, opts.filter)
+	if err != nil {
+		return err
+	}
+
+	if modified {
+		return writeDocument(filename, res, format)
+	}
+
+	return nil
+}
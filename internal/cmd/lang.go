@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+//nolint:gochecknoglobals
+var extensionLangs = map[string]string{
+	".bash":  "bash",
+	".c":     "c",
+	".cc":    "cpp",
+	".cpp":   "cpp",
+	".cs":    "csharp",
+	".css":   "css",
+	".go":    "go",
+	".h":     "c",
+	".hpp":   "cpp",
+	".html":  "html",
+	".java":  "java",
+	".js":    "js",
+	".json":  "json",
+	".jsx":   "jsx",
+	".kt":    "kotlin",
+	".lua":   "lua",
+	".md":    "markdown",
+	".mjs":   "js",
+	".php":   "php",
+	".pl":    "perl",
+	".ps1":   "powershell",
+	".py":    "python",
+	".rb":    "ruby",
+	".rs":    "rust",
+	".scala": "scala",
+	".sh":    "sh",
+	".sql":   "sql",
+	".swift": "swift",
+	".toml":  "toml",
+	".ts":    "ts",
+	".tsx":   "tsx",
+	".xml":   "xml",
+	".yaml":  "yaml",
+	".yml":   "yaml",
+	".zsh":   "zsh",
+}
+
+//nolint:gochecknoglobals
+var nameLangs = map[string]string{
+	"dockerfile": "dockerfile",
+	"makefile":   "makefile",
+	"go.mod":     "go-mod",
+}
+
+// langTools maps the canonical languages to the program needed to execute
+// (or render) their code blocks.
+//
+//nolint:gochecknoglobals
+var langTools = map[string]string{
+	"sh":         "sh",
+	"zsh":        "zsh",
+	"go":         "go",
+	"python":     "python3",
+	"js":         "node",
+	"ts":         "deno",
+	"ruby":       "ruby",
+	"perl":       "perl",
+	"php":        "php",
+	"lua":        "lua",
+	"powershell": "pwsh",
+	"rust":       "rustc",
+	"java":       "java",
+	"kotlin":     "kotlinc",
+	"scala":      "scala",
+	"swift":      "swift",
+	"c":          "cc",
+	"cpp":        "c++",
+	"csharp":     "dotnet",
+	"dockerfile": "docker",
+	"mermaid":    "mmdc",
+	"plantuml":   "plantuml",
+	"dot":        "dot",
+}
+
+// langRunners maps the canonical languages to the built-in commands running
+// a code block with mdcode run --block, {} is the path of the code block.
+//
+//nolint:gochecknoglobals
+var langRunners = map[string]string{
+	"sh":     "bash {}",
+	"go":     "go run {}",
+	"python": "python3 {}",
+	"js":     "node {}",
+	"ts":     "deno run {}",
+	"ruby":   "ruby {}",
+	"perl":   "perl {}",
+	"php":    "php {}",
+	"lua":    "lua {}",
+}
+
+// builtinAliases maps the alternative names of languages to the canonical
+// names used by the language tables.
+//
+//nolint:gochecknoglobals
+var builtinAliases = map[string]string{
+	"bash":       "sh",
+	"shell":      "sh",
+	"javascript": "js",
+	"node":       "js",
+	"golang":     "go",
+	"py":         "python",
+	"python3":    "python",
+	"typescript": "ts",
+	"rb":         "ruby",
+	"rs":         "rust",
+	"yml":        "yaml",
+	"c++":        "cpp",
+	"cs":         "csharp",
+	"c#":         "csharp",
+	"kt":         "kotlin",
+	"ps1":        "powershell",
+	"pwsh":       "powershell",
+	"md":         "markdown",
+	"patch":      "diff",
+
+	"shell-session": "console",
+	"shellsession":  "console",
+	"terminal":      "console",
+}
+
+// langAliases holds the language aliases given with --lang-alias, which
+// complete (and override) the built-in aliases.
+type langAliases map[string]string
+
+func newLangAliases(aliases map[string]string) langAliases {
+	result := make(langAliases, len(aliases))
+
+	for alias, lang := range aliases {
+		result[strings.ToLower(alias)] = strings.ToLower(lang)
+	}
+
+	return result
+}
+
+// canonical returns the canonical name of the language, used for filtering
+// and for looking up the language tables.
+func (a langAliases) canonical(lang string) string {
+	lang = strings.ToLower(lang)
+
+	if alias, ok := a[lang]; ok {
+		lang = alias
+	}
+
+	if alias, ok := builtinAliases[lang]; ok {
+		return alias
+	}
+
+	return lang
+}
+
+// langExtension returns the file name extension of the language. Languages
+// without a known extension get their name as extension.
+func langExtension(lang string) string {
+	if len(lang) == 0 {
+		return ".txt"
+	}
+
+	if extensionLangs["."+lang] == lang {
+		return "." + lang
+	}
+
+	var ext string
+
+	for e, l := range extensionLangs {
+		if l == lang && (len(ext) == 0 || len(e) < len(ext) || (len(e) == len(ext) && e < ext)) {
+			ext = e
+		}
+	}
+
+	if len(ext) == 0 {
+		return "." + lang
+	}
+
+	return ext
+}
+
+// langFromFilename infers the code block language from a file name.
+// It returns an empty string for unknown file types.
+func langFromFilename(name string) string {
+	base := strings.ToLower(filepath.Base(name))
+
+	if lang, ok := nameLangs[base]; ok {
+		return lang
+	}
+
+	return extensionLangs[filepath.Ext(base)]
+}
+
+//nolint:gochecknoglobals
+var commentSyntax = map[string][2]string{
+	"bash":       {"# ", ""},
+	"c":          {"// ", ""},
+	"cpp":        {"// ", ""},
+	"csharp":     {"// ", ""},
+	"css":        {"/* ", " */"},
+	"dockerfile": {"# ", ""},
+	"go":         {"// ", ""},
+	"html":       {"<!-- ", " -->"},
+	"java":       {"// ", ""},
+	"js":         {"// ", ""},
+	"jsx":        {"// ", ""},
+	"kotlin":     {"// ", ""},
+	"lua":        {"-- ", ""},
+	"makefile":   {"# ", ""},
+	"markdown":   {"<!-- ", " -->"},
+	"md":         {"<!-- ", " -->"},
+	"perl":       {"# ", ""},
+	"php":        {"// ", ""},
+	"powershell": {"# ", ""},
+	"python":     {"# ", ""},
+	"py":         {"# ", ""},
+	"ruby":       {"# ", ""},
+	"rust":       {"// ", ""},
+	"scala":      {"// ", ""},
+	"sh":         {"# ", ""},
+	"sql":        {"-- ", ""},
+	"swift":      {"// ", ""},
+	"toml":       {"# ", ""},
+	"ts":         {"// ", ""},
+	"tsx":        {"// ", ""},
+	"xml":        {"<!-- ", " -->"},
+	"yaml":       {"# ", ""},
+	"zsh":        {"# ", ""},
+}
+
+// comment returns text as a single line comment of the language, or false if
+// the comment syntax of the language is unknown.
+func comment(lang, text string) (string, bool) {
+	syntax, ok := commentSyntax[strings.ToLower(lang)]
+	if !ok {
+		return "", false
+	}
+
+	return syntax[0] + text + syntax[1], true
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"mvdan.cc/sh/v3/interp"
+)
+
+// limits are the resource limits of the commands executed on code blocks.
+type limits struct {
+	maxOutput int64
+	maxMemory string
+	nice      int
+}
+
+func (l *limits) supported() bool {
+	return runtime.GOOS != "windows"
+}
+
+// runnerOptions returns the interpreter options applying the memory and
+// scheduling priority limits to the external programs started by the command.
+func (l *limits) runnerOptions() ([]interp.RunnerOption, error) {
+	prefix, err := l.prefix()
+	if err != nil || len(prefix) == 0 {
+		return nil, err
+	}
+
+	return []interp.RunnerOption{interp.ExecHandlers(func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+		return func(ctx context.Context, args []string) error {
+			return next(ctx, append(append([]string{}, prefix...), args...))
+		}
+	})}, nil
+}
+
+// prefix returns the command line prefix applying the memory and scheduling
+// priority limits to a program.
+func (l *limits) prefix() ([]string, error) {
+	if (l.nice == 0 && len(l.maxMemory) == 0) || !l.supported() {
+		return nil, nil
+	}
+
+	var prefix []string
+
+	if len(l.maxMemory) != 0 {
+		size, err := parseSize(l.maxMemory)
+		if err != nil {
+			return nil, err
+		}
+
+		const kilo = 1024
+
+		prefix = append(prefix, "sh", "-c", fmt.Sprintf(`ulimit -v %d && exec "$@"`, size/kilo), "sh")
+	}
+
+	if l.nice != 0 {
+		prefix = append(prefix, "nice", "-n", strconv.Itoa(l.nice))
+	}
+
+	return prefix, nil
+}
+
+// parseSize parses a byte size with an optional K, M or G suffix.
+func parseSize(value string) (int64, error) {
+	multiplier := int64(1)
+	number := strings.ToUpper(strings.TrimSpace(value))
+
+	for idx, suffix := range []string{"K", "M", "G"} {
+		if trimmed, ok := strings.CutSuffix(strings.TrimSuffix(number, "B"), suffix); ok {
+			number = trimmed
+			multiplier = 1 << (10 * (idx + 1)) //nolint:gomnd
+
+			break
+		}
+	}
+
+	size, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("%w: %s", errInvalidSize, value)
+	}
+
+	return size * multiplier, nil
+}
+
+// outputLimit is a byte budget shared by the standard output and error of a
+// command. Output exceeding the budget is discarded.
+type outputLimit struct {
+	max       int64
+	written   int64
+	truncated bool
+	mu        sync.Mutex
+}
+
+func newOutputLimit(max int64) *outputLimit {
+	return &outputLimit{max: max} //nolint:exhaustruct
+}
+
+func (o *outputLimit) wrap(w io.Writer) io.Writer {
+	if o.max <= 0 {
+		return w
+	}
+
+	return &limitWriter{limit: o, w: w}
+}
+
+type limitWriter struct {
+	limit *outputLimit
+	w     io.Writer
+}
+
+func (l *limitWriter) Write(data []byte) (int, error) {
+	l.limit.mu.Lock()
+	defer l.limit.mu.Unlock()
+
+	remaining := l.limit.max - l.limit.written
+	if int64(len(data)) > remaining {
+		l.limit.truncated = true
+
+		if remaining <= 0 {
+			return len(data), nil
+		}
+
+		if _, err := l.w.Write(data[:remaining]); err != nil {
+			return 0, err
+		}
+
+		l.limit.written += remaining
+
+		return len(data), nil
+	}
+
+	l.limit.written += int64(len(data))
+
+	return l.w.Write(data)
+}
+
+var errInvalidSize = errors.New("invalid size")
@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/spf13/cobra"
+)
+
+//go:embed help/lint.md
+var lintHelp string
+
+// finding is a problem reported by a lint rule, Line is relative to the
+// first line of the code block.
+type finding struct {
+	Line int
+	Msg  string
+}
+
+// lintRule checks the content of a code block.
+type lintRule struct {
+	name  string
+	check func(code []byte) []finding
+}
+
+// lintRules are the available rules in the order they are applied.
+//
+//nolint:gochecknoglobals
+var lintRules = []lintRule{
+	{name: "secrets", check: checkSecrets},
+}
+
+func lintCmd(opts *options) *cobra.Command {
+	var names []string
+
+	cmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "lint [flags] [filename]",
+		Short: "Check the content of markdown code blocks",
+		Long:  lintHelp,
+		Args:  checkargs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			opts.createStatus(cmd.ErrOrStderr())
+
+			return opts.allBlocksFilter(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rules, err := selectRules(names)
+			if err != nil {
+				return err
+			}
+
+			files, err := sources(args, opts)
+			if err != nil {
+				return err
+			}
+
+			var count int
+
+			err = opts.eachSource(files, func(file string) error {
+				n, err := lintRun(file, cmd.OutOrStdout(), opts, rules)
+				count += n
+
+				return err
+			})
+			if err != nil {
+				return err
+			}
+
+			if count != 0 {
+				return fmt.Errorf("%w: %d problem(s)", errLint, count)
+			}
+
+			return nil
+		},
+
+		DisableAutoGenTag: true,
+	}
+
+	quietFlag(cmd, opts)
+
+	cmd.Flags().StringSliceVar(&names, "rule", nil, "apply only the given lint rules (default all)")
+
+	return cmd
+}
+
+// selectRules returns the lint rules with the given names, or all rules if
+// names is empty.
+func selectRules(names []string) ([]lintRule, error) {
+	if len(names) == 0 {
+		return lintRules, nil
+	}
+
+	rules := make([]lintRule, 0, len(names))
+
+	for _, name := range names {
+		idx := -1
+
+		for i := range lintRules {
+			if lintRules[i].name == name {
+				idx = i
+			}
+		}
+
+		if idx < 0 {
+			return nil, fmt.Errorf("%w: %s", errLintRule, name)
+		}
+
+		rules = append(rules, lintRules[idx])
+	}
+
+	return rules, nil
+}
+
+// lintRun applies the lint rules to the code blocks of the markdown
+// document, reports the problems with the document line and returns their
+// number.
+func lintRun(filename string, out io.Writer, opts *options, rules []lintRule) (int, error) {
+	opts.status("Linting code blocks in %s\n", filename)
+
+	src, _, err := opts.readDocument(filename)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+
+	_, _, err = walk(src, func(block *mdcode.Block) error {
+		for _, rule := range rules {
+			for _, found := range rule.check(block.Code) {
+				count++
+
+				line := block.StartLine + found.Line
+
+				fmt.Fprintf(out, "%s: %s: %s\n", opts.location(filename, line), rule.name, found.Msg)
+				opts.event("lint problem", "document", filename, "line", line, "rule", rule.name, "problem", found.Msg)
+			}
+		}
+
+		return nil
+	}, opts.filter)
+
+	return count, err
+}
+
+// secretPatterns are the credential patterns of the secrets rule.
+//
+//nolint:gochecknoglobals
+var secretPatterns = []struct {
+	re   *regexp.Regexp
+	desc string
+}{
+	{regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`), "AWS access key ID"},
+	{regexp.MustCompile(`(?i)aws_?secret_?access_?key\s*[:=]\s*["']?[A-Za-z0-9/+=]{40}\b`), "AWS secret access key"},
+	{regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )*PRIVATE KEY(?: BLOCK)?-----`), "private key"},
+	{regexp.MustCompile(`\b[Bb]earer\s+[A-Za-z0-9\-._~+/]{20,}=*`), "bearer token"},
+	{regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`), "GitHub token"},
+	{regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`), "Slack token"},
+}
+
+// checkSecrets reports the lines of code matching a credential pattern.
+// Matches containing a placeholder word (like the EXAMPLE suffix of the AWS
+// documentation keys) are not reported.
+func checkSecrets(code []byte) []finding {
+	var found []finding
+
+	for idx, line := range bytes.Split(code, []byte("\n")) {
+		for _, pattern := range secretPatterns {
+			match := pattern.re.Find(line)
+			if match == nil || isPlaceholder(string(match)) {
+				continue
+			}
+
+			found = append(found, finding{Line: idx + 1, Msg: "possible " + pattern.desc})
+
+			break
+		}
+	}
+
+	return found
+}
+
+func isPlaceholder(secret string) bool {
+	upper := strings.ToUpper(secret)
+
+	for _, word := range []string{"EXAMPLE", "XXXX", "PLACEHOLDER", "REDACTED", "YOUR"} {
+		if strings.Contains(upper, word) {
+			return true
+		}
+	}
+
+	return false
+}
+
+var (
+	errLint     = errors.New("lint problems")
+	errLintRule = errors.New("unknown lint rule")
+)
@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"os"
 	"sort"
 	"strings"
 
@@ -12,36 +11,87 @@ import (
 	"github.com/rodaine/table"
 )
 
-func listRun(filename string, out io.Writer, opts *options) error {
-	src, err := os.ReadFile(filename)
-	if err != nil {
-		return err
-	}
+func listRun(filenames []string, out io.Writer, opts *options) error {
+	var (
+		blocks    mdcode.Blocks
+		documents []string
+	)
 
-	blocks, err := unfence(src, func(lang string, meta mdcode.Meta) bool {
-		if isScript(lang, meta) {
-			return true
+	for _, filename := range filenames {
+		src, _, err := opts.readDocument(filename)
+		if err != nil {
+			return err
 		}
 
-		return opts.filter(lang, meta)
-	})
-	if err != nil {
-		return err
+		found, err := unfence(src, func(block *mdcode.Block) bool {
+			if isScript(block.Lang, block.Meta) {
+				return true
+			}
+
+			return opts.filter(block)
+		})
+		if err != nil {
+			return err
+		}
+
+		if opts.blame && !isURL(filename) {
+			lines, err := blameLines(filename)
+			if err != nil {
+				return err
+			}
+
+			for _, block := range found {
+				addBlame(block, lines)
+			}
+		}
+
+		blocks = append(blocks, found...)
+
+		for range found {
+			documents = append(documents, filename)
+		}
+	}
+
+	if opts.compact() {
+		listCompact(out, blocks, documents, opts)
+
+		return nil
+	}
+
+	if opts.format == formatPick {
+		listPick(out, blocks, documents)
+
+		return nil
+	}
+
+	if opts.delimited() {
+		return listDelimited(out, blocks, documents, opts)
+	}
+
+	if !opts.recursive {
+		documents = nil
 	}
 
 	if opts.json {
-		return listJSON(out, blocks)
+		return listJSON(out, blocks, documents)
 	}
 
-	listTabular(out, blocks)
+	listTabular(out, blocks, documents)
 
 	return nil
 }
 
-func listTabular(out io.Writer, blocks []*mdcode.Block) {
-	keys := metaKeys(blocks)
-	ikeys := make([]interface{}, 0, len(keys)+1)
-	ikeys = append(ikeys, "lang")
+// listTabular prints the blocks as a table. If documents is not nil, it
+// contains the markdown document of each block, printed in the first column.
+func listTabular(out io.Writer, blocks []*mdcode.Block, documents []string) {
+	keys := listedKeys(blocks)
+	ikeys := make([]interface{}, 0, len(keys)+2) //nolint:gomnd
+
+	if documents != nil {
+		ikeys = append(ikeys, "document")
+	}
+
+	ikeys = append(ikeys, metaID, "lang")
 
 	for _, k := range keys {
 		ikeys = append(ikeys, k)
@@ -53,9 +103,14 @@ func listTabular(out io.Writer, blocks []*mdcode.Block) {
 		return strings.ToUpper(fmt.Sprintf(format, vals...))
 	})
 
-	for _, block := range blocks {
+	for idx, block := range blocks {
 		vals := make([]interface{}, 0, len(ikeys))
-		vals = append(vals, block.Lang)
+
+		if documents != nil {
+			vals = append(vals, documents[idx])
+		}
+
+		vals = append(vals, blockID(block), block.Lang)
 
 		for _, key := range keys {
 			var value interface{}
@@ -75,14 +130,24 @@ func listTabular(out io.Writer, blocks []*mdcode.Block) {
 	tbl.Print()
 }
 
-func listJSON(out io.Writer, blocks []*mdcode.Block) error {
+func listJSON(out io.Writer, blocks []*mdcode.Block, documents []string) error {
 	enc := json.NewEncoder(out)
 
-	for _, b := range blocks {
+	for idx, b := range blocks {
+		if b.Meta == nil {
+			b.Meta = make(mdcode.Meta)
+		}
+
 		if len(b.Lang) != 0 {
 			b.Meta["lang"] = b.Lang
 		}
 
+		b.Meta[metaID] = blockID(b)
+
+		if documents != nil {
+			b.Meta["document"] = documents[idx]
+		}
+
 		if err := enc.Encode(b.Meta); err != nil {
 			return err
 		}
@@ -100,6 +165,26 @@ func metaKeys(blocks mdcode.Blocks) []string {
 		}
 	}
 
+	return sortedKeys(keyset)
+}
+
+// listedKeys returns the metadata keys listed in separate columns, without
+// the id metadata, which is listed as the identifier of the code block.
+func listedKeys(blocks mdcode.Blocks) []string {
+	keys := metaKeys(blocks)
+
+	for idx, key := range keys {
+		if key == metaID {
+			return append(keys[:idx], keys[idx+1:]...)
+		}
+	}
+
+	return keys
+}
+
+// sortedKeys returns the metadata keys with the well-known keys first,
+// followed by the others in alphabetical order.
+func sortedKeys[T any](keyset map[string]T) []string {
 	keys := make([]string, 0, len(keyset))
 	idx := 0
 
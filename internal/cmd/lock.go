@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	lockSuffix  = ".mdcode-lock"
+	lockTimeout = 30 * time.Second
+	lockRetry   = 100 * time.Millisecond
+)
+
+// documentLock is an advisory lock of a markdown document, held while the
+// document is read, processed and written back, so that concurrent mdcode
+// invocations can't interleave their writes. The lock is an operating system
+// lock (flock or LockFileEx) of a hidden file next to the document, released
+// by the operating system when the process exits, even when it is killed.
+type documentLock struct {
+	file *os.File
+}
+
+func lockFilename(document string) string {
+	return filepath.Join(filepath.Dir(document), "."+filepath.Base(document)+lockSuffix)
+}
+
+// lockDocument takes the lock of the markdown document, waiting for another
+// invocation to release it.
+func lockDocument(document string) (*documentLock, error) {
+	if isURL(document) {
+		return nil, fmt.Errorf("%w: %s", errRemote, document)
+	}
+
+	filename := lockFilename(document)
+	deadline := time.Now().Add(lockTimeout)
+
+	for {
+		lock, err := tryLockFile(filename)
+		if err != nil || lock != nil {
+			return lock, err
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w: %s", errLocked, document)
+		}
+
+		time.Sleep(lockRetry)
+	}
+}
+
+// tryLockFile takes the lock of the lock file without waiting. It returns a
+// nil lock if the file is locked by another process. The lock file is
+// removed by its holder on unlock: a file removed (or replaced) between its
+// opening and its locking is opened again.
+func tryLockFile(filename string) (*documentLock, error) {
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_RDWR, fileMode)
+	if err != nil {
+		return nil, err
+	}
+
+	locked, err := lockFile(file)
+	if err != nil || !locked {
+		return nil, errors.Join(err, file.Close())
+	}
+
+	opened, err := file.Stat()
+	if err != nil {
+		return nil, errors.Join(err, unlockFile(file), file.Close())
+	}
+
+	if current, err := os.Stat(filename); err != nil || !os.SameFile(opened, current) {
+		if err := errors.Join(unlockFile(file), file.Close()); err != nil {
+			return nil, err
+		}
+
+		return tryLockFile(filename)
+	}
+
+	return &documentLock{file: file}, nil
+}
+
+func (l *documentLock) unlock() error {
+	return errors.Join(removeLockFile(l.file), unlockFile(l.file), l.file.Close())
+}
+
+var errLocked = errors.New("markdown document is locked by another mdcode process")
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_lockDocument(t *testing.T) {
+	t.Parallel()
+
+	doc := filepath.Join(t.TempDir(), "doc.md")
+
+	lock, err := lockDocument(doc)
+	require.NoError(t, err)
+
+	other, err := tryLockFile(lockFilename(doc))
+	require.NoError(t, err)
+	require.Nil(t, other)
+
+	require.NoError(t, lock.unlock())
+
+	if runtime.GOOS != "windows" {
+		require.NoFileExists(t, lockFilename(doc))
+	}
+
+	// A lock file left behind by a killed process is not locked.
+	require.NoError(t, os.WriteFile(lockFilename(doc), []byte("1\n"), 0o600))
+
+	lock, err = tryLockFile(lockFilename(doc))
+	require.NoError(t, err)
+	require.NotNil(t, lock)
+	require.NoError(t, lock.unlock())
+}
@@ -0,0 +1,30 @@
+//go:build unix
+
+package cmd
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// lockFile takes the exclusive flock of the file without waiting, and
+// reports whether it is taken.
+func lockFile(file *os.File) (bool, error) {
+	err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
+
+// removeLockFile removes the locked file, before it is unlocked: the waiting
+// processes notice that they locked a removed file.
+func removeLockFile(file *os.File) error {
+	return os.Remove(file.Name())
+}
@@ -0,0 +1,34 @@
+//go:build windows
+
+package cmd
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockFile takes the exclusive lock of the file without waiting, and reports
+// whether it is taken.
+func lockFile(file *os.File) (bool, error) {
+	overlapped := new(windows.Overlapped)
+
+	err := windows.LockFileEx(windows.Handle(file.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY,
+		0, 1, 0, overlapped)
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func unlockFile(file *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, new(windows.Overlapped))
+}
+
+// removeLockFile leaves the lock file in place: an open file can't be removed
+// on Windows, and a left lock file is not locked.
+func removeLockFile(_ *os.File) error {
+	return nil
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// logDir returns the directory of the --output-dir log files of the markdown
+// document. When several documents are executed, each gets a subdirectory
+// named after its path, so that the log files of their blocks don't collide.
+func (e *execOptions) logDir(filename string, opts *options) string {
+	return perDocument(e.outputDir, filename, opts)
+}
+
+// perDocument returns the directory of the files of the markdown document
+// under root: a subdirectory named after the document path with --recursive,
+// root itself otherwise.
+func perDocument(root, filename string, opts *options) string {
+	if !opts.recursive {
+		return root
+	}
+
+	if filepath.IsLocal(filename) {
+		return filepath.Join(root, filename)
+	}
+
+	return filepath.Join(root, filepath.Base(filename))
+}
+
+// openLogs creates the <name>.out and <name>.err log files of the command
+// being executed, or returns nil files if no log name is set.
+func (e *execOptions) openLogs() (*os.File, *os.File, error) {
+	if len(e.logName) == 0 {
+		return nil, nil, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(e.logName), dirMode); err != nil {
+		return nil, nil, err
+	}
+
+	stdout, err := os.Create(e.logName + ".out")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stderr, err := os.Create(e.logName + ".err")
+	if err != nil {
+		return nil, nil, errors.Join(err, stdout.Close())
+	}
+
+	return stdout, stderr, nil
+}
+
+// setLogName sets the name of the log files of the next command, if
+// --output-dir is used.
+func (e *execOptions) setLogName(dir, format string, args ...any) {
+	if len(e.outputDir) == 0 {
+		e.logName = ""
+
+		return
+	}
+
+	e.logName = filepath.Join(dir, fmt.Sprintf(format, args...))
+}
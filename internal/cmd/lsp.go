@@ -0,0 +1,452 @@
+package cmd
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"unicode/utf16"
+
+	"github.com/ezerfernandes/mdcode/internal/lsp"
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/spf13/cobra"
+)
+
+//go:embed help/lsp.md
+var lspHelp string
+
+func lspCmd() *cobra.Command {
+	cmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "lsp",
+		Short: "Start a language server on standard input/output",
+		Long:  lspHelp,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return lspRun(cmd.InOrStdin(), cmd.OutOrStdout())
+		},
+
+		DisableAutoGenTag: true,
+	}
+
+	return cmd
+}
+
+const (
+	lspSource      = "mdcode"
+	lspCommandRun  = "mdcode.run"
+	lspCommandSync = "mdcode.sync"
+)
+
+type lspServer struct {
+	conn     *lsp.Conn
+	docs     map[string][]byte
+	shutdown bool
+
+	// root is the workspace root directory, the files of the code blocks
+	// must be inside it.
+	root string
+}
+
+func lspRun(in io.Reader, out io.Writer) error {
+	server := &lspServer{conn: lsp.NewConn(in, out), docs: make(map[string][]byte), shutdown: false, root: ""}
+
+	for {
+		msg, err := server.conn.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			return err
+		}
+
+		if msg.Method == "exit" {
+			return nil
+		}
+
+		if err = server.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *lspServer) handle(msg *lsp.Message) error {
+	if !msg.IsRequest() && !msg.IsNotification() {
+		return nil
+	}
+
+	result, err := s.dispatch(msg)
+
+	if msg.IsNotification() {
+		if err != nil {
+			return s.conn.Notify("window/logMessage", lsp.ShowMessageParams{Type: lsp.MessageError, Message: err.Error()})
+		}
+
+		return nil
+	}
+
+	if err != nil {
+		var rerr *lsp.ResponseError
+		if errors.As(err, &rerr) {
+			return s.conn.ReplyError(msg.ID, rerr.Code, rerr.Message)
+		}
+
+		return s.conn.ReplyError(msg.ID, lsp.CodeInternalError, err.Error())
+	}
+
+	return s.conn.Reply(msg.ID, result)
+}
+
+func (s *lspServer) dispatch(msg *lsp.Message) (any, error) {
+	switch msg.Method {
+	case "initialize":
+		var params lsp.InitializeParams
+		if len(msg.Params) != 0 {
+			if err := unmarshalParams(msg, &params); err != nil {
+				return nil, err
+			}
+		}
+
+		if len(params.RootURI) != 0 {
+			s.root = lsp.URIToPath(params.RootURI)
+		}
+
+		return s.initialize(), nil
+	case "shutdown":
+		s.shutdown = true
+
+		return nil, nil
+	case "textDocument/didOpen":
+		var params lsp.DidOpenTextDocumentParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+
+		s.docs[params.TextDocument.URI] = []byte(params.TextDocument.Text)
+
+		return nil, s.publish(params.TextDocument.URI)
+	case "textDocument/didChange":
+		var params lsp.DidChangeTextDocumentParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+
+		if n := len(params.ContentChanges); n != 0 {
+			s.docs[params.TextDocument.URI] = []byte(params.ContentChanges[n-1].Text)
+		}
+
+		return nil, s.publish(params.TextDocument.URI)
+	case "textDocument/didSave":
+		var params lsp.TextDocumentParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+
+		return nil, s.publish(params.TextDocument.URI)
+	case "textDocument/didClose":
+		var params lsp.TextDocumentParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+
+		delete(s.docs, params.TextDocument.URI)
+
+		return nil, s.conn.Notify("textDocument/publishDiagnostics",
+			lsp.PublishDiagnosticsParams{URI: params.TextDocument.URI, Diagnostics: []lsp.Diagnostic{}})
+	case "textDocument/codeLens":
+		return s.withDocument(msg, s.codeLenses)
+	case "textDocument/documentSymbol":
+		return s.withDocument(msg, s.symbols)
+	case "workspace/executeCommand":
+		var params lsp.ExecuteCommandParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+
+		return nil, s.execute(&params)
+	}
+
+	if msg.IsRequest() && !s.shutdown {
+		return nil, &lsp.ResponseError{Code: lsp.CodeMethodNotFound, Message: "method not found: " + msg.Method}
+	}
+
+	return nil, nil
+}
+
+func unmarshalParams(msg *lsp.Message, params any) error {
+	if err := json.Unmarshal(msg.Params, params); err != nil {
+		return &lsp.ResponseError{Code: lsp.CodeInvalidParams, Message: err.Error()}
+	}
+
+	return nil
+}
+
+func (s *lspServer) initialize() any {
+	return map[string]any{
+		"capabilities": map[string]any{
+			"textDocumentSync":       map[string]any{"openClose": true, "change": 1, "save": true},
+			"codeLensProvider":       map[string]any{"resolveProvider": false},
+			"documentSymbolProvider": true,
+			"executeCommandProvider": map[string]any{"commands": []string{lspCommandRun, lspCommandSync}},
+		},
+		"serverInfo": map[string]any{"name": appname, "version": version},
+	}
+}
+
+func (s *lspServer) withDocument(msg *lsp.Message, handler func(uri string, blocks mdcode.Blocks) any) (any, error) {
+	var params lsp.TextDocumentParams
+	if err := unmarshalParams(msg, &params); err != nil {
+		return nil, err
+	}
+
+	blocks, err := mdcode.Unfence(s.docs[params.TextDocument.URI])
+	if err != nil {
+		return []any{}, nil //nolint:nilerr
+	}
+
+	return handler(params.TextDocument.URI, blocks), nil
+}
+
+func blockRange(block *mdcode.Block) lsp.Range {
+	return lsp.LineRange(block.StartLine-1, block.EndLine+1)
+}
+
+func (s *lspServer) codeLenses(uri string, blocks mdcode.Blocks) any {
+	lenses := make([]lsp.CodeLens, 0, len(blocks))
+
+	for idx, block := range blocks {
+		rng := lsp.LineRange(block.StartLine-1, block.StartLine-1)
+
+		if reShell.MatchString(block.Lang) {
+			lenses = append(lenses, lsp.CodeLens{Range: rng, Command: &lsp.Command{
+				Title: "Run block", Command: lspCommandRun, Arguments: []any{uri, idx + 1},
+			}})
+		}
+
+		if len(block.Meta.Get(metaFile)) != 0 {
+			lenses = append(lenses, lsp.CodeLens{Range: rng, Command: &lsp.Command{
+				Title: "Sync block", Command: lspCommandSync, Arguments: []any{uri, idx + 1},
+			}})
+		}
+	}
+
+	return lenses
+}
+
+func (s *lspServer) symbols(_ string, blocks mdcode.Blocks) any {
+	symbols := make([]lsp.DocumentSymbol, 0, len(blocks))
+
+	for idx, block := range blocks {
+		symbol := lsp.DocumentSymbol{
+			Name:           fmt.Sprintf("block %d", idx+1),
+			Detail:         block.Lang,
+			Kind:           lsp.SymbolKindObject,
+			Range:          blockRange(block),
+			SelectionRange: lsp.LineRange(block.StartLine-1, block.StartLine-1),
+		}
+
+		if name := block.Meta.Get(metaName); len(name) != 0 {
+			symbol.Name, symbol.Kind = name, lsp.SymbolKindFunction
+		} else if file := block.Meta.Get(metaFile); len(file) != 0 {
+			symbol.Name, symbol.Kind = file, lsp.SymbolKindFile
+		}
+
+		symbols = append(symbols, symbol)
+	}
+
+	return symbols
+}
+
+func (s *lspServer) diagnostics(uri string) []lsp.Diagnostic {
+	diags := []lsp.Diagnostic{}
+
+	drifts, err := checkDrift(s.docs[uri], filepath.Dir(lsp.URIToPath(uri)), anyBlock, os.ReadFile)
+	if err != nil {
+		return append(diags, lsp.Diagnostic{
+			Range: lsp.LineRange(0, 0), Severity: lsp.SeverityError, Source: lspSource, Message: err.Error(),
+		})
+	}
+
+	for _, d := range drifts {
+		diags = append(diags, lsp.Diagnostic{
+			Range: blockRange(d.block), Severity: lsp.SeverityWarning, Source: lspSource, Message: d.message(),
+		})
+	}
+
+	return append(diags, lintDiagnostics(s.docs[uri])...)
+}
+
+// lintDiagnostics reports the problems found by the lint rules, at their
+// line of the document.
+func lintDiagnostics(src []byte) []lsp.Diagnostic {
+	var diags []lsp.Diagnostic
+
+	_, _, _ = mdcode.Walk(src, func(block *mdcode.Block) error {
+		for _, rule := range lintRules {
+			for _, found := range rule.check(block.Code) {
+				line := block.StartLine + found.Line
+
+				diags = append(diags, lsp.Diagnostic{
+					Range: lsp.LineRange(line-1, line), Severity: lsp.SeverityWarning, Source: lspSource,
+					Message: rule.name + ": " + found.Msg,
+				})
+			}
+		}
+
+		return nil
+	})
+
+	return diags
+}
+
+func (s *lspServer) publish(uri string) error {
+	return s.conn.Notify("textDocument/publishDiagnostics",
+		lsp.PublishDiagnosticsParams{URI: uri, Diagnostics: s.diagnostics(uri)})
+}
+
+func (s *lspServer) execute(params *lsp.ExecuteCommandParams) error {
+	const nargs = 2
+
+	if len(params.Arguments) != nargs {
+		return &lsp.ResponseError{Code: lsp.CodeInvalidParams, Message: "expected document URI and block index"}
+	}
+
+	uri, _ := params.Arguments[0].(string)
+	index, _ := params.Arguments[1].(float64)
+
+	src, ok := s.docs[uri]
+	if !ok {
+		return &lsp.ResponseError{Code: lsp.CodeInvalidParams, Message: "unknown document: " + uri}
+	}
+
+	path := lsp.URIToPath(uri)
+	dir := filepath.Dir(path)
+
+	switch params.Command {
+	case lspCommandRun:
+		if err := lspTrust(path, src); err != nil {
+			return err
+		}
+
+		return s.runBlock(src, int(index), dir)
+	case lspCommandSync:
+		return s.syncBlock(uri, src, int(index), dir)
+	}
+
+	return &lsp.ResponseError{Code: lsp.CodeInvalidParams, Message: "unknown command: " + params.Command}
+}
+
+// lspTrust refuses to run the code blocks of a document not trusted by
+// mdcode run or mdcode exec, there is no terminal to ask for confirmation.
+func lspTrust(path string, src []byte) error {
+	trust, err := loadTrust(false, &bytes.Buffer{}, io.Discard)
+	if err != nil {
+		return err
+	}
+
+	if trust.store.has(trustKey(path, src)) {
+		return nil
+	}
+
+	return &lsp.ResponseError{
+		Code:    lsp.CodeInvalidRequest,
+		Message: fmt.Sprintf("%s: %s, save it, review it and trust it with mdcode run --yes or mdcode exec --yes", errUntrusted, path),
+	}
+}
+
+func (s *lspServer) runBlock(src []byte, index int, dir string) error {
+	blocks, err := mdcode.Unfence(src)
+	if err != nil {
+		return err
+	}
+
+	if index < 1 || index > len(blocks) {
+		return fmt.Errorf("%w: %d", errMissingBlock, index)
+	}
+
+	var output bytes.Buffer
+
+	exitCode, err := runCommand(string(blocks[index-1].Code), dir, &bytes.Buffer{}, &output, &output)
+	if err != nil {
+		return err
+	}
+
+	if err = s.conn.Notify("window/logMessage", lsp.ShowMessageParams{Type: lsp.MessageLog, Message: output.String()}); err != nil {
+		return err
+	}
+
+	kind := lsp.MessageInfo
+	if exitCode != 0 {
+		kind = lsp.MessageError
+	}
+
+	return s.conn.Notify("window/showMessage",
+		lsp.ShowMessageParams{Type: kind, Message: fmt.Sprintf("block %d exited with %d", index, exitCode)})
+}
+
+func (s *lspServer) syncBlock(uri string, src []byte, index int, dir string) error {
+	current := 0
+
+	modified, result, err := mdcode.Walk(src, func(block *mdcode.Block) error {
+		current++
+
+		if current != index {
+			return nil
+		}
+
+		if err := s.confine(block, dir); err != nil {
+			return err
+		}
+
+		return load(block, dir, nostatus)
+	})
+	if err != nil {
+		return err
+	}
+
+	if !modified {
+		return nil
+	}
+
+	edit := lsp.TextEdit{Range: lsp.Range{Start: lsp.Position{Line: 0, Character: 0}, End: endPosition(src)}, NewText: string(result)}
+
+	return s.conn.Call("workspace/applyEdit", lsp.ApplyWorkspaceEditParams{
+		Label: "Sync block",
+		Edit:  lsp.WorkspaceEdit{Changes: map[string][]lsp.TextEdit{uri: {edit}}},
+	})
+}
+
+// confine checks that the file of the code block (relative to the directory
+// of the document) is inside the workspace root, or inside the directory of
+// the document without workspace root, like the confine option check.
+func (s *lspServer) confine(block *mdcode.Block, dir string) error {
+	file := block.Meta.Get(metaFile)
+	if len(file) == 0 {
+		return nil
+	}
+
+	root := s.root
+	if len(root) == 0 {
+		root = dir
+	}
+
+	relpath, err := filepath.Rel(root, filepath.Join(dir, filepath.FromSlash(file)))
+	if err != nil || filepath.IsAbs(filepath.FromSlash(file)) || !filepath.IsLocal(relpath) || escapes(root, relpath) {
+		return fmt.Errorf("%w: %s (line %d)", errOutside, file, block.StartLine)
+	}
+
+	return nil
+}
+
+func endPosition(src []byte) lsp.Position {
+	line := bytes.Count(src, []byte{'\n'})
+	last := src[bytes.LastIndexByte(src, '\n')+1:]
+
+	return lsp.Position{Line: line, Character: len(utf16.Encode([]rune(string(last))))}
+}
+
+var errMissingBlock = errors.New("missing code block")
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/spf13/cobra"
+)
+
+//go:embed help/merge.md
+var mergeHelp string
+
+func mergeCmd(opts *options) *cobra.Command {
+	var appendNew bool
+
+	cmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "merge [flags] source destination",
+		Short: "Copy code blocks from one markdown document into another",
+		Long:  mergeHelp,
+		Args:  cobra.ExactArgs(2), //nolint:gomnd
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			opts.createStatus(cmd.ErrOrStderr())
+
+			return opts.allBlocksFilter(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return mergeRun(args[0], args[1], opts, appendNew)
+		},
+
+		DisableAutoGenTag: true,
+	}
+
+	quietFlag(cmd, opts)
+
+	cmd.Flags().StringVarP(&opts.out, "output", "o", "", "output file (default: the destination document)")
+	cmd.Flags().BoolVar(&appendNew, "append", false, "append code blocks missing from the destination document")
+
+	cobra.CheckErr(cmd.MarkFlagFilename("output"))
+
+	return cmd
+}
+
+// blockKey identifies a code block across documents by its name or by its
+// file and region metadata. It returns an empty string for anonymous blocks.
+func blockKey(block *mdcode.Block) string {
+	if name := block.Meta.Get(metaName); len(name) != 0 {
+		return "name:" + name
+	}
+
+	if file := block.Meta.Get(metaFile); len(file) != 0 {
+		return "file:" + file + "#" + block.Meta.Get(metaRegion)
+	}
+
+	return ""
+}
+
+func mergeRun(srcName, dstName string, opts *options, appendNew bool) (err error) {
+	opts.status("Merging code blocks from %s into %s\n", srcName, dstName)
+
+	// The written document is locked, from reading the destination.
+	target := dstName
+	if len(opts.out) != 0 {
+		target = opts.out
+	}
+
+	lock, err := lockDocument(target)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err = errors.Join(err, lock.unlock())
+	}()
+
+	src, _, err := opts.readDocument(srcName)
+	if err != nil {
+		return err
+	}
+
+	var order []string
+
+	blocks := make(map[string]*mdcode.Block)
+
+	_, _, err = walk(src, func(block *mdcode.Block) error {
+		key := blockKey(block)
+		if _, has := blocks[key]; len(key) != 0 && !has {
+			blocks[key] = block
+			order = append(order, key)
+		}
+
+		return nil
+	}, opts.filter)
+	if err != nil {
+		return err
+	}
+
+	dst, format, err := opts.readDocument(dstName)
+	if err != nil {
+		return err
+	}
+
+	used := make(map[string]struct{})
+	eol := opts.lineEnding(dst)
+
+	modified, result, err := walk(dst, func(block *mdcode.Block) error {
+		key := blockKey(block)
+
+		if from, has := blocks[key]; has {
+			used[key] = struct{}{}
+
+			if code := convertEOL(from.Code, eol); !bytes.Equal(block.Code, code) {
+				opts.status("%s\n", key)
+
+				block.Code = code
+			}
+		}
+
+		return nil
+	}, opts.filter)
+	if err != nil {
+		return err
+	}
+
+	if !modified {
+		result = dst
+	}
+
+	if appendNew {
+		var tail bytes.Buffer
+
+		for _, key := range order {
+			if _, has := used[key]; has {
+				continue
+			}
+
+			opts.status("%s (appended)\n", key)
+
+			tail.WriteByte('\n')
+			writeFenced(&tail, formatInfo(blocks[key].Lang, blocks[key].Meta), blocks[key].Code)
+		}
+
+		if tail.Len() != 0 {
+			if result = bytes.TrimRight(result, "\r\n"); len(result) != 0 {
+				result = append(result, eol...)
+			}
+
+			result = append(result, convertEOL(tail.Bytes(), eol)...)
+			modified = true
+		}
+	}
+
+	if len(opts.out) != 0 {
+		return writeDocument(opts.out, result, format)
+	}
+
+	if modified {
+		return writeDocument(dstName, result, format)
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+)
+
+var reNamePlaceholder = regexp.MustCompile(`\{(\w+)(?::([^{}]+))?\}`)
+
+// nameValues returns the values of the --name-template placeholders for the
+// code block. The file placeholder is the slash separated file metadata, or
+// a generated block_<index><ext> name if it is missing.
+func nameValues(block *mdcode.Block, lang string, index int) map[string]any {
+	ext := langExtension(lang)
+
+	file := path.Clean(block.Meta.Get(metaFile))
+	if len(block.Meta.Get(metaFile)) == 0 {
+		file = fmt.Sprintf("block_%d%s", index, ext)
+	} else if e := path.Ext(file); len(e) != 0 {
+		ext = e
+	}
+
+	base := path.Base(file)
+
+	return map[string]any{
+		"index": index,
+		"lang":  block.Lang,
+		"group": block.Meta.Get(metaGroup),
+		"ext":   ext,
+		"file":  file,
+		"base":  base,
+		"stem":  strings.TrimSuffix(base, path.Ext(base)),
+	}
+}
+
+// expandName expands the placeholders of the name template. A placeholder
+// may contain a printf style format after a colon, like {index:03d}.
+func expandName(template string, values map[string]any) (string, error) {
+	var err error
+
+	name := reNamePlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		all := reNamePlaceholder.FindStringSubmatch(placeholder)
+
+		value, ok := values[all[1]]
+		if !ok {
+			err = fmt.Errorf("%w: unknown placeholder %s", errNameTemplate, placeholder)
+
+			return placeholder
+		}
+
+		if len(all[2]) == 0 {
+			return fmt.Sprint(value)
+		}
+
+		return fmt.Sprintf("%"+all[2], value)
+	})
+
+	return name, err
+}
+
+// checkNameTemplate validates the --name-template flag.
+func checkNameTemplate(template string) error {
+	if len(template) == 0 {
+		return nil
+	}
+
+	name, err := expandName(template, nameValues(&mdcode.Block{Lang: "go"}, "go", 1)) //nolint:exhaustruct
+	if err != nil {
+		return err
+	}
+
+	if strings.Contains(name, "%!") {
+		return fmt.Errorf("%w: invalid format in %s", errNameTemplate, template)
+	}
+
+	return nil
+}
+
+var errNameTemplate = errors.New("invalid name template")
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// Normalizations applied before comparing the updated code of a block with
+// its original code in exec --update.
+const (
+	normalizeEOL     = "eol"
+	normalizeSpace   = "space"
+	normalizeNewline = "newline"
+)
+
+func checkNormalize(modes []string) error {
+	for _, mode := range modes {
+		if mode != normalizeEOL && mode != normalizeSpace && mode != normalizeNewline {
+			return fmt.Errorf("%w: %s", errNormalize, mode)
+		}
+	}
+
+	return nil
+}
+
+// normalizeCode returns the code with the differences ignored by the modes
+// removed: line endings (eol), trailing whitespace of the lines (space) and
+// trailing blank lines (newline).
+func normalizeCode(code []byte, modes []string) []byte {
+	for _, mode := range modes {
+		switch mode {
+		case normalizeEOL:
+			code = bytes.ReplaceAll(code, crlf, lf)
+		case normalizeSpace:
+			lines := bytes.SplitAfter(code, lf)
+			for idx, line := range lines {
+				eol := line[len(bytes.TrimRight(line, "\r\n")):]
+				lines[idx] = append(bytes.TrimRight(line[:len(line)-len(eol)], " \t"), eol...)
+			}
+
+			code = bytes.Join(lines, nil)
+		case normalizeNewline:
+			code = append(bytes.TrimRight(code, "\r\n"), '\n')
+		}
+	}
+
+	return code
+}
+
+// sameCode reports whether the updated code of a block differs from the
+// original only in the ways ignored by the modes.
+func sameCode(original, updated []byte, modes []string) bool {
+	if bytes.Equal(original, updated) {
+		return true
+	}
+
+	if len(modes) == 0 {
+		return false
+	}
+
+	return bytes.Equal(normalizeCode(bytes.Clone(original), modes), normalizeCode(bytes.Clone(updated), modes))
+}
+
+var errNormalize = errors.New("invalid normalization (use eol, space or newline)")
@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/spf13/cobra"
+)
+
+//go:embed help/number.md
+var numberHelp string
+
+const defaultNumberPrefix = "block"
+
+type numberOptions struct {
+	key      string
+	prefix   string
+	sequence bool
+}
+
+func numberCmd(opts *options) *cobra.Command {
+	nopts := new(numberOptions)
+
+	cmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "number [flags] [filename]",
+		Short: "Write missing name metadata into code fences",
+		Long:  numberHelp,
+		Args:  checkargs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			opts.createStatus(cmd.ErrOrStderr())
+
+			if len(nopts.key) == 0 || strings.ContainsAny(nopts.key, " \t=\"'") {
+				return fmt.Errorf("%w: %q", errNumberKey, nopts.key)
+			}
+
+			if err := opts.createFilter(cmd); err != nil {
+				return err
+			}
+
+			return opts.allBlocksFilter(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files, err := sources(args, opts)
+			if err != nil {
+				return err
+			}
+
+			return opts.eachSource(files, func(file string) error {
+				return numberRun(file, opts, nopts)
+			})
+		},
+
+		DisableAutoGenTag: true,
+	}
+
+	cmd.Flags().StringVar(&nopts.key, "key", metaName, "metadata key written into the fences (e.g. "+metaID+")")
+	cmd.Flags().BoolVar(&nopts.sequence, "sequence", false, "generate sequence numbers instead of names from the headings")
+	cmd.Flags().StringVar(&nopts.prefix, "prefix", defaultNumberPrefix, "prefix of the generated sequence numbers")
+
+	quietFlag(cmd, opts)
+
+	return cmd
+}
+
+func numberRun(filename string, opts *options, nopts *numberOptions) (err error) {
+	opts.status("Numbering code blocks in %s\n", filename)
+
+	lock, err := lockDocument(filename)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err = errors.Join(err, lock.unlock())
+	}()
+
+	src, format, err := opts.readDocument(filename)
+	if err != nil {
+		return err
+	}
+
+	// The values of the key are unique in the whole document, not only
+	// among the filtered code blocks.
+	taken := make(map[string]struct{})
+
+	if _, _, err = mdcode.Walk(src, func(block *mdcode.Block) error {
+		if value := block.Meta.Get(nopts.key); len(value) != 0 {
+			taken[value] = struct{}{}
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	index := 0
+
+	modified, res, err := walk(src, func(block *mdcode.Block) error {
+		index++
+
+		if len(block.Fence) == 0 || len(block.Meta.Get(nopts.key)) != 0 {
+			return nil
+		}
+
+		value := uniqueValue(nopts.generate(block, index), taken)
+
+		info, ok := annotateInfo(block.Info, nopts.key, value)
+		if !ok {
+			opts.status("%s: metadata can't be added to the info string\n", opts.location(filename, block.StartLine))
+
+			return nil
+		}
+
+		taken[value] = struct{}{}
+
+		opts.status("%s: %s=%s\n", opts.location(filename, block.StartLine), nopts.key, value)
+		opts.event("block numbered", "document", filename, "line", block.StartLine, "key", nopts.key, "value", value)
+
+		block.Info = info
+
+		return nil
+	}, opts.filter)
+	if err != nil {
+		return err
+	}
+
+	if modified {
+		return writeDocument(filename, res, format)
+	}
+
+	return nil
+}
+
+// generate returns the value generated for the code block with the given
+// (1-based) index: the slug of its closest heading, or the prefixed index.
+func (n *numberOptions) generate(block *mdcode.Block, index int) string {
+	if !n.sequence && len(block.Headings) != 0 {
+		if slug := slugify(block.Headings[len(block.Headings)-1]); len(slug) != 0 {
+			return slug
+		}
+	}
+
+	return fmt.Sprintf("%s-%d", n.prefix, index)
+}
+
+// uniqueValue returns value, or value with the first free -2, -3, ... suffix
+// if it is taken.
+func uniqueValue(value string, taken map[string]struct{}) string {
+	unique := value
+
+	for idx := 2; ; idx++ {
+		if _, has := taken[unique]; !has {
+			return unique
+		}
+
+		unique = fmt.Sprintf("%s-%d", value, idx)
+	}
+}
+
+// slugify returns the lower case words of the heading joined with hyphens,
+// markdown punctuation (like backticks) is dropped.
+func slugify(heading string) string {
+	words := strings.FieldsFunc(strings.ToLower(heading), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	return strings.Join(words, "-")
+}
+
+// annotateInfo appends the key=value metadata to the info string. Info
+// strings with JSON metadata are rewritten in name="value" format, unless
+// they have structured values. The last result is false if the metadata
+// can't be added.
+func annotateInfo(info, key, value string) (string, bool) {
+	lang, meta, err := mdcode.ParseInfo(info)
+	if err != nil {
+		return info, false
+	}
+
+	rest := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(info), lang))
+	if !strings.HasPrefix(rest, "{") {
+		return strings.TrimSpace(info + " " + key + "=" + quoteMeta(value)), true
+	}
+
+	for _, value := range meta {
+		switch value.(type) {
+		case string, float64, bool:
+		default:
+			return info, false
+		}
+	}
+
+	if meta == nil {
+		meta = make(mdcode.Meta)
+	}
+
+	meta[key] = value
+
+	return formatInfo(lang, meta), true
+}
+
+var errNumberKey = errors.New("invalid metadata key")
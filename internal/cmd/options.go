@@ -3,7 +3,12 @@ package cmd
 import (
 	"fmt"
 	"io"
+	"log/slog"
 	"strings"
+	"time"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/spf13/cobra"
 )
 
 const (
@@ -11,59 +16,264 @@ const (
 	metaRegion  = "region"
 	metaOutline = "outline"
 	metaName    = "name"
+	metaGroup   = "group"
+	metaDir     = "dir"
+	metaSkip    = mdcode.MetaSkip
+	metaCmd     = mdcode.MetaCmd
+)
+
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
 )
 
 type statusFunc func(format string, args ...any)
 
 type options struct {
-	lang []string
-	file []string
-	name string
-	meta map[string]string
+	lang  []string
+	file  []string
+	group []string
+	name  string
+	block int
+	meta  map[string]string
+
+	tags     []string
+	skipTags []string
 
 	dir string
 	out string
 
-	json bool
+	recursive bool
+	include   []string
+	exclude   []string
+	noIgnore  bool
+
+	defaultDocuments []string
+
+	hidden       bool
+	allowOutside bool
+
+	maxBlockSize  string
+	maxBlockBytes int64
+	maxBlocks     int
+
+	langAlias map[string]string
+	aliases   langAliases
+	inferLang bool
+	fileKeys  []string
+
+	json  bool
+	blame bool
+
+	quiet     bool
+	verbosity int
+	keep      bool
+	stamp     bool
+
+	nameTemplate string
+	archive      string
+
+	colorMode string
+	logFormat string
+	format    string
+
+	eol      string
+	encoding string
+
+	insecure bool
+	headers  []string
+
+	exitZero     bool
+	requireMatch bool
+	matched      int
+
+	changed string
+	changes changedLines
+
+	fromSelection string
+	selection     *selection
+	selected      map[int]struct{}
+
+	ids      []string
+	headings []string
 
-	quiet bool
-	keep  bool
+	state     *syncState
+	conflicts int
+
+	document string
+	includes map[string]mdcode.Blocks
 
 	filter filterFunc
 	status statusFunc
+	stderr io.Writer
+	color  palette
+	logger *slog.Logger
 }
 
-func (o *options) createFilter() error {
-	addMeta := func(key string, values []string) {
-		v, ok := o.meta[key]
-		if ok {
-			v += ","
+func (o *options) createFilter(cmd *cobra.Command) error {
+	var err error
+
+	if len(o.group) != 0 {
+		if o.meta == nil {
+			o.meta = make(map[string]string)
 		}
 
-		o.meta[key] = v + strings.Join(values, ",")
+		o.meta[metaGroup] = strings.Join(o.group, ",")
+	}
+
+	o.aliases = newLangAliases(o.langAlias)
+	o.filter, err = filter(o.lang, o.metaFilter(cmd.Flag("file").Changed), o.headings, o.hidden, o.aliases)
+	o.filter = o.wrapFilter(o.filter)
+
+	return err
+}
+
+// wrapFilter completes the metadata filter with the filters of the other
+// selection flags, and counts the matching code blocks.
+func (o *options) wrapFilter(filter filterFunc) filterFunc {
+	filter = tagged(filter, o.tags, o.skipTags)
+	filter = inferring(filter, o.inferLang)
+	filter = fileKeyed(filter, o.fileKeys)
+	filter = changedOnly(filter, o)
+	filter = selectedOnly(filter, o)
+	filter = identified(filter, o.ids)
+	filter = including(filter, o)
+
+	return counted(filter, &o.matched)
+}
+
+// metaFilter returns the metadata filter patterns completed with the --file
+// patterns. If a file pattern is given with --meta, the --file patterns are
+// only added when fileChanged is set, so the default --file pattern does not
+// widen the selection.
+func (o *options) metaFilter(fileChanged bool) map[string]string {
+	meta := make(map[string]string, len(o.meta)+1)
+
+	for k, v := range o.meta {
+		meta[k] = v
+	}
+
+	if _, has := meta[metaFile]; has && !fileChanged {
+		return meta
+	}
+
+	if v, has := meta[metaFile]; has {
+		meta[metaFile] = v + "," + strings.Join(o.file, ",")
+	} else {
+		meta[metaFile] = strings.Join(o.file, ",")
+	}
+
+	return meta
+}
+
+// allBlocksFilter recreates the filter for commands working with all code
+// blocks: unless the --file and --lang flags are used, code blocks without
+// file metadata or language are not filtered out.
+func (o *options) allBlocksFilter(cmd *cobra.Command) error {
+	fileChanged := cmd.Flag("file").Changed
+	langChanged := cmd.Flag("lang").Changed
+
+	if fileChanged && langChanged {
+		return nil
 	}
 
-	if o.meta == nil {
-		o.meta = make(map[string]string)
+	meta := o.meta
+	if fileChanged {
+		meta = o.metaFilter(true)
 	}
 
-	addMeta(metaFile, o.file)
+	lang := o.lang
+	if !langChanged {
+		lang = []string{"*"}
+	}
 
 	var err error
 
-	if o.filter, err = filter(o.lang, o.meta); err != nil {
-		return err
+	o.filter, err = filter(lang, meta, o.headings, o.hidden, o.aliases)
+	o.filter = o.wrapFilter(o.filter)
+
+	return err
+}
+
+// filterChanged reports whether the code blocks are filtered with the
+// --lang, --file, --group, --meta, --tags, --id or --heading flags.
+func filterChanged(cmd *cobra.Command) bool {
+	for _, name := range []string{"lang", "file", "group", "meta", "tags", "id", "heading"} {
+		if flag := cmd.Flag(name); flag != nil && flag.Changed {
+			return true
+		}
 	}
 
-	return nil
+	return false
 }
 
+func nostatus(string, ...any) {}
+
 func (o *options) createStatus(stderr io.Writer) {
+	o.stderr = stderr
+	o.color, _ = newPalette(o.colorMode, stderr)
+
+	if o.logFormat == logFormatJSON {
+		o.logger = slog.New(slog.NewJSONHandler(stderr, nil))
+		o.status = nostatus
+		o.quiet = true
+
+		return
+	}
+
 	if o.quiet {
-		o.status = func(format string, args ...any) {}
+		o.status = nostatus
 	} else {
 		o.status = func(format string, args ...any) {
 			fmt.Fprintf(stderr, format, args...)
 		}
 	}
 }
+
+// event logs a lifecycle event in structured logging mode.
+func (o *options) event(msg string, args ...any) {
+	if o.logger != nil {
+		o.logger.Info(msg, args...)
+	}
+}
+
+// verbose prints the status message if the verbosity is at least level.
+func (o *options) verbose(level int, format string, args ...any) {
+	if o.verbosity >= level && !o.quiet && o.stderr != nil {
+		fmt.Fprintf(o.stderr, format, args...)
+	}
+}
+
+// eachSource calls fn for each markdown document. When several documents are
+// processed at the default verbosity on a terminal, a progress bar replaces
+// the status messages. With -vv the processing time of each document is shown.
+func (o *options) eachSource(files []string, fn func(filename string) error) error {
+	prog := newProgress(o.stderr, len(files))
+
+	if prog != nil && o.verbosity == 0 && !o.quiet {
+		status := o.status
+		o.status = nostatus
+
+		defer func() { o.status = status }()
+	} else {
+		prog = nil
+	}
+
+	for idx, file := range files {
+		prog.update(idx, file)
+
+		start := time.Now()
+
+		if err := fn(file); err != nil {
+			prog.finish()
+
+			return err
+		}
+
+		o.verbose(2, "%s processed in %s\n", file, time.Since(start).Round(time.Millisecond))
+	}
+
+	prog.finish()
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/ezerfernandes/mdcode/internal/patch"
+)
+
+const langDiff = "diff"
+
+// isPatch reports whether the code block is a diff to apply to the file of
+// its file metadata, instead of the content of the file.
+func isPatch(lang string) bool {
+	return langAliases(nil).canonical(lang) == langDiff
+}
+
+// patchTransform returns the file content with the diff of the code block
+// applied. A missing file is created by the diff, a diff already applied is
+// skipped, so extracting is repeatable.
+func patchTransform(filename string, block *mdcode.Block, fsys fs.FS, status statusFunc) ([]byte, bool, error) {
+	orig, err := fs.ReadFile(fsys, filepath.ToSlash(filename))
+	exists := err == nil
+
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, false, err
+	}
+
+	if exists && patch.Applied(orig, block.Code) {
+		status("%s (patch already applied)\n", filename)
+
+		return orig, true, nil
+	}
+
+	status("%s (patch)\n", filename)
+
+	data, err := patch.Apply(orig, block.Code)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	return data, exists, nil
+}
+
+// checkPatch checks that the diff of the code block applies cleanly to its
+// file, or is already applied.
+func checkPatch(block *mdcode.Block, dir string, read readFunc) error {
+	filename := rel(dir, filepath.FromSlash(block.Meta.Get(metaFile)))
+
+	orig, err := read(filename)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if patch.Applied(orig, block.Code) {
+		return nil
+	}
+
+	if _, err = patch.Apply(orig, block.Code); err != nil {
+		return fmt.Errorf("%w to %s", err, filename)
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"runtime"
+	"strings"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+)
+
+const (
+	metaOS   = "os"
+	metaArch = "arch"
+)
+
+// platformNames maps the common alternative names of operating systems and
+// architectures to the Go names.
+//
+//nolint:gochecknoglobals
+var platformNames = map[string]string{
+	"macos":   "darwin",
+	"mac":     "darwin",
+	"osx":     "darwin",
+	"win":     "windows",
+	"x86_64":  "amd64",
+	"x64":     "amd64",
+	"aarch64": "arm64",
+	"x86":     "386",
+	"i386":    "386",
+}
+
+// platformMismatch returns the os or arch metadata of the code block not
+// matching the running platform, or an empty string if it can run here. The
+// metadata is a comma separated list of names, names prefixed with ! are
+// excluded.
+func platformMismatch(block *mdcode.Block) string {
+	for _, check := range []struct{ key, current string }{{metaOS, runtime.GOOS}, {metaArch, runtime.GOARCH}} {
+		value := block.Meta.Get(check.key)
+		if len(value) != 0 && !platformMatch(value, check.current) {
+			return check.key + "=" + value
+		}
+	}
+
+	return ""
+}
+
+func platformMatch(list string, current string) bool {
+	included, hasIncluded := false, false
+
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		negated := strings.HasPrefix(name, "!")
+		name = strings.TrimPrefix(name, "!")
+
+		if canonical, has := platformNames[name]; has {
+			name = canonical
+		}
+
+		switch {
+		case negated && name == current:
+			return false
+		case !negated:
+			hasIncluded = true
+			included = included || name == current
+		}
+	}
+
+	return included || !hasIncluded
+}
@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/ezerfernandes/mdcode/internal/textenc"
+	"github.com/spf13/cobra"
+)
+
+//go:embed help/plugins.md
+var pluginsHelp string
+
+// pluginManifestVersion is the version of the manifest format passed to the
+// plugins, incremented on incompatible changes.
+const pluginManifestVersion = 1
+
+// pluginManifest is the JSON document passed to the standard input of the
+// plugins: the code blocks of the markdown documents.
+type pluginManifest struct {
+	Version   int               `json:"version"`
+	Mdcode    string            `json:"mdcode"`
+	Documents []*pluginDocument `json:"documents"`
+}
+
+type pluginDocument struct {
+	Document string         `json:"document"`
+	Blocks   []*pluginBlock `json:"blocks"`
+}
+
+type pluginBlock struct {
+	Index     int         `json:"index"`
+	ID        string      `json:"id"`
+	Lang      string      `json:"lang"`
+	Meta      mdcode.Meta `json:"meta"`
+	Headings  []string    `json:"headings,omitempty"`
+	StartLine int         `json:"start_line"`
+	EndLine   int         `json:"end_line"`
+	Hidden    bool        `json:"hidden,omitempty"`
+	Code      string      `json:"code"`
+}
+
+func pluginPrefix() string {
+	return appname + "-"
+}
+
+// findPlugin returns the path of the mdcode-<name> executable on the PATH
+// if the first argument is neither a command, a flag nor an existing file.
+func findPlugin(root *cobra.Command, args []string) (string, bool) {
+	if len(args) == 0 || len(args[0]) == 0 || strings.HasPrefix(args[0], "-") || strings.ContainsAny(args[0], `/\.`) {
+		return "", false
+	}
+
+	if found, _, err := root.Find(args[:1]); err == nil && found != root {
+		return "", false
+	}
+
+	switch args[0] {
+	case "help", "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return "", false
+	}
+
+	if _, err := os.Stat(args[0]); err == nil {
+		return "", false
+	}
+
+	path, err := exec.LookPath(pluginPrefix() + args[0])
+	if err != nil {
+		return "", false
+	}
+
+	return path, true
+}
+
+// runPlugin runs the plugin with the arguments, passing the manifest of the
+// markdown documents to its standard input. The documents are the markdown
+// files among the arguments, or the default document. The exit code of the
+// plugin is returned.
+func runPlugin(path string, args []string, stdout, stderr io.Writer) (int, error) {
+	manifest, err := newPluginManifest(pluginDocuments(args))
+	if err != nil {
+		return exitCodeOf(err, false), err
+	}
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return exitError, err
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		self = appname
+	}
+
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.Env = append(os.Environ(), "MDCODE="+self, "MDCODE_VERSION="+version)
+
+	err = cmd.Run()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+
+	if err != nil {
+		return exitError, err
+	}
+
+	return exitOK, nil
+}
+
+func pluginDocuments(args []string) []string {
+	var documents []string
+
+	for _, arg := range args {
+		switch strings.ToLower(filepath.Ext(arg)) {
+		case ".md", ".markdown":
+		default:
+			continue
+		}
+
+		if info, err := os.Stat(arg); err == nil && info.Mode().IsRegular() {
+			documents = append(documents, arg)
+		}
+	}
+
+	if len(documents) == 0 {
+		if document, err := defaultDocument([]string{defaultArg, "*.md"}); err == nil {
+			documents = append(documents, document)
+		}
+	}
+
+	return documents
+}
+
+func newPluginManifest(documents []string) (*pluginManifest, error) {
+	manifest := &pluginManifest{Version: pluginManifestVersion, Mdcode: version, Documents: []*pluginDocument{}}
+
+	for _, document := range documents {
+		data, err := os.ReadFile(filepath.Clean(document))
+		if err != nil {
+			return nil, err
+		}
+
+		src, _, err := textenc.Decode(data, "")
+		if err != nil {
+			return nil, err
+		}
+
+		doc := &pluginDocument{Document: document, Blocks: []*pluginBlock{}}
+		index := 0
+
+		_, _, err = mdcode.Walk(src, func(block *mdcode.Block) error {
+			index++
+
+			if block.Virtual {
+				return nil
+			}
+
+			doc.Blocks = append(doc.Blocks, &pluginBlock{
+				Index:     index,
+				ID:        blockID(block),
+				Lang:      block.Lang,
+				Meta:      block.Meta,
+				Headings:  block.Headings,
+				StartLine: block.StartLine,
+				EndLine:   block.EndLine,
+				Hidden:    block.Hidden,
+				Code:      string(block.Code),
+			})
+
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", document, err)
+		}
+
+		manifest.Documents = append(manifest.Documents, doc)
+	}
+
+	return manifest, nil
+}
+
+func pluginsCmd() *cobra.Command {
+	cmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "plugins",
+		Short: "List the plugins found on the PATH",
+		Long:  pluginsHelp,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			for _, plugin := range listPlugins() {
+				name := strings.TrimPrefix(filepath.Base(plugin), pluginPrefix())
+
+				fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s\n", strings.TrimSuffix(name, filepath.Ext(name)), plugin)
+			}
+
+			return nil
+		},
+
+		DisableAutoGenTag: true,
+	}
+
+	return cmd
+}
+
+// listPlugins returns the paths of the mdcode-<name> executables on the PATH,
+// the first one of each name.
+func listPlugins() []string {
+	seen := make(map[string]struct{})
+
+	var plugins []string
+
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		matches, _ := filepath.Glob(filepath.Join(dir, pluginPrefix()+"*"))
+
+		sort.Strings(matches)
+
+		for _, match := range matches {
+			name := filepath.Base(match)
+			if _, has := seen[name]; has {
+				continue
+			}
+
+			if _, err := exec.LookPath(match); err != nil {
+				continue
+			}
+
+			seen[name] = struct{}{}
+			plugins = append(plugins, match)
+		}
+	}
+
+	return plugins
+}
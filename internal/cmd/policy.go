@@ -0,0 +1,273 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/gobwas/glob"
+)
+
+// policyFilename is the policy file of the user configuration directory,
+// loaded by exec when the --policy flag is not given, if it exists. The
+// policy is never taken from the executed documents or their repository,
+// which could allow themselves anything.
+const policyFilename = "policy.json"
+
+// policy restricts what exec may run. Languages lists the languages of the
+// code blocks allowed to be executed, Commands the glob patterns of the
+// allowed commands (the command after '--' or the cmd metadata, before
+// placeholder expansion) and Deny the regular expressions refused in the
+// commands and in the code. Empty lists allow everything. NonExecutable
+// extends the built-in data languages, Executable removes languages from
+// them.
+type policy struct {
+	Languages     []string `json:"languages"`
+	Commands      []string `json:"commands"`
+	Deny          []string `json:"deny"`
+	NonExecutable []string `json:"non_executable"`
+	Executable    []string `json:"executable"`
+
+	commands []glob.Glob
+	deny     []*regexp.Regexp
+}
+
+// loadPolicy reads the policy file, the one of the user configuration
+// directory if filename is empty. A missing file is only an error if it was
+// given explicitly.
+func loadPolicy(filename string) (*policy, error) {
+	required := len(filename) != 0
+
+	if !required {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, err
+		}
+
+		filename = filepath.Join(dir, appname, policyFilename)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if !required && errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	pol := new(policy)
+
+	if err := json.Unmarshal(data, pol); err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", errPolicyFile, filename, err)
+	}
+
+	for _, pattern := range pol.Commands {
+		// Braces are literal, so the {} placeholder can be written as is.
+		comp, err := glob.Compile(strings.NewReplacer("{", `\{`, "}", `\}`).Replace(pattern))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", errPolicyFile, filename, err)
+		}
+
+		pol.commands = append(pol.commands, comp)
+	}
+
+	for _, pattern := range pol.Deny {
+		comp, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", errPolicyFile, filename, err)
+		}
+
+		pol.deny = append(pol.deny, comp)
+	}
+
+	return pol, nil
+}
+
+// violation is a code block (or command) refused by the policy.
+type violation struct {
+	line int
+	msg  string
+}
+
+// check returns the policy violations of the markdown document: the code
+// blocks selected by filter are checked along with their command, the setup
+// and teardown code blocks are checked as commands.
+func (p *policy) check(src []byte, scr string, filter filterFunc, aliases langAliases) ([]violation, error) {
+	var found []violation
+
+	_, _, err := mdcode.Walk(src, func(block *mdcode.Block) error {
+		if len(block.Meta.Get(metaRole)) != 0 {
+			found = append(found, p.denied(block.StartLine, "code", string(block.Code))...)
+
+			return nil
+		}
+
+		if !filter(block) {
+			return nil
+		}
+
+		if !p.allowedLang(block.Lang, aliases) {
+			found = append(found, violation{block.StartLine, fmt.Sprintf("language %q is not allowed", block.Lang)})
+		}
+
+		command := blockCommand(scr, block)
+
+		if !p.allowedCommand(command) {
+			found = append(found, violation{block.StartLine, fmt.Sprintf("command %q is not allowed", command)})
+		}
+
+		found = append(found, p.denied(block.StartLine, "command", command)...)
+		found = append(found, p.denied(block.StartLine, "code", string(block.Code))...)
+
+		return nil
+	})
+
+	return found, err
+}
+
+func (p *policy) allowedLang(lang string, aliases langAliases) bool {
+	if len(p.Languages) == 0 {
+		return true
+	}
+
+	for _, allowed := range p.Languages {
+		if aliases.canonical(allowed) == aliases.canonical(lang) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *policy) allowedCommand(command string) bool {
+	if len(p.commands) == 0 || len(command) == 0 {
+		return true
+	}
+
+	for _, allowed := range p.commands {
+		if allowed.Match(command) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dataLanguages are the languages of code blocks holding data, text or
+// diagrams rather than programs.
+//
+//nolint:gochecknoglobals
+var dataLanguages = []string{
+	"text", "txt", "plain", "plaintext", "output", "log",
+	"json", "jsonc", "yaml", "toml", "ini", "xml", "csv", "tsv",
+	"mermaid", "plantuml", "dot", "diff", "markdown",
+}
+
+// executable reports whether the code blocks of the canonical language may
+// be executed: the data languages are not, unless the policy says otherwise.
+// The policy may be nil.
+func (p *policy) executable(lang string, aliases langAliases) bool {
+	has := func(langs []string) bool {
+		for _, name := range langs {
+			if aliases.canonical(name) == lang {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if p != nil {
+		if has(p.Executable) {
+			return true
+		}
+
+		if has(p.NonExecutable) {
+			return false
+		}
+	}
+
+	return !has(dataLanguages)
+}
+
+// executableBlocks drops the code blocks of the non-executable languages from
+// the filter, so patterns like --lang '*' don't select them. Code blocks with
+// their own command, or whose language is given by name with --lang, are
+// kept.
+func (o *options) executableBlocks(pol *policy) {
+	named := make(map[string]struct{})
+
+	for _, lang := range o.lang {
+		if !strings.ContainsAny(lang, "*?[{") {
+			named[o.aliases.canonical(lang)] = struct{}{}
+		}
+	}
+
+	filter := o.filter
+
+	o.filter = func(block *mdcode.Block) bool {
+		if !filter(block) {
+			return false
+		}
+
+		if len(block.Meta.Get(metaCmd)) != 0 {
+			return true
+		}
+
+		lang := o.aliases.canonical(block.Lang)
+		if _, has := named[lang]; has {
+			return true
+		}
+
+		return pol.executable(lang, o.aliases)
+	}
+}
+
+// denied returns a violation for each deny pattern found in text.
+func (p *policy) denied(line int, what string, text string) []violation {
+	var found []violation
+
+	for idx, re := range p.deny {
+		if re.MatchString(text) {
+			found = append(found, violation{line, fmt.Sprintf("%s matches denied pattern %q", what, p.Deny[idx])})
+		}
+	}
+
+	return found
+}
+
+// enforcePolicy reports the policy violations of the markdown document and
+// refuses to execute it if there is any.
+func (e *execOptions) enforcePolicy(filename string, src []byte, scr string, opts *options) error {
+	if e.policy == nil {
+		return nil
+	}
+
+	found, err := e.policy.check(src, scr, opts.filter, opts.aliases)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range found {
+		fmt.Fprintf(opts.stderr, "%s: policy: %s\n", opts.location(filename, v.line), v.msg)
+		opts.event("policy violation", "document", filename, "line", v.line, "violation", v.msg)
+	}
+
+	if len(found) != 0 {
+		return fmt.Errorf("%w: %d violation(s)", errPolicy, len(found))
+	}
+
+	return nil
+}
+
+var (
+	errPolicy     = errors.New("execution refused by policy")
+	errPolicyFile = errors.New("invalid policy file")
+)
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_policy_executable(t *testing.T) {
+	t.Parallel()
+
+	var pol *policy
+
+	require.True(t, pol.executable("sh", nil))
+	require.False(t, pol.executable("json", nil))
+	require.False(t, pol.executable("yaml", nil))
+
+	pol = &policy{Executable: []string{"json"}, NonExecutable: []string{"sh"}} //nolint:exhaustruct
+
+	require.True(t, pol.executable("json", nil))
+	require.False(t, pol.executable("sh", nil))
+	require.False(t, pol.executable("yaml", nil))
+}
+
+// execLanguages executes the code blocks of the document selected by the
+// arguments and returns their concatenated code.
+func execLanguages(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	ran := filepath.Join(dir, "ran")
+
+	require.NoError(t, os.WriteFile(ran, nil, 0o600))
+
+	args = append(append([]string{"exec", "--no-history", "--yes"}, args...), "doc.md", "--", "cat {} >> "+ran)
+
+	out, err := runMdcode(t, dir, args...)
+	require.NoError(t, err, out)
+
+	data, err := os.ReadFile(ran)
+	require.NoError(t, err)
+
+	return string(data)
+}
+
+func Test_options_executableBlocks(t *testing.T) {
+	dir := t.TempDir()
+
+	src := "```sh\necho sh\n```\n\n```json\n{\"json\": true}\n```\n\n```yaml\nyaml: true\n```\n"
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "doc.md"), []byte(src), 0o600))
+
+	require.Equal(t, "echo sh\n", execLanguages(t, dir, "--lang", "*"))
+	require.Equal(t, "{\"json\": true}\n", execLanguages(t, dir, "--lang", "json"))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "policy.json"), []byte(`{"executable": ["json"]}`), 0o600))
+	require.Equal(t, "echo sh\n{\"json\": true}\n", execLanguages(t, dir, "--lang", "*", "--policy", "policy.json"))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "policy.json"), []byte(`{"non_executable": ["sh"]}`), 0o600))
+	require.Equal(t, "", execLanguages(t, dir, "--lang", "*", "--policy", "policy.json"))
+	require.Equal(t, "echo sh\n", execLanguages(t, dir, "--lang", "sh", "--policy", "policy.json"))
+}
+
+func Test_loadPolicy_default(t *testing.T) {
+	dir := t.TempDir()
+
+	src := "```sh\necho sh\n```\n\n```json\n{\"json\": true}\n```\n"
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "doc.md"), []byte(src), 0o600))
+
+	// The policy of the document's directory is ignored.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".mdcode-policy.json"), []byte(`{"executable": ["json"]}`), 0o600))
+	require.Equal(t, "echo sh\n", execLanguages(t, dir, "--lang", "*"))
+
+	config := filepath.Join(dir, ".config", appname)
+
+	require.NoError(t, os.MkdirAll(config, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(config, policyFilename), []byte(`{"executable": ["json"]}`), 0o600))
+	require.Equal(t, "echo sh\n{\"json\": true}\n", execLanguages(t, dir, "--lang", "*"))
+
+	out, err := runMdcode(t, dir, "exec", "--no-history", "--yes", "--policy", "missing.json", "doc.md")
+	require.ErrorIs(t, err, os.ErrNotExist, out)
+}
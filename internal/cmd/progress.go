@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+const progressWidth = 30
+
+// progress is a single line progress bar drawn on a terminal.
+type progress struct {
+	out   io.Writer
+	total int
+}
+
+// newProgress returns a progress bar for more than one item, if out is a
+// terminal. Otherwise it returns nil, which is a valid no-op progress bar.
+func newProgress(out io.Writer, total int) *progress {
+	if total < 2 || !isTerminal(out) {
+		return nil
+	}
+
+	return &progress{out: out, total: total}
+}
+
+func (p *progress) update(done int, name string) {
+	if p == nil {
+		return
+	}
+
+	filled := progressWidth * done / p.total
+
+	fmt.Fprintf(p.out, "\r\033[K[%s%s] %d/%d %s",
+		strings.Repeat("#", filled), strings.Repeat(".", progressWidth-filled), done+1, p.total, filepath.Base(name))
+}
+
+func (p *progress) finish() {
+	if p == nil {
+		return
+	}
+
+	fmt.Fprint(p.out, "\r\033[K")
+}
+
+// isTerminal reports whether the stream is a terminal (character devices
+// like /dev/null are not).
+func isTerminal(stream any) bool {
+	file, ok := stream.(*os.File)
+
+	return ok && term.IsTerminal(int(file.Fd()))
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var reFilePosition = regexp.MustCompile(`([^\s:"'()\[\]]+):(\d+)(:\d+)?`)
+
+// remapper rewrites the positions of temporary files in the standard error
+// of commands, like block_3.go:7:2, to the position in the markdown document
+// (README.md:120:2) using the line mappings of the code blocks.
+type remapper struct {
+	document string
+	infos    []*blockInfo
+}
+
+func newRemapper(document string) *remapper {
+	return &remapper{document: document, infos: nil}
+}
+
+// add registers the temporary file of a code block. A nil remapper does
+// nothing.
+func (r *remapper) add(info *blockInfo) {
+	if r != nil {
+		r.infos = append(r.infos, info)
+	}
+}
+
+// lookup returns the code block written to the file path. Relative paths
+// match the end of the temporary file path.
+func (r *remapper) lookup(path string) *blockInfo {
+	path = filepath.ToSlash(path)
+	suffix := "/" + strings.TrimPrefix(path, "./")
+
+	for _, info := range r.infos {
+		temp := filepath.ToSlash(info.tempPath)
+		if temp == path || strings.HasSuffix(temp, suffix) {
+			return info
+		}
+	}
+
+	return nil
+}
+
+func (r *remapper) remap(line []byte) []byte {
+	return reFilePosition.ReplaceAllFunc(line, func(position []byte) []byte {
+		all := reFilePosition.FindSubmatch(position)
+
+		info := r.lookup(string(all[1]))
+		if info == nil {
+			return position
+		}
+
+		num, err := strconv.Atoi(string(all[2]))
+		if err != nil {
+			return position
+		}
+
+		docLine, ok := documentLine(info.mappings, num)
+		if !ok {
+			return position
+		}
+
+		return []byte(fmt.Sprintf("%s:%d%s", filepath.ToSlash(r.document), docLine, all[3]))
+	})
+}
+
+// writer returns a writer rewriting the positions line by line. The returned
+// function writes the last incomplete line.
+func (r *remapper) writer(out io.Writer) (io.Writer, func()) {
+	writer := &remapWriter{remapper: r, out: out} //nolint:exhaustruct
+
+	return writer, writer.flush
+}
+
+type remapWriter struct {
+	remapper *remapper
+	out      io.Writer
+	pending  []byte
+}
+
+func (w *remapWriter) Write(data []byte) (int, error) {
+	w.pending = append(w.pending, data...)
+
+	for {
+		idx := bytes.IndexByte(w.pending, '\n')
+		if idx < 0 {
+			return len(data), nil
+		}
+
+		if _, err := w.out.Write(w.remapper.remap(w.pending[:idx+1])); err != nil {
+			return 0, err
+		}
+
+		w.pending = w.pending[idx+1:]
+	}
+}
+
+func (w *remapWriter) flush() {
+	if len(w.pending) != 0 {
+		w.out.Write(w.remapper.remap(w.pending)) //nolint:errcheck
+
+		w.pending = nil
+	}
+}
+
+// documentLine returns the markdown document line of a temporary file line.
+func documentLine(mappings []lineMapping, line int) (int, bool) {
+	for _, mapping := range mappings {
+		if line >= mapping.Line && line < mapping.Line+mapping.Count {
+			return mapping.DocumentLine + line - mapping.Line, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const fetchTimeout = 30 * time.Second
+
+// isURL reports whether the markdown document name is an http(s) URL.
+func isURL(name string) bool {
+	lower := strings.ToLower(name)
+
+	return strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "http://")
+}
+
+// fetch downloads a remotely hosted markdown document, sending the headers
+// given with --header. Certificate verification is skipped with --insecure.
+func (o *options) fetch(url string) ([]byte, error) {
+	client := &http.Client{Timeout: fetchTimeout} //nolint:exhaustruct
+
+	if o.insecure {
+		client.Transport = &http.Transport{ //nolint:exhaustruct
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, //nolint:exhaustruct,gosec
+		}
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, header := range o.headers {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", errHeader, header)
+		}
+
+		req.Header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s: %s", errFetch, url, resp.Status)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+var (
+	errFetch  = errors.New("failed to fetch markdown document")
+	errHeader = errors.New("invalid header (use \"Name: value\")")
+	errRemote = errors.New("remote markdown documents can't be modified")
+)
@@ -0,0 +1,260 @@
+package cmd
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/spf13/cobra"
+)
+
+//go:embed help/render.md
+var renderHelp string
+
+const metaImage = "image"
+
+// renderers are the default commands rendering diagram code blocks, by
+// language. The {} placeholder is replaced by the path of the diagram
+// source, {out} by the path of the image and {format} by the image format.
+//
+//nolint:gochecknoglobals
+var renderers = map[string]string{
+	"mermaid":  "mmdc --quiet -i {} -o {out}",
+	"plantuml": "plantuml -t{format} -pipe < {} > {out}",
+	"dot":      "dot -T{format} -o {out} {}",
+}
+
+// diagramLangs maps the language aliases of the diagram languages.
+//
+//nolint:gochecknoglobals
+var diagramLangs = map[string]string{
+	"mmd":      "mermaid",
+	"puml":     "plantuml",
+	"uml":      "plantuml",
+	"graphviz": "dot",
+	"gv":       "dot",
+}
+
+func renderCmd(opts *options) *cobra.Command {
+	var (
+		format   string
+		link     bool
+		commands map[string]string
+	)
+
+	cmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "render [flags] [filename]",
+		Short: "Render diagram code blocks to image files",
+		Long:  renderHelp,
+		Args:  checkargs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			opts.createStatus(cmd.ErrOrStderr())
+
+			if format != "svg" && format != "png" {
+				return fmt.Errorf("%w: %s", errImageFormat, format)
+			}
+
+			return opts.allBlocksFilter(cmd)
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			files, err := sources(args, opts)
+			if err != nil {
+				return err
+			}
+
+			for lang, command := range renderers {
+				if _, has := commands[lang]; !has {
+					commands[lang] = command
+				}
+			}
+
+			return opts.eachSource(files, func(file string) error {
+				return renderRun(file, opts, format, link, commands)
+			})
+		},
+
+		DisableAutoGenTag: true,
+	}
+
+	quietFlag(cmd, opts)
+
+	cmd.Flags().StringVar(&format, "image-format", "svg", "format of the rendered images (svg or png)")
+	cmd.Flags().BoolVar(&link, "link", false, "insert an image link after the diagram code blocks, unless already present")
+	cmd.Flags().StringToStringVar(&commands, "renderer", map[string]string{},
+		"renderer command of a diagram language (e.g. dot=\"dot -T{format} -o {out} {}\")")
+
+	return cmd
+}
+
+// diagramLang returns the diagram language of the code block, or an empty
+// string if it is not a diagram.
+func diagramLang(lang string, aliases langAliases) string {
+	lang = aliases.canonical(lang)
+
+	if canonical, has := diagramLangs[lang]; has {
+		return canonical
+	}
+
+	if _, has := renderers[lang]; has {
+		return lang
+	}
+
+	return ""
+}
+
+func renderRun(filename string, opts *options, format string, link bool, commands map[string]string) (err error) {
+	opts.status("Rendering diagrams of %s\n", filename)
+
+	if link {
+		lock, lerr := lockDocument(filename)
+		if lerr != nil {
+			return lerr
+		}
+
+		defer func() {
+			err = errors.Join(err, lock.unlock())
+		}()
+	}
+
+	src, encoding, err := opts.readDocument(filename)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.MkdirTemp("", "mdcode-render-")
+	if err != nil {
+		return err
+	}
+
+	defer os.RemoveAll(tmp)
+
+	dir := filepath.Dir(filename)
+	stem := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	index := 0
+
+	modified, res, err := walk(src, func(block *mdcode.Block) error {
+		lang := diagramLang(block.Lang, opts.aliases)
+		if len(lang) == 0 {
+			return nil
+		}
+
+		index++
+
+		image, err := imageName(block, stem, index, format)
+		if err != nil {
+			return err
+		}
+
+		if err := renderDiagram(block, commands[lang], filepath.Join(tmp, fmt.Sprintf("diagram_%d.%s", index, lang)),
+			filepath.Join(dir, filepath.FromSlash(image)), format); err != nil {
+			return fmt.Errorf("%s: %w", opts.location(filename, block.StartLine), err)
+		}
+
+		opts.status("%s\n", filepath.Join(dir, filepath.FromSlash(image)))
+		opts.event("diagram rendered", "document", filename, "line", block.StartLine, "image", image)
+
+		if link && !hasImageLink(src, block, image) {
+			alt := block.Meta.Get(metaName)
+			if len(alt) == 0 {
+				alt = lang + " diagram"
+			}
+
+			block.InsertAfter([]byte(fmt.Sprintf("%s![%s](%s)%s", opts.lineEnding(src), alt, image, opts.lineEnding(src))))
+		}
+
+		return nil
+	}, opts.filter)
+	if err != nil {
+		return err
+	}
+
+	if modified {
+		return writeDocument(filename, res, encoding)
+	}
+
+	return nil
+}
+
+// imageName returns the path of the image rendered from the code block,
+// relative to the markdown document: the image metadata, or the name
+// metadata (or the document name and the diagram index) with the extension
+// of the format.
+func imageName(block *mdcode.Block, stem string, index int, format string) (string, error) {
+	image := block.Meta.Get(metaImage)
+
+	if len(image) == 0 {
+		name := block.Meta.Get(metaName)
+		if len(name) == 0 {
+			name = fmt.Sprintf("%s-%d", stem, index)
+		}
+
+		image = name + "." + format
+	}
+
+	if !filepath.IsLocal(filepath.FromSlash(image)) {
+		return "", fmt.Errorf("%w: %s (line %d)", errOutside, image, block.StartLine)
+	}
+
+	return image, nil
+}
+
+// renderDiagram writes the code of the block to source and runs the
+// renderer command producing the image.
+func renderDiagram(block *mdcode.Block, command, source, image, format string) error {
+	if err := os.WriteFile(source, block.Code, fileMode); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(image), dirMode); err != nil {
+		return err
+	}
+
+	expanded := strings.NewReplacer("{}", source, "{out}", image, "{format}", format).Replace(command)
+
+	var stderr bytes.Buffer
+
+	exitCode, err := runCommand(expanded, ".", nil, &stderr, &stderr)
+	if err != nil {
+		return err
+	}
+
+	if exitCode != 0 {
+		return fmt.Errorf("%w: %s exited with %d: %s", errRender, expanded, exitCode, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+// hasImageLink reports whether the first non-blank line after the code
+// block is an image link to image.
+func hasImageLink(src []byte, block *mdcode.Block, image string) bool {
+	lines := strings.Split(string(src), "\n")
+	fence := false
+
+	for idx := block.EndLine; idx < len(lines); idx++ {
+		line := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(lines[idx]), ">"))
+
+		switch {
+		case len(line) == 0:
+			continue
+		case !fence && len(block.Fence) != 0 && strings.HasPrefix(line, block.Fence[:1]+block.Fence[:1]+block.Fence[:1]):
+			fence = true
+
+			continue
+		}
+
+		return strings.HasPrefix(line, "![") && strings.HasSuffix(line, "]("+image+")")
+	}
+
+	return false
+}
+
+var (
+	errImageFormat = errors.New("invalid image format")
+	errRender      = errors.New("rendering failed")
+)
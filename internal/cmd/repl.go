@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// replSentinel is printed by the drivers after each code block, followed by
+// its exit status.
+const replSentinel = "\x1emdcode-done:"
+
+// The drivers read one file path per line from standard input and evaluate
+// the file in a namespace kept for the lifetime of the process.
+const (
+	pythonDriver = `import sys, traceback
+ns = {"__name__": "__main__"}
+for path in sys.stdin:
+    status = 0
+    try:
+        with open(path.rstrip("\n")) as f:
+            exec(compile(f.read(), path.rstrip("\n"), "exec"), ns)
+    except SystemExit as e:
+        status = e.code if isinstance(e.code, int) else 1
+    except BaseException:
+        traceback.print_exc()
+        status = 1
+    sys.stderr.flush()
+    sys.stdout.write("` + replSentinel + `%d\n" % status)
+    sys.stdout.flush()
+`
+	nodeDriver = `const fs = require("fs"), vm = require("vm"), rl = require("readline");
+rl.createInterface({ input: process.stdin }).on("line", (path) => {
+  let status = 0;
+  try {
+    vm.runInThisContext(fs.readFileSync(path, "utf8"), { filename: path });
+  } catch (e) {
+    console.error(e && e.stack ? e.stack : e);
+    status = 1;
+  }
+  process.stdout.write("` + replSentinel + `" + status + "\n");
+});
+`
+)
+
+//nolint:gochecknoglobals
+var replDrivers = map[string][]string{
+	"python": {"python3", "-u", "-c", pythonDriver},
+	"js":     {"node", "-e", nodeDriver},
+}
+
+// hasREPL reports whether the language (given by its canonical name) has an
+// interpreter usable in --session mode.
+func hasREPL(lang string) bool {
+	_, ok := replDrivers[lang]
+
+	return ok
+}
+
+// repl is a long-lived interpreter process evaluating the code blocks of one
+// language in --session mode.
+type repl struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	stderr *switchWriter
+	exited bool
+}
+
+func startREPL(lang, dir string) (*repl, error) {
+	args := replDrivers[lang]
+
+	cmd := exec.Command(args[0], args[1:]...) //nolint:gosec
+	cmd.Dir = dir
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stderr := new(switchWriter)
+	cmd.Stderr = stderr
+
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	return &repl{cmd: cmd, stdin: stdin, stdout: bufio.NewReader(stdout), stderr: stderr, exited: false}, nil
+}
+
+// eval evaluates the file in the interpreter, copying its output until the
+// driver reports the exit status of the code block.
+func (r *repl) eval(path string, stdout, stderr io.Writer) (int, error) {
+	r.stderr.set(stderr)
+
+	if _, err := fmt.Fprintln(r.stdin, path); err != nil {
+		return -1, err
+	}
+
+	for {
+		line, err := r.stdout.ReadString('\n')
+
+		if idx := strings.Index(line, replSentinel); idx >= 0 {
+			if idx > 0 {
+				fmt.Fprintln(stdout, line[:idx])
+			}
+
+			return strconv.Atoi(strings.TrimSpace(line[idx+len(replSentinel):]))
+		}
+
+		io.WriteString(stdout, line) //nolint:errcheck
+
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return r.exitCode()
+			}
+
+			return -1, err
+		}
+	}
+}
+
+// exitCode waits for an interpreter that exited while evaluating a block,
+// for example by calling process.exit().
+func (r *repl) exitCode() (int, error) {
+	r.exited = true
+
+	err := r.cmd.Wait()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+
+	if err != nil {
+		return -1, err
+	}
+
+	return 0, nil
+}
+
+func (r *repl) close() error {
+	if err := r.stdin.Close(); err != nil {
+		return err
+	}
+
+	return r.cmd.Wait()
+}
+
+// switchWriter forwards writes to a writer that can be replaced between code
+// blocks.
+type switchWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *switchWriter) set(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.w = w
+}
+
+func (s *switchWriter) Write(data []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.w == nil {
+		return len(data), nil
+	}
+
+	return s.w.Write(data)
+}
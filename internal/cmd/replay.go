@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+)
+
+// recording captures the output of the commands into fixtures (--record) or
+// verifies that the output of the commands reproduces the fixtures
+// (--replay) byte for byte.
+type recording struct {
+	replay bool
+	dir    string
+	name   string
+	stdout bytes.Buffer
+	stderr bytes.Buffer
+}
+
+//nolint:gochecknoglobals
+var defaultFixtureDir = filepath.Join(stateDir, "fixtures")
+
+func newRecording(record, replay string) (*recording, error) {
+	switch {
+	case len(record) != 0 && len(replay) != 0:
+		return nil, errRecordReplay
+	case len(record) != 0:
+		return &recording{dir: record}, nil //nolint:exhaustruct
+	case len(replay) != 0:
+		return &recording{replay: true, dir: replay}, nil //nolint:exhaustruct
+	default:
+		return nil, nil
+	}
+}
+
+// fixture sets the name of the fixture of the next command, in the fixture
+// directory of the markdown document.
+func (r *recording) fixture(filename string, name string) {
+	if r == nil {
+		return
+	}
+
+	r.name = filepath.Join(fixtureDir(r.dir, filename), name)
+}
+
+// fixtureDir returns the fixture directory of the markdown document under
+// root, named after the document path (relative to the current directory),
+// so the fixtures of the documents sharing root don't overwrite each other.
+// The documents outside of the current directory use their base name.
+func fixtureDir(root, filename string) string {
+	if abs, err := filepath.Abs(filename); err == nil {
+		if wd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(wd, abs); err == nil {
+				filename = rel
+			}
+		}
+	}
+
+	if filepath.IsLocal(filename) {
+		return filepath.Join(root, filename)
+	}
+
+	return filepath.Join(root, filepath.Base(filename))
+}
+
+// writers returns the writers capturing the output of the command too.
+func (r *recording) writers(stdout, stderr io.Writer) (io.Writer, io.Writer) {
+	if r == nil || len(r.name) == 0 {
+		return stdout, stderr
+	}
+
+	r.stdout.Reset()
+	r.stderr.Reset()
+
+	return io.MultiWriter(stdout, &r.stdout), io.MultiWriter(stderr, &r.stderr)
+}
+
+// finish writes the fixture of the command, or compares the command with its
+// fixture. When replaying, a command reproducing its fixture (even a failure)
+// passes, and one differing from it fails.
+func (r *recording) finish(exitCode int, opts *options) (int, error) {
+	if r == nil || len(r.name) == 0 {
+		return exitCode, nil
+	}
+
+	name := r.name
+	r.name = ""
+
+	if !r.replay {
+		if err := os.MkdirAll(filepath.Dir(name), dirMode); err != nil {
+			return exitCode, err
+		}
+
+		return exitCode, errors.Join(
+			os.WriteFile(name+".out", r.stdout.Bytes(), fileMode),
+			os.WriteFile(name+".err", r.stderr.Bytes(), fileMode),
+			os.WriteFile(name+".exit", []byte(strconv.Itoa(exitCode)+"\n"), fileMode),
+		)
+	}
+
+	mismatches, err := r.compare(name, exitCode)
+	if err != nil {
+		return exitCode, err
+	}
+
+	for _, mismatch := range mismatches {
+		opts.status("%s\n", opts.color.warn(mismatch))
+	}
+
+	switch {
+	case len(mismatches) == 0:
+		return 0, nil
+	case exitCode == 0:
+		return exitMismatch, nil
+	default:
+		return exitCode, nil
+	}
+}
+
+// compare returns the differences of the captured output and exit code from
+// the fixture.
+func (r *recording) compare(name string, exitCode int) ([]string, error) {
+	expected, err := os.ReadFile(name + ".exit")
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []string{fmt.Sprintf("no fixture recorded in %s, run with --record", filepath.Dir(name))}, nil
+		}
+
+		return nil, err
+	}
+
+	var mismatches []string
+
+	if code := strings.TrimSpace(string(expected)); code != strconv.Itoa(exitCode) {
+		mismatches = append(mismatches, fmt.Sprintf("exit code %d differs from the recorded %s", exitCode, code))
+	}
+
+	for _, stream := range []struct {
+		ext  string
+		data []byte
+	}{{".out", r.stdout.Bytes()}, {".err", r.stderr.Bytes()}} {
+		want, err := os.ReadFile(name + stream.ext)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+
+		if bytes.Equal(want, stream.data) {
+			continue
+		}
+
+		lines := []string{"output differs from " + name + stream.ext + ":"}
+
+		for _, line := range lineDiff(splitLines(want), splitLines(stream.data)) {
+			if !strings.HasPrefix(line, " ") {
+				lines = append(lines, line)
+			}
+		}
+
+		mismatches = append(mismatches, strings.Join(lines, "\n"))
+	}
+
+	return mismatches, nil
+}
+
+// fixtureName returns the name of the fixture of the code block: its name
+// metadata, or its index.
+func fixtureName(block *mdcode.Block, index int) string {
+	if name := block.Meta.Get(metaName); len(name) != 0 && filepath.IsLocal(name) {
+		return name
+	}
+
+	return fmt.Sprintf("block_%d", index)
+}
+
+var errRecordReplay = errors.New("--record and --replay can't be used together")
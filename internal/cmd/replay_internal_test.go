@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_recording_per_document(t *testing.T) {
+	dir := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.md"), []byte("```sh\necho A\n```\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.md"), []byte("```sh\necho B\n```\n"), 0o600))
+
+	for _, doc := range []string{"a.md", "b.md"} {
+		out, err := runMdcode(t, dir, "exec", "--no-history", "--yes", "--record", doc, "--", "sh {}")
+		require.NoError(t, err, out)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, defaultFixtureDir, "a.md", "block_1.out"))
+	require.NoError(t, err)
+	require.Equal(t, "A\n", string(data))
+
+	data, err = os.ReadFile(filepath.Join(dir, defaultFixtureDir, "b.md", "block_1.out"))
+	require.NoError(t, err)
+	require.Equal(t, "B\n", string(data))
+
+	out, err := runMdcode(t, dir, "exec", "--no-history", "--yes", "--replay", "a.md", "--", "sh {}")
+	require.NoError(t, err, out)
+}
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed report.html
+var reportTemplate string
+
+const (
+	reportHTML = "html"
+	reportJSON = "json"
+)
+
+// report collects the execution results of code blocks for --report.
+type report struct {
+	format   string
+	filename string
+	started  time.Time
+	entries  []*reportEntry
+	output   syncBuffer
+	tempDir  string
+}
+
+// reportEntry is the execution result of a code block (or a batch).
+type reportEntry struct {
+	Document  string        `json:"document"`
+	ID        string        `json:"id,omitempty"`
+	Title     string        `json:"title"`
+	Lang      string        `json:"lang,omitempty"`
+	Code      string        `json:"code"`
+	Command   string        `json:"command,omitempty"`
+	Output    string        `json:"output,omitempty"`
+	Duration  time.Duration `json:"duration_ns,omitempty"`
+	ExitCode  int           `json:"exit_code"`
+	Status    string        `json:"status"`
+	StartLine int           `json:"start_line"`
+}
+
+// Report entry statuses.
+const (
+	reportPassed  = "passed"
+	reportFailed  = "failed"
+	reportSkipped = "skipped"
+	reportCached  = "cached"
+)
+
+// newReport parses the format=filename value of --report.
+func newReport(value string) (*report, error) {
+	format, filename, ok := strings.Cut(value, "=")
+	if !ok || len(filename) == 0 {
+		return nil, fmt.Errorf("%w: %s", errReport, value)
+	}
+
+	if format != reportHTML && format != reportJSON {
+		return nil, fmt.Errorf("%w: %s", errReport, format)
+	}
+
+	return &report{format: format, filename: filename, started: time.Now()}, nil //nolint:exhaustruct
+}
+
+// newCollector returns a report collecting the execution results without
+// writing them, for the history journal.
+func newCollector() *report {
+	return &report{started: time.Now()} //nolint:exhaustruct
+}
+
+// captures reports whether the output of the commands is captured for the
+// report.
+func (r *report) captures() bool {
+	return r != nil && len(r.filename) != 0
+}
+
+// add records an execution result, taking the captured output of the last
+// command if it was executed.
+func (r *report) add(entry *reportEntry) {
+	if r == nil {
+		return
+	}
+
+	if entry.Status == reportPassed || entry.Status == reportFailed {
+		entry.Output = r.output.String()
+	}
+
+	r.entries = append(r.entries, entry)
+}
+
+func (r *report) count(status string) int {
+	count := 0
+
+	for _, entry := range r.entries {
+		if entry.Status == status {
+			count++
+		}
+	}
+
+	return count
+}
+
+// retain records the retained temporary directory in the report.
+func (r *report) retain(dir string) {
+	if r != nil {
+		r.tempDir = dir
+	}
+}
+
+func (r *report) write() error {
+	if len(r.filename) == 0 {
+		return nil
+	}
+
+	if r.format == reportJSON {
+		return r.writeJSON()
+	}
+
+	tmpl, err := template.New("report").Parse(reportTemplate)
+	if err != nil {
+		return err
+	}
+
+	var buff bytes.Buffer
+
+	err = tmpl.Execute(&buff, map[string]any{
+		"Started":  r.started.Format(time.RFC3339),
+		"Duration": time.Since(r.started).Round(time.Millisecond),
+		"Entries":  r.entries,
+		"Passed":   r.count(reportPassed),
+		"Failed":   r.count(reportFailed),
+		"Skipped":  r.count(reportSkipped) + r.count(reportCached),
+		"TempDir":  r.tempDir,
+	})
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(r.filename, buff.Bytes(), fileMode)
+}
+
+func (r *report) writeJSON() error {
+	data, err := json.MarshalIndent(map[string]any{
+		"started":     r.started.Format(time.RFC3339),
+		"duration_ns": time.Since(r.started),
+		"passed":      r.count(reportPassed),
+		"failed":      r.count(reportFailed),
+		"skipped":     r.count(reportSkipped) + r.count(reportCached),
+		"temp_dir":    r.tempDir,
+		"entries":     r.entries,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(r.filename, append(data, '\n'), fileMode)
+}
+
+// syncBuffer is a buffer safe for the concurrent writes of standard output
+// and error.
+type syncBuffer struct {
+	mu   sync.Mutex
+	buff bytes.Buffer
+}
+
+func (s *syncBuffer) Write(data []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.buff.Write(data)
+}
+
+func (s *syncBuffer) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buff.Reset()
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.buff.String()
+}
+
+var errReport = errors.New("invalid report (use html=filename or json=filename)")
+
+func blockEntry(filename string, info *blockInfo, code []byte, status string) *reportEntry {
+	return &reportEntry{
+		Document:  filename,
+		ID:        info.id,
+		Title:     fmt.Sprintf("block %d (%s%s)", info.index, info.lang, fileLabel(info.file)),
+		Lang:      info.lang,
+		Code:      string(code),
+		Status:    status,
+		StartLine: info.startLine,
+	}
+}
+
+func reportStatus(exitCode int) string {
+	if exitCode == 0 {
+		return reportPassed
+	}
+
+	return reportFailed
+}
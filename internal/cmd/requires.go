@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+)
+
+const (
+	metaRequires = "requires"
+
+	missingFail = "fail"
+	missingSkip = "skip"
+)
+
+var (
+	reRequirement = regexp.MustCompile(`^([\w.+-]+?)(?:(>=|<=|==|=|>|<)(\d+(?:\.\d+)*))?$`)
+	reVersion     = regexp.MustCompile(`\d+(?:\.\d+)*`)
+)
+
+// versionArgs are the arguments printing the version of the tools not
+// supporting --version.
+//
+//nolint:gochecknoglobals
+var versionArgs = map[string][]string{
+	"go":   {"version"},
+	"java": {"-version"},
+}
+
+// toolchain checks the requirements of the code blocks, caching the versions
+// of the tools.
+type toolchain struct {
+	mu       sync.Mutex
+	versions map[string]string
+}
+
+func newToolchain() *toolchain {
+	return &toolchain{versions: make(map[string]string)} //nolint:exhaustruct
+}
+
+// check verifies the space separated requirements of the requires metadata,
+// like "go>=1.22 docker": the tool must be on the PATH, with at least (or at
+// most) the given version. The error describes the first unmet requirement.
+func (t *toolchain) check(requires string) error {
+	for _, req := range strings.Fields(requires) {
+		match := reRequirement.FindStringSubmatch(req)
+		if match == nil {
+			return fmt.Errorf("%w: %s", errRequirement, req)
+		}
+
+		tool, op, want := match[1], match[2], match[3]
+
+		if _, err := exec.LookPath(tool); err != nil {
+			return fmt.Errorf("%w: %s not found on PATH", errUnmet, tool)
+		}
+
+		if len(op) == 0 {
+			continue
+		}
+
+		have := t.version(tool)
+		if len(have) == 0 {
+			return fmt.Errorf("%w: %s version unknown, %s%s required", errUnmet, tool, op, want)
+		}
+
+		if !compareVersion(have, op, want) {
+			return fmt.Errorf("%w: %s %s found, %s%s required", errUnmet, tool, have, op, want)
+		}
+	}
+
+	return nil
+}
+
+// enforceRequirements reports the unmet requirements of the code blocks to
+// be executed and refuses to execute the markdown document if there is any,
+// unless the code blocks are skipped with --missing skip.
+func (e *execOptions) enforceRequirements(filename string, src []byte, opts *options) error {
+	if e.missing != missingFail {
+		return nil
+	}
+
+	var count int
+
+	_, _, err := walk(src, func(block *mdcode.Block) error {
+		if len(platformMismatch(block)) != 0 {
+			return nil
+		}
+
+		err := e.toolchain.check(block.Meta.Get(metaRequires))
+		if err == nil {
+			return nil
+		}
+
+		if !errors.Is(err, errUnmet) {
+			return fmt.Errorf("%s: %w", opts.location(filename, block.StartLine), err)
+		}
+
+		count++
+
+		fmt.Fprintf(opts.stderr, "%s: %s\n", opts.location(filename, block.StartLine), err)
+		opts.event("block requirement not met", "document", filename, "line", block.StartLine, "requirement", err.Error())
+
+		return nil
+	}, opts.filter)
+	if err != nil {
+		return err
+	}
+
+	if count != 0 {
+		return fmt.Errorf("%w: %d code block(s), install the tools or use --missing skip", errUnmet, count)
+	}
+
+	return nil
+}
+
+// version returns the first version number printed by the tool.
+func (t *toolchain) version(tool string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if version, has := t.versions[tool]; has {
+		return version
+	}
+
+	args, has := versionArgs[tool]
+	if !has {
+		args = []string{"--version"}
+	}
+
+	out, _ := exec.Command(tool, args...).CombinedOutput() //nolint:gosec
+	version := reVersion.FindString(string(out))
+
+	t.versions[tool] = version
+
+	return version
+}
+
+// compareVersion compares the dotted version numbers have and want.
+func compareVersion(have, op, want string) bool {
+	cmp := 0
+	hparts, wparts := strings.Split(have, "."), strings.Split(want, ".")
+
+	for idx := 0; idx < len(hparts) || idx < len(wparts); idx++ {
+		var h, w int
+
+		if idx < len(hparts) {
+			h, _ = strconv.Atoi(hparts[idx])
+		}
+
+		if idx < len(wparts) {
+			w, _ = strconv.Atoi(wparts[idx])
+		}
+
+		if h != w {
+			cmp = 1
+			if h < w {
+				cmp = -1
+			}
+
+			break
+		}
+	}
+
+	switch op {
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	default:
+		return cmp == 0
+	}
+}
+
+var (
+	errRequirement = errors.New("invalid requirement")
+	errUnmet       = errors.New("requirement not met")
+	errMissing     = errors.New("invalid --missing value (use fail or skip)")
+)
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_reVersion(t *testing.T) {
+	t.Parallel()
+
+	for out, expected := range map[string]string{
+		"go version go1.22.1 linux/amd64":   "1.22.1",
+		"Python 3.12.4":                     "3.12.4",
+		"openjdk version \"21\" 2023-10-17": "21",
+		"tool version 21":                   "21",
+	} {
+		require.Equal(t, expected, reVersion.FindString(out), out)
+	}
+}
+
+func Test_toolchain_check_major(t *testing.T) {
+	dir := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "majortool"), []byte("#!/bin/sh\necho majortool version 21\n"), 0o700)) //nolint:gosec
+
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	require.NoError(t, newToolchain().check("majortool>=17"))
+	require.ErrorIs(t, newToolchain().check("majortool>=22"), errUnmet)
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+)
+
+const metaRetries = "retries"
+
+// blockRetries returns the number of retries of the code block: the value of
+// its retries metadata, or --retries by default.
+func (e *execOptions) blockRetries(block *mdcode.Block) (int, error) {
+	value := block.Meta.Get(metaRetries)
+	if len(value) == 0 {
+		return e.retries, nil
+	}
+
+	retries, err := strconv.Atoi(value)
+	if err != nil || retries < 0 {
+		return 0, fmt.Errorf("%w: %s", errRetries, value)
+	}
+
+	return retries, nil
+}
+
+// retry calls run until it succeeds or the retries are exhausted. The delay
+// between the attempts starts with --retry-delay and doubles after each one.
+func (e *execOptions) retry(retries int, opts *options, run func() (int, error)) (int, error) {
+	delay := e.retryDelay
+
+	for attempt := 1; ; attempt++ {
+		exitCode, err := run()
+		if err != nil || exitCode == 0 || attempt > retries {
+			return exitCode, err
+		}
+
+		opts.status("%s\n", opts.color.warn(fmt.Sprintf("exit status %d, retrying in %s (%d/%d)", exitCode, delay, attempt, retries)))
+		opts.event("block retried", "attempt", attempt, "exit_code", exitCode)
+
+		time.Sleep(delay)
+
+		delay *= 2
+	}
+}
+
+var errRetries = errors.New("invalid retries metadata (use a non-negative number)")
@@ -4,12 +4,14 @@ package cmd
 import (
 	_ "embed"
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"runtime/debug"
 	"strings"
 
+	"github.com/ezerfernandes/mdcode/internal/textenc"
 	"github.com/spf13/cobra"
 )
 
@@ -33,11 +35,27 @@ func init() {
 func Execute(args []string, stdout, stderr io.Writer) {
 	root := RootCmd()
 
+	if path, found := findPlugin(root, args); found {
+		code, err := runPlugin(path, args[1:], stdout, stderr)
+		if err != nil {
+			fmt.Fprintln(stderr, "Error:", err)
+		}
+
+		os.Exit(code)
+	}
+
 	root.SetArgs(args)
 	root.SetErr(stderr)
 	root.SetOut(stdout)
 
-	cobra.CheckErr(root.Execute())
+	err := root.Execute()
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+	}
+
+	exitZero, _ := root.PersistentFlags().GetBool("exit-zero")
+
+	os.Exit(exitCodeOf(err, exitZero))
 }
 
 //go:embed help/root.md
@@ -54,13 +72,46 @@ func RootCmd() *cobra.Command {
 		Version: version,
 		Args:    checkargs,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			err := opts.createFilter()
+			err := opts.createFilter(cmd)
 			if err != nil {
 				return err
 			}
 
-			if flag := cmd.Flag("dir"); flag != nil && !flag.Changed {
-				opts.dir = filepath.Dir(source(args))
+			if _, err = newPalette(opts.colorMode, cmd.ErrOrStderr()); err != nil {
+				return err
+			}
+
+			if opts.logFormat != logFormatText && opts.logFormat != logFormatJSON {
+				return fmt.Errorf("%w: %s", errLogFormat, opts.logFormat)
+			}
+
+			if !validFormat(opts.format) {
+				return fmt.Errorf("%w: %s", errFormat, opts.format)
+			}
+
+			if err = opts.parseMaxBlockSize(); err != nil {
+				return err
+			}
+
+			if err = opts.loadSelection(cmd.InOrStdin()); err != nil {
+				return err
+			}
+
+			if !validEOL(opts.eol) {
+				return fmt.Errorf("%w: %s", errEOL, opts.eol)
+			}
+
+			if _, err = textenc.Normalize(opts.encoding); err != nil {
+				return err
+			}
+
+			documentDir(cmd, opts, opts.source(args))
+
+			return nil
+		},
+		PersistentPostRunE: func(cmd *cobra.Command, _ []string) error {
+			if opts.requireMatch && opts.matched == 0 && filterChanged(cmd) {
+				return errNoMatch
 			}
 
 			return nil
@@ -71,7 +122,12 @@ func RootCmd() *cobra.Command {
 				return err
 			}
 
-			if err = listRun(source(args), out, opts); err != nil {
+			files, err := sources(args, opts)
+			if err != nil {
+				return err
+			}
+
+			if err = listRun(files, out, opts); err != nil {
 				return err
 			}
 
@@ -83,23 +139,55 @@ func RootCmd() *cobra.Command {
 		DisableAutoGenTag: true,
 	}
 
+	cmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return fmt.Errorf("%w: %w", errUsage, err)
+	})
+
 	cmd.SetVersionTemplate(
 		`{{with .Name}}{{printf "%s" .}}{{end}}{{printf " version %s\n" .Version}}`,
 	)
 
 	globalFlags(cmd, opts)
+	registerCompletions(cmd)
 
 	outputFlag(cmd, opts)
 
 	cmd.Flags().BoolVar(&opts.json, "json", false, "generate JSON output")
+	cmd.Flags().BoolVar(&opts.blame, "git", false, "add the last commit, author and date of the code blocks from git blame")
 
 	cmd.AddCommand(updateCmd(opts))
 	cmd.AddCommand(extractCmd(opts))
 	cmd.AddCommand(dumpCmd(opts))
 	cmd.AddCommand(runCmd(opts))
 	cmd.AddCommand(execCmd(opts))
-
-	cmd.AddCommand(metadataTopic(), filteringTopic(), regionsTopic(), invisibleTopic(), outlineTopic())
+	cmd.AddCommand(lspCmd())
+	cmd.AddCommand(hookCmd(opts))
+	cmd.AddCommand(fenceCmd(opts))
+	cmd.AddCommand(mergeCmd(opts))
+	cmd.AddCommand(splitCmd(opts))
+	cmd.AddCommand(dupesCmd(opts))
+	cmd.AddCommand(coverageCmd(opts))
+	cmd.AddCommand(gistCmd(opts))
+	cmd.AddCommand(graphCmd(opts))
+	cmd.AddCommand(normalizeCmd(opts))
+	cmd.AddCommand(labelCmd(opts))
+	cmd.AddCommand(numberCmd(opts))
+	cmd.AddCommand(showCmd(opts))
+	cmd.AddCommand(sedCmd(opts))
+	cmd.AddCommand(templateCmd(opts))
+	cmd.AddCommand(pluginsCmd())
+	cmd.AddCommand(validateCmd(opts))
+	cmd.AddCommand(lintCmd(opts))
+	cmd.AddCommand(approveCmd(opts))
+	cmd.AddCommand(renderCmd(opts))
+	cmd.AddCommand(doctorCmd(opts))
+	cmd.AddCommand(uiCmd(opts))
+	cmd.AddCommand(historyCmd(opts))
+	cmd.AddCommand(undoCmd(opts))
+	cmd.AddCommand(expandCmd(opts))
+
+	cmd.AddCommand(metadataTopic(), filteringTopic(), regionsTopic(), invisibleTopic(), outlineTopic(),
+		recursiveTopic(), statusTopic(), exitCodesTopic())
 
 	return cmd
 }
@@ -109,7 +197,38 @@ func globalFlags(cmd *cobra.Command, opts *options) {
 
 	flags.StringSliceVarP(&opts.file, "file", "f", []string{"?*"}, "file filter")
 	flags.StringSliceVarP(&opts.lang, "lang", "l", []string{"?*"}, "language filter")
+	flags.StringSliceVarP(&opts.group, "group", "g", nil, "group filter")
 	flags.StringToStringVarP(&opts.meta, "meta", "m", nil, "metadata filter")
+	flags.StringArrayVar(&opts.tags, "tags", nil, "tag filter, one of the comma separated tags is required, repeat the flag to require all")
+	flags.StringSliceVar(&opts.ids, "id", nil, "process only the code blocks with the given identifiers (or identifier prefixes, see mdcode list)")
+	flags.StringSliceVar(&opts.headings, "heading", nil, "section heading filter (code blocks in a section with a matching heading)")
+	flags.StringArrayVar(&opts.skipTags, "skip-tags", nil, "skip code blocks having any of the comma separated tags")
+	flags.StringSliceVar(&opts.defaultDocuments, "default-document", []string{defaultArg, "*.md"},
+		"patterns of the markdown document processed if the filename argument is missing (the first single match is used)")
+	flags.BoolVarP(&opts.recursive, "recursive", "r", false, "process markdown files in the directory tree")
+	flags.StringSliceVar(&opts.include, "include", []string{defaultInclude}, "file name pattern to include (with --recursive)")
+	flags.StringSliceVar(&opts.exclude, "exclude", nil, "file name pattern to exclude (with --recursive)")
+	flags.BoolVar(&opts.noIgnore, "no-ignore", false, "don't skip files listed in "+ignoreFilename+" files")
+	flags.StringToStringVar(&opts.langAlias, "lang-alias", nil, "additional language alias (e.g. nodejs=js)")
+	flags.StringSliceVar(&opts.fileKeys, "file-keys", defaultFileKeys, "metadata keys used as file name when the file metadata is missing")
+	flags.BoolVar(&opts.inferLang, "infer-lang", false, "infer the language of unlabeled code blocks from their file metadata, shebang or keywords")
+	flags.StringVar(&opts.changed, "changed", "", "process only code blocks overlapping lines changed since a git revision (default HEAD)")
+	flags.Lookup("changed").NoOptDefVal = changedDefault
+	flags.StringVar(&opts.fromSelection, "from-selection", "",
+		"process only the code blocks of lines picked from the pick format listing (- reads them from stdin)")
+	flags.BoolVar(&opts.hidden, "hidden", true, "process invisible code blocks (use --hidden=false to skip them)")
+	flags.StringVar(&opts.colorMode, "color", colorAuto, "colorize the status output (auto, always or never)")
+	flags.StringVar(&opts.logFormat, "log-format", logFormatText, "status output format (text or json)")
+	flags.StringVar(&opts.format, "format", formatText, "listing and diagnostic format (text, compact for editors: file:line:column, csv, tsv or pick for fzf)")
+	flags.StringVar(&opts.encoding, "encoding", "", "encoding of markdown documents (utf-8, latin-1 or utf-16, default: detected from the byte order mark)")
+	flags.BoolVar(&opts.allowOutside, "allow-outside", false, "allow file metadata pointing outside of the base directory (absolute or ../ paths)")
+	flags.StringVar(&opts.maxBlockSize, "max-block-size", "0", "refuse documents having a larger code block (e.g. 10M, 0 for no limit)")
+	flags.IntVar(&opts.maxBlocks, "max-blocks", 0, "refuse documents having more code blocks (0 for no limit)")
+	flags.BoolVar(&opts.insecure, "insecure", false, "don't verify the certificate of https markdown document URLs")
+	flags.StringArrayVar(&opts.headers, "header", nil, "HTTP header sent when fetching markdown document URLs (\"Name: value\")")
+	flags.BoolVar(&opts.requireMatch, "require-match", false, "fail if the --lang, --file or --meta filters select no code blocks")
+	flags.BoolVar(&opts.exitZero, "exit-zero", false, "exit with 0 when code blocks failed, drift is detected or nothing matched the filter")
+	flags.StringVar(&opts.eol, "eol", eolAuto, "line ending of written code (lf, crlf or native, default: same as the document)")
 }
 
 func outputFlag(cmd *cobra.Command, opts *options) {
@@ -126,6 +245,7 @@ func dirFlag(cmd *cobra.Command, opts *options) {
 
 func quietFlag(cmd *cobra.Command, opts *options) {
 	cmd.Flags().BoolVarP(&opts.quiet, "quiet", "q", false, "suppress the status output")
+	cmd.Flags().CountVarP(&opts.verbosity, "verbose", "v", "increase the status output verbosity (-vv shows timing)")
 }
 
 func checkargs(cmd *cobra.Command, args []string) error {
@@ -135,18 +255,65 @@ func checkargs(cmd *cobra.Command, args []string) error {
 		return errTooManyArg
 	}
 
+	if recursive, _ := cmd.Flags().GetBool("recursive"); recursive {
+		return nil
+	}
+
+	if selection, _ := cmd.Flags().GetString("from-selection"); len(selection) != 0 {
+		return nil
+	}
+
 	if len(args) == 0 {
-		if _, err := os.Stat(defaultArg); errors.Is(err, os.ErrNotExist) {
-			return errMissingArg
+		patterns, err := cmd.Flags().GetStringSlice("default-document")
+		if err != nil {
+			return err
+		}
+
+		if _, err = defaultDocument(patterns); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
+// defaultDocument returns the markdown document processed when the filename
+// argument is missing: the only file matching the first pattern with matches.
+func defaultDocument(patterns []string) (string, error) {
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return "", err
+		}
+
+		var files []string
+
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && info.Mode().IsRegular() {
+				files = append(files, match)
+			}
+		}
+
+		switch len(files) {
+		case 0:
+			continue
+		case 1:
+			return files[0], nil
+		default:
+			return "", fmt.Errorf("%w: %s", errAmbiguousArg, strings.Join(files, ", "))
+		}
+	}
+
+	return "", errMissingArg
+}
+
 var (
-	errMissingArg = errors.New("the filename argument is missing and " + defaultArg + " is not found")
-	errTooManyArg = errors.New("too many arguments")
+	errMissingArg   = errors.New("the filename argument is missing and no markdown document is found")
+	errAmbiguousArg = errors.New("the filename argument is missing and several markdown documents are found")
+	errTooManyArg   = errors.New("too many arguments")
+	errLogFormat    = errors.New("invalid log format (use text or json)")
+	errEOL          = errors.New("invalid line ending (use lf, crlf or native)")
+	errFormat       = errors.New("invalid format (use text, compact, csv, tsv or pick)")
 )
 
 func openOutput(out string, cmd *cobra.Command) (io.Writer, error) {
@@ -165,12 +332,20 @@ func closeOutput(out io.Writer) error {
 	return nil
 }
 
-func source(args []string) string {
-	if len(args) == 0 {
-		return defaultArg
+func (o *options) source(args []string) string {
+	if len(args) != 0 {
+		return args[0]
+	}
+
+	if o.selection != nil {
+		return o.selection.documents[0]
+	}
+
+	if document, err := defaultDocument(o.defaultDocuments); err == nil {
+		return document
 	}
 
-	return args[0]
+	return defaultArg
 }
 
 func script(cmd *cobra.Command, args []string) (string, []string) {
@@ -186,4 +361,5 @@ const (
 
 	dirMode  = 0o750
 	fileMode = 0o600
+	execMode = 0o755
 )
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 
@@ -19,18 +20,34 @@ import (
 var runHelp string
 
 func runCmd(opts *options) *cobra.Command {
+	var yes bool
+
 	cmd := &cobra.Command{ //nolint:exhaustruct
-		Use:     "run [flags] [filename] [-- commands]",
+		Use:     "run [flags] [filename [name]] [-- commands]",
 		Aliases: []string{"r"},
 		Short:   "Run shell commands on markdown code blocks",
 		Long:    runHelp,
-		Args:    checkargs,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if scr, args := script(cmd, args); len(args) == 2 { //nolint:gomnd
+				if len(scr) != 0 {
+					return errTooManyArg
+				}
+
+				return nil
+			}
+
+			return checkargs(cmd, args)
+		},
 		PreRun: func(cmd *cobra.Command, _ []string) {
 			opts.createStatus(cmd.ErrOrStderr())
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			script, args := script(cmd, args)
 
+			if len(args) == 2 { //nolint:gomnd
+				opts.name, args = args[1], args[:1]
+			}
+
 			if !cmd.Flag("dir").Changed {
 				dir, err := os.MkdirTemp(".", "mdcode-tmp-")
 				if err != nil {
@@ -44,7 +61,17 @@ func runCmd(opts *options) *cobra.Command {
 				}
 			}
 
-			return runRun(source(args), opts, script)
+			files, err := sources(args, opts)
+			if err != nil {
+				return err
+			}
+
+			trust, err := loadTrust(yes, cmd.InOrStdin(), cmd.ErrOrStderr())
+			if err != nil {
+				return err
+			}
+
+			return runRun(files, opts, script, trust)
 		},
 		DisableAutoGenTag: true,
 	}
@@ -53,7 +80,10 @@ func runCmd(opts *options) *cobra.Command {
 	quietFlag(cmd, opts)
 
 	cmd.Flags().StringVarP(&opts.name, "name", "n", "", "code block name contains commands")
+	cmd.Flags().IntVarP(&opts.block, "block", "b", 0, "run the code block with the given number (1-based) by the built-in runner of its language")
+	cobra.CheckErr(cmd.RegisterFlagCompletionFunc("block", completeBlocks))
 	cmd.Flags().BoolVarP(&opts.keep, "keep", "k", false, "don't remove temporary directory")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "run documents not run before (or modified since) without confirmation, and trust them")
 
 	return cmd
 }
@@ -64,15 +94,39 @@ func isScript(lang string, meta mdcode.Meta) bool {
 	return reShell.MatchString(lang) && len(meta.Get(metaName)) != 0
 }
 
-func findScript(filename string, opts *options) (string, error) {
-	src, err := os.ReadFile(filename)
-	if err != nil {
-		return "", err
+func findScript(filenames []string, opts *options) (string, error) {
+	var script string
+
+	for _, filename := range filenames {
+		if len(script) != 0 {
+			break
+		}
+
+		src, _, err := opts.readDocument(filename)
+		if err != nil {
+			return "", err
+		}
+
+		if script, err = findScriptIn(src, opts); err != nil {
+			return "", err
+		}
 	}
 
+	if len(script) == 0 {
+		if len(opts.name) != 0 {
+			return "", fmt.Errorf("%w: %s", errMissingScript, opts.name)
+		}
+
+		return "", fmt.Errorf("%w: '-- commands' argument required", errMissingScript)
+	}
+
+	return script, nil
+}
+
+func findScriptIn(src []byte, opts *options) (string, error) {
 	var script string
 
-	_, _, err = mdcode.Walk(src, func(block *mdcode.Block) error {
+	_, _, err := mdcode.Walk(src, func(block *mdcode.Block) error {
 		if len(script) != 0 {
 			return nil
 		}
@@ -93,24 +147,37 @@ func findScript(filename string, opts *options) (string, error) {
 
 		return nil
 	})
-	if err != nil {
-		return "", err
-	}
 
-	if len(script) == 0 {
-		if len(opts.name) != 0 {
-			return "", fmt.Errorf("%w: %s", errMissingScript, opts.name)
+	return script, err
+}
+
+func runRun(filenames []string, opts *options, script string, trust *trust) error {
+	for _, filename := range filenames {
+		src, _, err := opts.readDocument(filename)
+		if err != nil {
+			return err
 		}
 
-		return "", fmt.Errorf("%w: '-- commands' argument required", errMissingScript)
+		if err := trust.check(filename, src); err != nil {
+			return err
+		}
 	}
 
-	return script, nil
-}
+	if opts.block > 0 && len(script) == 0 {
+		value, err := blockScript(filenames, opts)
+		if err != nil {
+			return err
+		}
+
+		script = value
+	}
 
-func runRun(filename string, opts *options, script string) error {
 	if len(script) == 0 {
-		value, err := findScript(filename, opts)
+		value, err := findScript(filenames, opts)
+		if errors.Is(err, errMissingScript) && len(opts.name) != 0 {
+			value, err = blockScript(filenames, opts)
+		}
+
 		if err != nil {
 			return err
 		}
@@ -118,8 +185,10 @@ func runRun(filename string, opts *options, script string) error {
 		script = value
 	}
 
-	if err := extractRun(filename, opts); err != nil {
-		return err
+	for _, filename := range filenames {
+		if err := extractRun(filename, opts); err != nil {
+			return err
+		}
 	}
 
 	opts.status("Executing in %s\n%s\n", opts.dir, script)
@@ -137,4 +206,72 @@ func runRun(filename string, opts *options, script string) error {
 	return runner.Run(context.TODO(), file)
 }
 
-var errMissingScript = errors.New("missing script")
+// blockScript writes the --block code block (or the code block named by
+// --name) of the markdown document to the directory (unless it is extracted
+// by its file metadata) and returns the command running it, chosen by the
+// language of the code block.
+func blockScript(filenames []string, opts *options) (string, error) {
+	if len(filenames) != 1 {
+		return "", fmt.Errorf("%w: a single markdown document is required", errMissingScript)
+	}
+
+	src, _, err := opts.readDocument(filenames[0])
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		found *mdcode.Block
+		index int
+	)
+
+	_, _, err = mdcode.Walk(src, func(block *mdcode.Block) error {
+		index++
+
+		if found == nil && ((opts.block > 0 && index == opts.block) || (opts.block == 0 && block.Meta.Get(metaName) == opts.name)) {
+			found = block
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case found == nil && opts.block == 0:
+		return "", fmt.Errorf("%w: %s", errMissingScript, opts.name)
+	case found == nil:
+		return "", fmt.Errorf("%w: %d (the document has %d code blocks)", errMissingBlock, opts.block, index)
+	}
+
+	lang := opts.aliases.canonical(found.Lang)
+
+	runner, has := langRunners[lang]
+	if !has {
+		return "", fmt.Errorf("%w: %s (line %d)", errNoRunner, langLabel(found.Lang), found.StartLine)
+	}
+
+	name := found.Meta.Get(metaFile)
+	if len(name) == 0 || len(found.Meta.Get(metaRegion)) != 0 {
+		name = "main" + langExtension(lang)
+
+		if err := os.WriteFile(filepath.Join(opts.dir, name), found.Code, fileMode); err != nil {
+			return "", err
+		}
+	}
+
+	// The file name comes from the document, it may contain shell syntax.
+	quoted, err := syntax.Quote(name, syntax.LangPOSIX)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s (line %d)", errScriptName, name, found.StartLine)
+	}
+
+	return strings.ReplaceAll(runner, "{}", quoted), nil
+}
+
+var (
+	errMissingScript = errors.New("missing script")
+	errNoRunner      = errors.New("no built-in runner for language")
+	errScriptName    = errors.New("invalid file name of script")
+)
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_runRun_untrusted(t *testing.T) {
+	dir := t.TempDir()
+
+	src := "```sh name=build\ntouch built\n```\n"
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "doc.md"), []byte(src), 0o600))
+
+	out, err := runMdcode(t, dir, "run", "--dir", ".", "doc.md", "build")
+	require.ErrorIs(t, err, errUntrusted, out)
+	require.NoFileExists(t, filepath.Join(dir, "built"))
+
+	out, err = runMdcode(t, dir, "run", "--block", "1", "--dir", ".", "doc.md")
+	require.ErrorIs(t, err, errUntrusted, out)
+	require.NoFileExists(t, filepath.Join(dir, "built"))
+
+	out, err = runMdcode(t, dir, "run", "--yes", "--dir", ".", "doc.md", "build")
+	require.NoError(t, err, out)
+	require.FileExists(t, filepath.Join(dir, "built"))
+}
+
+func Test_browser_run_untrusted(t *testing.T) {
+	dir := t.TempDir()
+
+	src := "```sh\ntouch \"$HOME/built\"\n```\n"
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "doc.md"), []byte(src), 0o600))
+
+	out, err := runMdcodeInput(t, dir, "r 1\nn\nq\n", "ui", "doc.md")
+	require.NoError(t, err, out)
+	require.Contains(t, out, errUntrusted.Error())
+	require.NoFileExists(t, filepath.Join(dir, "built"))
+
+	out, err = runMdcodeInput(t, dir, "r 1\ny\nq\n", "ui", "doc.md")
+	require.NoError(t, err, out)
+	require.FileExists(t, filepath.Join(dir, "built"))
+}
+
+func Test_blockScript_quoted(t *testing.T) {
+	dir := t.TempDir()
+
+	src := "```sh file=\"my script.sh\" name=build\ntouch built\n```\n\n```sh file=\"$(touch pwned).sh\" name=evil\n```\n"
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "doc.md"), []byte(src), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "my script.sh"), []byte("touch built\n"), 0o600))
+
+	out, err := runMdcode(t, dir, "run", "--yes", "--dir", ".", "doc.md", "build")
+	require.NoError(t, err, out)
+	require.FileExists(t, filepath.Join(dir, "built"))
+
+	out, _ = runMdcode(t, dir, "run", "--yes", "--dir", ".", "doc.md", "evil")
+	require.NoFileExists(t, filepath.Join(dir, "pwned"), out)
+}
@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+const (
+	sandboxNsjail   = "nsjail"
+	sandboxBwrap    = "bwrap"
+	sandboxFirejail = "firejail"
+)
+
+// isolation runs the command of a code block isolated from the host, in a
+// container or in a sandbox.
+type isolation interface {
+	run(lang, command, dir string, stdout, stderr io.Writer) (int, error)
+}
+
+// sandbox runs the commands of the code blocks with a lightweight sandbox
+// program: the network is not available, and with readOnly the file system
+// is read-only except for the temporary directory and /tmp is private.
+type sandbox struct {
+	name     string
+	readOnly bool
+	prefix   []string
+}
+
+// newSandbox returns the sandbox of the sandbox program, the prefix
+// (applying the resource limits) is run inside the sandbox.
+func newSandbox(name string, readOnly bool, prefix []string) (*sandbox, error) {
+	switch name {
+	case sandboxNsjail, sandboxBwrap, sandboxFirejail:
+	default:
+		return nil, fmt.Errorf("%w: %s", errSandbox, name)
+	}
+
+	// Checked before anything runs, not when the first command fails.
+	if _, err := exec.LookPath(name); err != nil {
+		return nil, fmt.Errorf("%w: %s", errSandboxMissing, name)
+	}
+
+	return &sandbox{name: name, readOnly: readOnly, prefix: prefix}, nil
+}
+
+// availableSandbox returns the first sandbox program found on the PATH.
+func availableSandbox() (string, bool) {
+	for _, name := range []string{sandboxBwrap, sandboxNsjail, sandboxFirejail} {
+		if _, err := exec.LookPath(name); err == nil {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
+// isolationFlags are the exec flags isolating the commands of the code
+// blocks from the host.
+type isolationFlags struct {
+	image     string
+	engine    string
+	images    map[string]string
+	sandbox   string
+	noNetwork bool
+}
+
+// setIsolation selects the container (--container) or the sandbox
+// (--sandbox) running the commands of the code blocks, if any. With
+// --no-network alone, an available sandbox denies the network access,
+// otherwise the proxy environment variables point to an unreachable proxy.
+func (e *execOptions) setIsolation(flags *isolationFlags, aliases langAliases) error {
+	if len(flags.image) != 0 || len(flags.sandbox) != 0 {
+		if (len(flags.image) != 0 && len(flags.sandbox) != 0) || e.batch || e.session {
+			return errIsolation
+		}
+	}
+
+	switch {
+	case len(flags.image) != 0:
+		box, err := newContainer(flags.engine, flags.image, flags.images, flags.noNetwork, aliases)
+		if err != nil {
+			return err
+		}
+
+		e.isolation = box
+
+		return nil
+	case len(flags.sandbox) != 0:
+		return e.setSandbox(flags.sandbox, true)
+	case !flags.noNetwork:
+		return nil
+	}
+
+	if !e.batch && !e.session {
+		if name, found := availableSandbox(); found {
+			return e.setSandbox(name, false)
+		}
+	}
+
+	return setOfflineEnv()
+}
+
+func (e *execOptions) setSandbox(name string, readOnly bool) error {
+	prefix, err := e.limits.prefix()
+	if err != nil {
+		return err
+	}
+
+	jail, err := newSandbox(name, readOnly, prefix)
+	if err != nil {
+		return err
+	}
+
+	e.isolation = jail
+
+	return nil
+}
+
+// offlineEnv are the environment variables sending the network requests of
+// the commands to an unreachable proxy (the discard port of the loopback
+// interface), and switching common package managers to offline mode.
+//
+//nolint:gochecknoglobals
+var offlineEnv = map[string]string{
+	"http_proxy":         offlineProxy,
+	"https_proxy":        offlineProxy,
+	"ftp_proxy":          offlineProxy,
+	"all_proxy":          offlineProxy,
+	"HTTP_PROXY":         offlineProxy,
+	"HTTPS_PROXY":        offlineProxy,
+	"FTP_PROXY":          offlineProxy,
+	"ALL_PROXY":          offlineProxy,
+	"no_proxy":           offlineNoProxy,
+	"NO_PROXY":           offlineNoProxy,
+	"GOPROXY":            "off",
+	"GOTOOLCHAIN":        "local",
+	"PIP_NO_INDEX":       "1",
+	"npm_config_offline": "true",
+	"CARGO_NET_OFFLINE":  "true",
+}
+
+const (
+	offlineProxy   = "http://127.0.0.1:9"
+	offlineNoProxy = "localhost,127.0.0.1,::1"
+)
+
+// setOfflineEnv sets the offline environment variables of the executed
+// commands.
+func setOfflineEnv() error {
+	for key, value := range offlineEnv {
+		if err := os.Setenv(key, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// args returns the command line running the command in dir in the sandbox.
+func (s *sandbox) args(command, dir string) ([]string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var args []string
+
+	switch {
+	case !s.readOnly:
+		args = s.networkArgs(dir)
+	case s.name == sandboxNsjail:
+		args = []string{
+			sandboxNsjail, "--mode", "o", "--quiet", "--disable_rlimits", "--time_limit", "0",
+			"--bindmount_ro", "/", "--tmpfsmount", "/tmp", "--bindmount", dir, "--cwd", dir, "--",
+		}
+	case s.name == sandboxBwrap:
+		args = []string{
+			sandboxBwrap, "--ro-bind", "/", "/", "--dev", "/dev", "--proc", "/proc", "--tmpfs", "/tmp",
+			"--bind", dir, dir, "--chdir", dir, "--unshare-net", "--unshare-pid", "--die-with-parent", "--",
+		}
+	case s.name == sandboxFirejail:
+		args = []string{
+			sandboxFirejail, "--quiet", "--noprofile", "--net=none", "--private-tmp",
+			"--read-only=/", "--read-write=" + dir, "--",
+		}
+	}
+
+	args = append(args, s.prefix...)
+
+	return append(args, "/bin/sh", "-c", command), nil
+}
+
+// networkArgs returns the sandbox program arguments denying only the network
+// access.
+func (s *sandbox) networkArgs(dir string) []string {
+	switch s.name {
+	case sandboxNsjail:
+		return []string{
+			sandboxNsjail, "--mode", "o", "--quiet", "--disable_rlimits", "--time_limit", "0",
+			"--bindmount", "/", "--cwd", dir, "--",
+		}
+	case sandboxBwrap:
+		return []string{
+			sandboxBwrap, "--bind", "/", "/", "--dev", "/dev", "--proc", "/proc",
+			"--chdir", dir, "--unshare-net", "--die-with-parent", "--",
+		}
+	default:
+		return []string{sandboxFirejail, "--quiet", "--noprofile", "--net=none", "--"}
+	}
+}
+
+// run runs the command of the code block in the sandbox.
+func (s *sandbox) run(_, command, dir string, stdout, stderr io.Writer) (int, error) {
+	args, err := s.args(command, dir)
+	if err != nil {
+		return -1, err
+	}
+
+	return runProgram(args, dir, os.Stdin, stdout, stderr)
+}
+
+var (
+	errSandbox        = errors.New("unknown sandbox (nsjail, bwrap or firejail)")
+	errSandboxMissing = errors.New("sandbox program not found")
+	errIsolation      = errors.New("--container and --sandbox can't be used together, nor with --batch or --session")
+)
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recordIsolation records the commands instead of running them.
+type recordIsolation struct {
+	commands []string
+}
+
+func (r *recordIsolation) run(_, command, _ string, _, _ io.Writer) (int, error) {
+	r.commands = append(r.commands, command)
+
+	return 0, nil
+}
+
+func Test_execOptions_execute_isolated(t *testing.T) {
+	t.Parallel()
+
+	box := new(recordIsolation)
+	eopts := &execOptions{isolation: box, shell: shellBuiltin} //nolint:exhaustruct
+
+	exitCode, err := eopts.execute("touch ran", t.TempDir(), io.Discard, io.Discard)
+	require.NoError(t, err)
+	require.Zero(t, exitCode)
+	require.Equal(t, []string{"touch ran"}, box.commands)
+}
+
+func Test_exec_sandbox_missing(t *testing.T) {
+	if _, err := exec.LookPath(sandboxFirejail); err == nil {
+		t.Skip("firejail is installed")
+	}
+
+	dir := t.TempDir()
+	src := "```sh role=setup\ntouch \"$HOME/setup\"\n```\n\n```console\n$ touch \"$HOME/console\"\n```\n"
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "doc.md"), []byte(src), 0o600))
+
+	out, err := runMdcode(t, dir, "exec", "--no-history", "--yes", "--console", "--sandbox", sandboxFirejail, "doc.md")
+	require.ErrorIs(t, err, errSandboxMissing, out)
+	require.NoFileExists(t, filepath.Join(dir, "setup"))
+	require.NoFileExists(t, filepath.Join(dir, "console"))
+}
+
+func Test_exec_no_network_sandboxed(t *testing.T) {
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "bin")
+	log := filepath.Join(dir, "sandbox.log")
+
+	// The fake sandbox logs the commands and runs them after the "--".
+	fake := "#!/bin/sh\nwhile [ \"$1\" != -- ]; do shift; done\nshift\necho \"$3\" >> " + log + "\nexec \"$@\"\n"
+
+	require.NoError(t, os.MkdirAll(bin, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(bin, sandboxBwrap), []byte(fake), 0o700)) //nolint:gosec
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	src := "```sh role=setup\necho setup\n```\n\n```sh role=teardown\necho teardown\n```\n\n" +
+		"```console\n$ echo step\nstep\n```\n"
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "doc.md"), []byte(src), 0o600))
+
+	out, err := runMdcode(t, dir, "exec", "--no-history", "--yes", "--console", "--no-network", "doc.md")
+	require.NoError(t, err, out)
+
+	data, err := os.ReadFile(log)
+	require.NoError(t, err)
+	require.Equal(t, "echo setup\n\necho step\necho teardown\n\n", string(data))
+}
@@ -0,0 +1,272 @@
+package cmd
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/spf13/cobra"
+)
+
+//go:embed help/sed.md
+var sedHelp string
+
+func sedCmd(opts *options) *cobra.Command {
+	var (
+		sub    *substitution
+		dryRun bool
+	)
+
+	cmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "sed [flags] s/regexp/replacement/[flags] [filename]",
+		Short: "Search and replace in the code blocks only",
+		Long:  sedHelp,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return fmt.Errorf("%w: missing expression", errSubstitution)
+			}
+
+			return checkargs(cmd, args[1:])
+		},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			opts.createStatus(cmd.ErrOrStderr())
+
+			var err error
+
+			if sub, err = parseSubstitution(args[0]); err != nil {
+				return err
+			}
+
+			if err = opts.createFilter(cmd); err != nil {
+				return err
+			}
+
+			return opts.allBlocksFilter(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files, err := sources(args[1:], opts)
+			if err != nil {
+				return err
+			}
+
+			return opts.eachSource(files, func(file string) error {
+				return sedRun(file, opts, sub, dryRun)
+			})
+		},
+
+		DisableAutoGenTag: true,
+	}
+
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "only report the code blocks that would be changed")
+
+	quietFlag(cmd, opts)
+
+	return cmd
+}
+
+func sedRun(filename string, opts *options, sub *substitution, dryRun bool) (err error) {
+	opts.status("Substituting in code blocks of %s\n", filename)
+
+	lock, err := lockDocument(filename)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err = errors.Join(err, lock.unlock())
+	}()
+
+	src, format, err := opts.readDocument(filename)
+	if err != nil {
+		return err
+	}
+
+	modified, res, err := walk(src, func(block *mdcode.Block) error {
+		if block.Virtual || len(block.Meta.Get(mdcode.MetaInclude)) != 0 {
+			return nil
+		}
+
+		code, count := sub.apply(block.Code)
+		if count == 0 {
+			return nil
+		}
+
+		opts.status("%s: %d substitution(s)\n", opts.location(filename, block.StartLine), count)
+		opts.event("block substituted", "document", filename, "line", block.StartLine, "count", count)
+
+		if !dryRun {
+			block.Code = code
+		}
+
+		return nil
+	}, opts.filter)
+	if err != nil {
+		return err
+	}
+
+	if modified {
+		return writeDocument(filename, res, format)
+	}
+
+	return nil
+}
+
+// substitution is a parsed s/regexp/replacement/flags expression.
+type substitution struct {
+	re          *regexp.Regexp
+	replacement []byte
+	global      bool
+}
+
+// parseSubstitution parses a sed style substitution. Any character can be
+// used as the delimiter (escaped with a backslash in the parts), the g flag
+// replaces every match of a line (not only the first), the i flag ignores
+// the case. The & and \1...\9 references of the replacement are converted
+// to regexp template references.
+func parseSubstitution(expr string) (*substitution, error) {
+	if len(expr) < 2 || expr[0] != 's' { //nolint:gomnd
+		return nil, fmt.Errorf("%w: %s", errSubstitution, expr)
+	}
+
+	delim := expr[1]
+	parts := splitEscaped(expr[2:], delim)
+
+	if len(parts) != 3 { //nolint:gomnd
+		return nil, fmt.Errorf("%w: %s", errSubstitution, expr)
+	}
+
+	sub := new(substitution)
+	pattern := parts[0]
+
+	for _, flag := range parts[2] {
+		switch flag {
+		case 'g':
+			sub.global = true
+		case 'i':
+			pattern = "(?i)" + pattern
+		default:
+			return nil, fmt.Errorf("%w: unknown flag %c", errSubstitution, flag)
+		}
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errSubstitution, err)
+	}
+
+	sub.re = re
+	sub.replacement = []byte(sedTemplate(parts[1]))
+
+	return sub, nil
+}
+
+// splitEscaped splits s at the unescaped delim characters. An escaped
+// delimiter is unescaped, other escapes are kept for the regexp.
+func splitEscaped(s string, delim byte) []string {
+	var (
+		parts []string
+		part  strings.Builder
+	)
+
+	for idx := 0; idx < len(s); idx++ {
+		switch {
+		case s[idx] == '\\' && idx+1 < len(s) && s[idx+1] == delim:
+			part.WriteByte(delim)
+			idx++
+		case s[idx] == '\\' && idx+1 < len(s):
+			part.WriteString(s[idx : idx+2])
+			idx++
+		case s[idx] == delim:
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteByte(s[idx])
+		}
+	}
+
+	return append(parts, part.String())
+}
+
+// sedTemplate converts a sed replacement to a regexp template: & is the
+// whole match, \1...\9 are the groups, \& and \\ are literal characters.
+func sedTemplate(replacement string) string {
+	var buff strings.Builder
+
+	for idx := 0; idx < len(replacement); idx++ {
+		char := replacement[idx]
+
+		switch {
+		case char == '\\' && idx+1 < len(replacement):
+			idx++
+
+			next := replacement[idx]
+
+			switch {
+			case next >= '0' && next <= '9':
+				fmt.Fprintf(&buff, "${%c}", next)
+			case next == 'n':
+				buff.WriteByte('\n')
+			case next == 't':
+				buff.WriteByte('\t')
+			default:
+				buff.WriteByte(next)
+			}
+		case char == '&':
+			buff.WriteString("${0}")
+		case char == '$':
+			buff.WriteString("$$")
+		default:
+			buff.WriteByte(char)
+		}
+	}
+
+	return buff.String()
+}
+
+// apply substitutes the matches of each line of the code, and returns the
+// result with the number of substitutions.
+func (s *substitution) apply(code []byte) ([]byte, int) {
+	var (
+		buff  bytes.Buffer
+		count int
+	)
+
+	if len(code) == 0 {
+		return code, 0
+	}
+
+	for _, line := range bytes.SplitAfter(code, []byte{'\n'}) {
+		if len(line) == 0 {
+			break
+		}
+
+		body := bytes.TrimRight(line, "\r\n")
+		eol := line[len(body):]
+
+		matches := s.re.FindAllSubmatchIndex(body, -1)
+		if !s.global && len(matches) > 1 {
+			matches = matches[:1]
+		}
+
+		last := 0
+
+		for _, match := range matches {
+			buff.Write(body[last:match[0]])
+			buff.Write(s.re.Expand(nil, s.replacement, body, match))
+			last = match[1]
+		}
+
+		buff.Write(body[last:])
+		buff.Write(eol)
+
+		count += len(matches)
+	}
+
+	return buff.Bytes(), count
+}
+
+var errSubstitution = errors.New("invalid substitution (use s/regexp/replacement/flags)")
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+)
+
+// pickSummaryLength is the maximum length of the code summary column of the
+// pick format.
+const pickSummaryLength = 60
+
+// selection are the code blocks picked from the pick format listing: the
+// start lines of the code blocks by markdown document, and the documents in
+// the order of appearance.
+type selection struct {
+	documents []string
+	lines     map[string]map[int]struct{}
+}
+
+// parseSelection parses picked lines of the pick format listing (as printed by
+// fzf, one per line). Only the first, document:line field is used, so the rest
+// of the line can be anything.
+func parseSelection(text string) (*selection, error) {
+	sel := &selection{lines: make(map[string]map[int]struct{})} //nolint:exhaustruct
+
+	for _, line := range strings.Split(text, "\n") {
+		field, _, _ := strings.Cut(strings.TrimRight(line, "\r"), "\t")
+		if len(strings.TrimSpace(field)) == 0 {
+			continue
+		}
+
+		idx := strings.LastIndex(field, ":")
+		if idx <= 0 {
+			return nil, fmt.Errorf("%w: %s", errSelection, field)
+		}
+
+		number, err := strconv.Atoi(field[idx+1:])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errSelection, field)
+		}
+
+		document := filepath.Clean(field[:idx])
+
+		if _, has := sel.lines[document]; !has {
+			sel.lines[document] = make(map[int]struct{})
+			sel.documents = append(sel.documents, document)
+		}
+
+		sel.lines[document][number] = struct{}{}
+	}
+
+	if len(sel.documents) == 0 {
+		return nil, errEmptySelection
+	}
+
+	return sel, nil
+}
+
+// loadSelection parses the --from-selection value, read from stdin if it is
+// "-".
+func (o *options) loadSelection(stdin io.Reader) error {
+	if len(o.fromSelection) == 0 {
+		return nil
+	}
+
+	text := o.fromSelection
+
+	if text == "-" {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return err
+		}
+
+		text = string(data)
+	}
+
+	sel, err := parseSelection(text)
+	if err != nil {
+		return err
+	}
+
+	o.selection = sel
+
+	return nil
+}
+
+// selectLines sets the selected code block lines of the markdown document.
+func (o *options) selectLines(filename string) {
+	if o.selection != nil {
+		o.selected = o.selection.lines[filepath.Clean(filename)]
+	}
+}
+
+// selectedOnly returns the filter restricting the code blocks to the ones of
+// the --from-selection lines.
+func selectedOnly(filter filterFunc, opts *options) filterFunc {
+	if len(opts.fromSelection) == 0 {
+		return filter
+	}
+
+	return func(block *mdcode.Block) bool {
+		_, has := opts.selected[block.StartLine]
+
+		return has && filter(block)
+	}
+}
+
+// listPick prints one tab separated line per block for fuzzy finders like
+// fzf: the document:line location, the language, the name (or file) and the
+// first line of the code.
+func listPick(out io.Writer, blocks []*mdcode.Block, documents []string) {
+	for idx, block := range blocks {
+		label := block.Meta.Get(metaName)
+		if len(label) == 0 {
+			label = block.Meta.Get(metaFile)
+		}
+
+		fmt.Fprintf(out, "%s:%d\t%s\t%s\t%s\n", documents[idx], block.StartLine, block.Lang, label, codeSummary(block.Code))
+	}
+}
+
+// codeSummary returns the first non-blank line of the code, shortened.
+func codeSummary(code []byte) string {
+	scanner := bufio.NewScanner(strings.NewReader(string(code)))
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.ReplaceAll(scanner.Text(), "\t", " "))
+		if len(line) == 0 {
+			continue
+		}
+
+		if utf8.RuneCountInString(line) > pickSummaryLength {
+			line = string([]rune(line)[:pickSummaryLength-1]) + "…"
+		}
+
+		return line
+	}
+
+	return ""
+}
+
+var (
+	errSelection      = errors.New("invalid selection line (document:line expected)")
+	errEmptySelection = errors.New("empty selection")
+)
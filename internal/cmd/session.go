@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"mvdan.cc/sh/v3/interp"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// sessionCommand sources the code block into the persistent interpreter, so
+// that variables, functions and the working directory carry over.
+const sessionCommand = ". {}"
+
+// session is a shell interpreter shared by the commands executed for the code
+// blocks of a document in --session mode.
+type session struct {
+	runner *interp.Runner
+	repls  map[string]*repl
+	dir    string
+}
+
+func newSession(dir string, limits limits) (*session, error) {
+	options, err := limits.runnerOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	runner, err := interp.New(append([]interp.RunnerOption{interp.Dir(dir)}, options...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session{runner: runner, repls: make(map[string]*repl), dir: dir}, nil
+}
+
+// run executes command in the session with the given output writers.
+func (s *session) run(command string, stdout, stderr io.Writer) (int, error) {
+	file, err := syntax.NewParser().Parse(strings.NewReader(command), "")
+	if err != nil {
+		return -1, err
+	}
+
+	if err = interp.StdIO(os.Stdin, stdout, stderr)(s.runner); err != nil {
+		return -1, err
+	}
+
+	if err = s.runner.Run(context.TODO(), file); err != nil {
+		if status, ok := interp.IsExitStatus(err); ok {
+			return int(status), nil
+		}
+
+		return -1, err
+	}
+
+	return 0, nil
+}
+
+// eval evaluates the file in the interpreter process of the language, which
+// is started on first use. An interpreter that exited is started again for
+// the next code block.
+func (s *session) eval(lang, path string, stdout, stderr io.Writer) (int, error) {
+	key := replDrivers[lang][0]
+
+	interpreter, ok := s.repls[key]
+	if !ok {
+		var err error
+
+		if interpreter, err = startREPL(lang, s.dir); err != nil {
+			return -1, err
+		}
+
+		s.repls[key] = interpreter
+	}
+
+	exitCode, err := interpreter.eval(path, stdout, stderr)
+
+	if interpreter.exited {
+		delete(s.repls, key)
+	}
+
+	return exitCode, err
+}
+
+// close stops the interpreter processes of the session.
+func (s *session) close() error {
+	var errs []error
+
+	for _, interpreter := range s.repls {
+		errs = append(errs, interpreter.close())
+	}
+
+	return errors.Join(errs...)
+}
+
+// sessionBlocks restricts the filter to the code blocks the default session
+// command can run: shell code blocks and code blocks with an interpreter
+// (and console code blocks with --console).
+func (o *options) sessionBlocks(console bool) {
+	filter := o.filter
+
+	o.filter = func(block *mdcode.Block) bool {
+		lang := o.aliases.canonical(block.Lang)
+
+		return (reShell.MatchString(block.Lang) || hasREPL(lang) || console && isConsole(lang)) && filter(block)
+	}
+}
+
+var errSession = errors.New("--session can't be used with --batch, --cache or --shell")
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+)
+
+const (
+	metaRole     = "role"
+	roleSetup    = "setup"
+	roleTeardown = "teardown"
+)
+
+// excludeRoles drops setup and teardown code blocks from the filter, they are
+// run by runSetup and runTeardown instead of the exec command.
+func (o *options) excludeRoles() {
+	filter := o.filter
+
+	o.filter = func(block *mdcode.Block) bool {
+		return len(block.Meta.Get(metaRole)) == 0 && filter(block)
+	}
+}
+
+// roleScripts returns the commands to run before and after the code blocks
+// of the document: the --setup and --teardown flags, followed by the code of
+// the code blocks with role=setup and role=teardown metadata.
+func (e *execOptions) roleScripts(src []byte, dir string, opts *options) ([]string, []string, error) {
+	var setup, teardown []string
+
+	if len(e.setup) != 0 {
+		setup = append(setup, strings.ReplaceAll(e.setup, "{dir}", e.path(dir)))
+	}
+
+	if len(e.teardown) != 0 {
+		teardown = append(teardown, strings.ReplaceAll(e.teardown, "{dir}", e.path(dir)))
+	}
+
+	_, _, err := mdcode.Walk(src, func(block *mdcode.Block) error {
+		role := block.Meta.Get(metaRole)
+		if len(role) != 0 {
+			if err := opts.resolveRole(block); err != nil {
+				return err
+			}
+		}
+
+		switch role {
+		case roleSetup:
+			setup = append(setup, string(block.Code))
+		case roleTeardown:
+			teardown = append(teardown, string(block.Code))
+		case "":
+		default:
+			return fmt.Errorf("%w: %s (line %d)", errUnknownRole, role, block.StartLine)
+		}
+
+		return nil
+	})
+
+	return setup, teardown, err
+}
+
+// resolveRole resolves the include metadata of the setup or teardown code
+// block, which is not selected (nor resolved) by the filter.
+func (o *options) resolveRole(block *mdcode.Block) error {
+	if err := o.resolveInclude(block); err != nil {
+		return fmt.Errorf("line %d: %w: %w", block.StartLine, errInclude, err)
+	}
+
+	return nil
+}
+
+// runRole runs the setup or teardown commands in dir, stopping at the first
+// failing command.
+func (e *execOptions) runRole(role string, commands []string, filename, dir string, opts *options) error {
+	for _, command := range commands {
+		opts.status("%s\n", opts.color.header(fmt.Sprintf("--- %s : %s ---", role, filepath.Base(filename))))
+		opts.verbose(1, "%s\n", command)
+
+		exitCode, err := e.run(command, dir, opts.status)
+		if err != nil {
+			return err
+		}
+
+		opts.event(role+" executed", "document", filename, "command", command, "exit_code", exitCode)
+		opts.status("%s\n\n", exitStatus(exitCode, opts.color))
+
+		if exitCode != 0 {
+			return fmt.Errorf("%w: %s exited with %d", errRole, role, exitCode)
+		}
+	}
+
+	return nil
+}
+
+var (
+	errUnknownRole = errors.New("unknown role (use setup or teardown)")
+	errRole        = errors.New("command failed")
+)
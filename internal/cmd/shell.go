@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+)
+
+// shellBuiltin is the name of the built-in POSIX shell interpreter.
+const shellBuiltin = "sh"
+
+//nolint:gochecknoglobals
+var shells = map[string][]string{
+	shellBuiltin: nil,
+	"bash":       {"bash", "-c"},
+	"pwsh":       {"pwsh", "-NoProfile", "-NonInteractive", "-Command"},
+	"powershell": {"powershell", "-NoProfile", "-NonInteractive", "-Command"},
+	"cmd":        {"cmd", "/C"},
+}
+
+func checkShell(name string) error {
+	if _, ok := shells[name]; !ok {
+		return fmt.Errorf("%w: %s", errUnknownShell, name)
+	}
+
+	return nil
+}
+
+// shellArgs returns the command line running the command with an external shell.
+func shellArgs(name, command string) []string {
+	return append(append([]string{}, shells[name]...), command)
+}
+
+// nativePaths reports whether the shell expects native (on Windows,
+// backslash separated) paths. POSIX shells treat backslashes as escape
+// characters, so they get slash separated paths.
+func nativePaths(name string) bool {
+	return name == "pwsh" || name == "powershell" || name == "cmd"
+}
+
+// path formats a file path for use in the command of the selected shell.
+func (e *execOptions) path(name string) string {
+	if nativePaths(e.shell) {
+		return filepath.FromSlash(name)
+	}
+
+	return filepath.ToSlash(name)
+}
+
+// runProgram runs an external program and returns its exit code.
+func runProgram(args []string, dir string, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	cmd := exec.Command(args[0], args[1:]...) //nolint:gosec
+	cmd.Dir = dir
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = stdin, stdout, stderr
+
+	err := cmd.Run()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode(), nil
+		}
+
+		return -1, err
+	}
+
+	return 0, nil
+}
+
+var errUnknownShell = errors.New("unknown shell")
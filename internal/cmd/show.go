@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/spf13/cobra"
+)
+
+//go:embed help/show.md
+var showHelp string
+
+const defaultShowContext = 1
+
+var reClosingFence = regexp.MustCompile("^(`{3,}|~{3,})\\s*$")
+
+func showCmd(opts *options) *cobra.Command {
+	var context int
+
+	cmd := &cobra.Command{ //nolint:exhaustruct
+		Use:               "show [flags] index|id [filename]",
+		Short:             "Show a code block with the surrounding text",
+		Long:              showHelp,
+		ValidArgsFunction: completeShowArgs,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errShowBlock
+			}
+
+			return checkargs(cmd, args[1:])
+		},
+		PreRun: func(cmd *cobra.Command, _ []string) {
+			opts.createStatus(cmd.ErrOrStderr())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files, err := sources(args[1:], opts)
+			if err != nil {
+				return err
+			}
+
+			return showRun(files, args[0], cmd.OutOrStdout(), opts, context)
+		},
+
+		DisableAutoGenTag: true,
+	}
+
+	cmd.Flags().IntVarP(&context, "context", "C", defaultShowContext, "number of paragraphs shown before and after the code block")
+
+	return cmd
+}
+
+// showRun prints the code block given by its (1-based) index or identifier,
+// along with its heading chain and context paragraphs before and after it.
+// Numbers shorter than identifiers are indexes. Identifiers are searched in
+// every document, an index requires a single one.
+func showRun(filenames []string, ref string, out io.Writer, opts *options, context int) error {
+	index, err := strconv.Atoi(ref)
+	if err != nil || len(ref) >= idLength || index < 1 {
+		index = 0
+	}
+
+	if index > 0 && len(filenames) != 1 {
+		return fmt.Errorf("%w: a single markdown document is required for a block index", errShowBlock)
+	}
+
+	for _, filename := range filenames {
+		src, _, err := opts.readDocument(filename)
+		if err != nil {
+			return err
+		}
+
+		lines := bytes.Split(src, []byte{'\n'})
+
+		var (
+			found  *mdcode.Block
+			number int
+			blocks []lineRange
+		)
+
+		_, _, err = mdcode.Walk(src, func(block *mdcode.Block) error {
+			number++
+
+			if block.Virtual {
+				return nil
+			}
+
+			blocks = append(blocks, blockLines(block, lines))
+
+			if found == nil && (number == index || (index == 0 && strings.HasPrefix(blockID(block), ref))) {
+				found = block
+				index = number
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if found != nil {
+			showBlock(out, filename, found, index, lines, blocks, context)
+
+			return nil
+		}
+
+		if index > 0 {
+			return fmt.Errorf("%w: %d (the document has %d code blocks)", errMissingBlock, index, number)
+		}
+	}
+
+	return fmt.Errorf("%w: %s", errShowBlock, ref)
+}
+
+// blockLines returns the lines of the code block, from the opening fence to
+// the closing one (if there is any).
+func blockLines(block *mdcode.Block, lines [][]byte) lineRange {
+	end := block.EndLine
+
+	if len(block.Code) != 0 && end < len(lines) && reClosingFence.Match(bytes.TrimLeft(lines[end], " \t>")) {
+		end++
+	}
+
+	return lineRange{from: block.StartLine, to: end}
+}
+
+// paragraphs splits the document lines to blank line separated paragraphs,
+// keeping the code blocks in one piece.
+func paragraphs(lines [][]byte, blocks []lineRange) []lineRange {
+	var (
+		paras []lineRange
+		next  int
+	)
+
+	for line := 1; line <= len(lines); line++ {
+		for next < len(blocks) && blocks[next].to < line {
+			next++
+		}
+
+		if next < len(blocks) && blocks[next].from == line {
+			paras = append(paras, blocks[next])
+			line = blocks[next].to
+
+			continue
+		}
+
+		if len(bytes.TrimSpace(lines[line-1])) == 0 {
+			continue
+		}
+
+		if last := len(paras) - 1; last >= 0 && paras[last].to == line-1 && !isBlock(paras[last], blocks) {
+			paras[last].to = line
+		} else {
+			paras = append(paras, lineRange{from: line, to: line})
+		}
+	}
+
+	return paras
+}
+
+func isBlock(para lineRange, blocks []lineRange) bool {
+	for _, block := range blocks {
+		if block == para {
+			return true
+		}
+	}
+
+	return false
+}
+
+func showBlock(out io.Writer, filename string, block *mdcode.Block, index int, lines [][]byte, blocks []lineRange, context int) {
+	bounds := blockLines(block, lines)
+
+	fmt.Fprintf(out, "%s:%d: block %d (%s), id %s\n", filename, block.StartLine, index, langLabel(block.Lang), blockID(block))
+
+	if len(block.Headings) != 0 {
+		fmt.Fprintf(out, "%s\n", strings.Join(block.Headings, " > "))
+	}
+
+	var before, after []lineRange
+
+	for _, para := range paragraphs(lines, blocks) {
+		switch {
+		case para.to < bounds.from:
+			before = append(before, para)
+		case para.from > bounds.to && len(after) < context:
+			after = append(after, para)
+		}
+	}
+
+	switch {
+	case context <= 0:
+		before = nil
+	case len(before) > context:
+		before = before[len(before)-context:]
+	}
+
+	for _, para := range append(append(before, bounds), after...) {
+		fmt.Fprintln(out)
+
+		for _, line := range lines[para.from-1 : para.to] {
+			fmt.Fprintf(out, "%s\n", bytes.TrimRight(line, "\r"))
+		}
+	}
+}
+
+var errShowBlock = errors.New("code block index or identifier required")
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// lineMapping relates count consecutive lines of a temporary file, starting
+// at line, to the lines of the markdown document starting at documentLine.
+type lineMapping struct {
+	Line         int `json:"line"`
+	DocumentLine int `json:"document_line"`
+	Count        int `json:"count"`
+}
+
+// sourceFile is the source map entry of a temporary file. The file path is
+// slash separated and relative to the temporary directory.
+type sourceFile struct {
+	File     string        `json:"file"`
+	Document string        `json:"document"`
+	Block    int           `json:"block"`
+	Mappings []lineMapping `json:"mappings"`
+}
+
+// sourceMap collects the line mappings of the temporary files written by
+// exec, for wrapper tools translating error positions back to the markdown
+// documents. A nil source map does nothing.
+type sourceMap struct {
+	filename string
+	dir      string
+	files    []*sourceFile
+}
+
+func (m *sourceMap) add(dir string, document string, info *blockInfo) {
+	if m == nil {
+		return
+	}
+
+	if len(m.dir) == 0 {
+		m.dir = dir
+	}
+
+	file, err := filepath.Rel(m.dir, info.tempPath)
+	if err != nil {
+		file = info.tempPath
+	}
+
+	m.files = append(m.files, &sourceFile{
+		File:     filepath.ToSlash(file),
+		Document: filepath.ToSlash(document),
+		Block:    info.index,
+		Mappings: info.mappings,
+	})
+}
+
+func (m *sourceMap) write() error {
+	data, err := json.MarshalIndent(map[string]any{
+		"dir":   m.dir,
+		"files": m.files,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(m.filename, append(data, '\n'), fileMode)
+}
+
+// lineMappings returns the line mappings of a code block written to a
+// temporary file as written. The code of the block starts on the line after
+// the opening fence at fenceLine, the provenance comment added by --stamp
+// has no markdown line.
+func lineMappings(fenceLine int, code, written []byte) []lineMapping {
+	count := bytes.Count(code, []byte("\n"))
+	if len(code) != 0 && code[len(code)-1] != '\n' {
+		count++
+	}
+
+	if len(written) == len(code) || count == 0 {
+		return []lineMapping{{Line: 1, DocumentLine: fenceLine + 1, Count: count}}
+	}
+
+	var mappings []lineMapping
+
+	before := 0
+	if bytes.HasPrefix(code, []byte("#!")) {
+		before = 1
+		mappings = append(mappings, lineMapping{Line: 1, DocumentLine: fenceLine + 1, Count: before})
+	}
+
+	if count > before {
+		mappings = append(mappings, lineMapping{Line: before + 2, DocumentLine: fenceLine + 1 + before, Count: count - before})
+	}
+
+	return mappings
+}
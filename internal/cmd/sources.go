@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ezerfernandes/mdcode/internal/ignore"
+	"github.com/gobwas/glob"
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultInclude = "**/*.md"
+	ignoreFilename = ".mdcodeignore"
+)
+
+// sources returns the markdown documents to process. Without --recursive it
+// is the single filename argument (or the default), otherwise the documents
+// found in the directory argument.
+func sources(args []string, opts *options) ([]string, error) {
+	if opts.selection != nil && len(args) == 0 {
+		return opts.selection.documents, nil
+	}
+
+	if !opts.recursive {
+		return []string{opts.source(args)}, nil
+	}
+
+	root := "."
+	if len(args) != 0 {
+		root = args[0]
+	}
+
+	return discover(root, opts.include, opts.exclude, !opts.noIgnore)
+}
+
+// discover walks the directory tree under root in lexical order and returns
+// the files matching any include pattern and none of the exclude patterns.
+// Patterns are matched against slash separated paths relative to root.
+// If useIgnore is set, paths listed in .mdcodeignore files are skipped too.
+func discover(root string, include, exclude []string, useIgnore bool) ([]string, error) {
+	inc, err := pathGlob(include)
+	if err != nil {
+		return nil, err
+	}
+
+	exc, err := pathGlob(exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		files   []string
+		ignored ignore.List
+	)
+
+	err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		rel = filepath.ToSlash(rel)
+
+		if entry.IsDir() {
+			if rel != "." && (entry.Name() == ".git" || matches(exc, rel) || matches(exc, rel+"/") ||
+				ignored.Ignored(rel, true)) {
+				return filepath.SkipDir
+			}
+
+			if useIgnore {
+				return loadIgnore(&ignored, path, rel)
+			}
+
+			return nil
+		}
+
+		if matches(inc, rel) && !matches(exc, rel) && !ignored.Ignored(rel, false) {
+			files = append(files, path)
+		}
+
+		return nil
+	})
+
+	return files, err
+}
+
+func loadIgnore(list *ignore.List, dir, rel string) error {
+	data, err := os.ReadFile(filepath.Join(dir, ignoreFilename))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
+
+	return list.Add(rel, data)
+}
+
+// pathGlob compiles the path patterns. A leading `**/` also matches files
+// directly in the root directory. The patterns are compiled one by one,
+// because alternatives starting with `**` don't always match in a `{}` list.
+func pathGlob(patterns []string) ([]glob.Glob, error) {
+	globs := make([]glob.Glob, 0, len(patterns))
+
+	for _, pattern := range patterns {
+		expanded := []string{pattern}
+
+		if rest, ok := strings.CutPrefix(pattern, "**/"); ok {
+			expanded = append(expanded, rest)
+		}
+
+		for _, src := range expanded {
+			g, err := glob.Compile(src, '/')
+			if err != nil {
+				return nil, err
+			}
+
+			globs = append(globs, g)
+		}
+	}
+
+	return globs, nil
+}
+
+func matches(globs []glob.Glob, path string) bool {
+	for _, g := range globs {
+		if g.Match(path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// documentDir sets the base directory to the directory of the markdown
+// document, unless it was given with the --dir flag.
+func documentDir(cmd *cobra.Command, opts *options, filename string) {
+	if flag := cmd.Flag("dir"); flag != nil && !flag.Changed {
+		opts.dir = filepath.Dir(filename)
+
+		if isURL(filename) {
+			opts.dir = "."
+		}
+	}
+}
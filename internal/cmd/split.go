@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/spf13/cobra"
+)
+
+//go:embed help/split.md
+var splitHelp string
+
+const manifestFilename = "mdcode-manifest.json"
+
+func splitCmd(opts *options) *cobra.Command {
+	var manifest string
+
+	cmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "split [flags] [filename]",
+		Short: "Write every markdown code block to its own file",
+		Long:  splitHelp,
+		Args:  checkargs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			opts.createStatus(cmd.ErrOrStderr())
+
+			return opts.allBlocksFilter(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files, err := sources(args, opts)
+			if err != nil {
+				return err
+			}
+
+			var entries []manifestEntry
+
+			err = opts.eachSource(files, func(file string) error {
+				documentDir(cmd, opts, file)
+
+				split, err := splitRun(file, opts)
+				entries = append(entries, split...)
+
+				return err
+			})
+			if err != nil {
+				return err
+			}
+
+			if len(manifest) == 0 {
+				manifest = rel(opts.dir, manifestFilename)
+			}
+
+			return writeManifest(manifest, entries)
+		},
+
+		DisableAutoGenTag: true,
+	}
+
+	dirFlag(cmd, opts)
+	quietFlag(cmd, opts)
+
+	cmd.Flags().StringVar(&manifest, "manifest", "", "manifest file name (default: "+manifestFilename+" in the base directory)")
+
+	cobra.CheckErr(cmd.MarkFlagFilename("manifest", "json"))
+
+	return cmd
+}
+
+// manifestEntry maps a file written by split back to its code block.
+type manifestEntry struct {
+	File      string      `json:"file"`
+	Document  string      `json:"document"`
+	Index     int         `json:"index"`
+	StartLine int         `json:"start_line"`
+	EndLine   int         `json:"end_line"`
+	Lang      string      `json:"lang,omitempty"`
+	Meta      mdcode.Meta `json:"meta,omitempty"`
+}
+
+func splitRun(filename string, opts *options) ([]manifestEntry, error) {
+	opts.status("Splitting code blocks from %s\n", filename)
+
+	src, _, err := opts.readDocument(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []manifestEntry
+
+	eol := opts.lineEnding(src)
+	stem := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	index := 0
+
+	_, _, err = mdcode.Walk(src, func(block *mdcode.Block) error {
+		index++
+
+		if !opts.filter(block) {
+			return nil
+		}
+
+		if err := opts.confine(block); err != nil {
+			return err
+		}
+
+		name := block.Meta.Get(metaFile)
+		if len(name) == 0 || len(block.Meta.Get(metaRegion)) != 0 {
+			name = fmt.Sprintf("%s-%d%s", stem, index, langExtension(opts.aliases.canonical(block.Lang)))
+		}
+
+		name = rel(opts.dir, filepath.FromSlash(name))
+
+		opts.status("%s\n", name)
+
+		if err := os.MkdirAll(filepath.Dir(name), dirMode); err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(name, convertEOL(block.Code, eol), fileMode); err != nil {
+			return err
+		}
+
+		opts.event("block split", "document", filename, "line", block.StartLine, "file", name)
+
+		entries = append(entries, manifestEntry{
+			File:      filepath.ToSlash(name),
+			Document:  filepath.ToSlash(filename),
+			Index:     index,
+			StartLine: block.StartLine,
+			EndLine:   block.EndLine,
+			Lang:      block.Lang,
+			Meta:      block.Meta,
+		})
+
+		return nil
+	})
+
+	return entries, err
+}
+
+func writeManifest(filename string, entries []manifestEntry) error {
+	if entries == nil {
+		entries = []manifestEntry{}
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), dirMode); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filename, append(data, '\n'), fileMode)
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/spf13/cobra"
+)
+
+func stampFlag(cmd *cobra.Command, opts *options) {
+	cmd.Flags().BoolVar(&opts.stamp, "stamp", false, "prepend a generated code comment naming the source document")
+}
+
+// stampLine returns the provenance comment of the code block, or nil if the
+// comment syntax of its language is unknown. The language is taken from the
+// code block, or inferred from its file metadata.
+func (o *options) stampLine(filename string, block *mdcode.Block, eol []byte) []byte {
+	lang := block.Lang
+	if len(lang) == 0 {
+		lang = langFromFilename(block.Meta.Get(metaFile))
+	}
+
+	text := fmt.Sprintf("Code generated from %s:%d by %s; DO NOT EDIT.", filepath.ToSlash(filename), block.StartLine, appname)
+
+	line, ok := comment(o.aliases.canonical(lang), text)
+	if !ok {
+		return nil
+	}
+
+	return append([]byte(line), eol...)
+}
+
+// stampCode prepends the provenance comment to code if --stamp is set,
+// keeping a leading shebang line in place.
+func (o *options) stampCode(filename string, block *mdcode.Block, code []byte) []byte {
+	if !o.stamp {
+		return code
+	}
+
+	line := o.stampLine(filename, block, detectEOL(code))
+	if line == nil {
+		return code
+	}
+
+	pos := 0
+	if bytes.HasPrefix(code, []byte("#!")) {
+		if pos = bytes.IndexByte(code, '\n') + 1; pos == 0 {
+			pos = len(code)
+		}
+	}
+
+	stamped := make([]byte, 0, len(code)+len(line))
+	stamped = append(stamped, code[:pos]...)
+	stamped = append(stamped, line...)
+
+	return append(stamped, code[pos:]...)
+}
+
+// unstampCode removes the provenance comment added by stampCode, so that it
+// does not end up in the markdown document.
+func (o *options) unstampCode(filename string, block *mdcode.Block, code []byte) []byte {
+	if !o.stamp {
+		return code
+	}
+
+	line := o.stampLine(filename, block, detectEOL(code))
+	if line == nil {
+		return code
+	}
+
+	return bytes.Replace(code, line, nil, 1)
+}
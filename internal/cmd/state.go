@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ezerfernandes/mdcode/internal/diff3"
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+)
+
+const stateDir = ".mdcode"
+
+//nolint:gochecknoglobals
+var stateFilename = filepath.Join(stateDir, "state")
+
+// syncState holds the baseline of the code blocks synchronized with their
+// files: the code of the last sync, by document, file and region. When both
+// the code block and the file changed since, the changes are merged.
+type syncState struct {
+	Blocks map[string]string `json:"blocks"`
+	dirty  bool
+}
+
+func loadSyncState(filename string) (*syncState, error) {
+	state := &syncState{Blocks: make(map[string]string), dirty: false}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return state, nil
+		}
+
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", errState, filename, err)
+	}
+
+	if state.Blocks == nil {
+		state.Blocks = make(map[string]string)
+	}
+
+	return state, nil
+}
+
+func (s *syncState) save(filename string) error {
+	if s == nil || !s.dirty {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), dirMode); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filename, append(data, '\n'), fileMode)
+}
+
+// stateKey identifies the code block of the document synchronized with the
+// file (as found on disk) and region. The paths are absolute, so the same
+// document named differently (like README.md and ./README.md) shares its
+// baseline.
+func stateKey(document, file string, block *mdcode.Block) string {
+	key := filepath.ToSlash(statePath(document)) + " " + filepath.ToSlash(statePath(file))
+
+	if region := block.Meta.Get(metaRegion); len(region) != 0 {
+		key += "#" + region
+	}
+
+	return key
+}
+
+// statePath returns the absolute and clean path of the state key.
+func statePath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+
+	return abs
+}
+
+// reconcile returns the code synchronizing the target side (the content to
+// be overwritten, nil if missing) with the source side. If only the source
+// changed since the baseline the source wins, if only the target changed the
+// target is kept. Otherwise the changes of both sides are merged, the result
+// having conflict markers (with the labels of the sides) where they overlap.
+// Without conflict the merged code becomes the baseline, and the returned
+// flag reports that it must be written to the source side too, so both sides
+// match the baseline.
+func (o *options) reconcile(document string, block *mdcode.Block, file string, target, source []byte,
+	targetLabel, sourceLabel string,
+) ([]byte, bool) {
+	if o.state == nil {
+		return source, false
+	}
+
+	key := stateKey(document, file, block)
+
+	base, has := o.state.Blocks[key]
+
+	switch {
+	case !has || target == nil || base == string(target):
+		o.state.record(key, source)
+
+		return source, false
+	case base == string(source):
+		o.state.record(key, target)
+
+		return target, false
+	}
+
+	merged, conflict := diff3.Merge([]byte(base), target, source, targetLabel, sourceLabel)
+	if conflict {
+		o.conflicts++
+
+		fmt.Fprintf(o.stderr, "%s: merge conflict with %s, resolve the conflict markers\n",
+			o.location(document, block.StartLine), file)
+
+		return merged, false
+	}
+
+	o.state.record(key, merged)
+
+	return merged, !bytes.Equal(merged, source)
+}
+
+func (s *syncState) record(key string, code []byte) {
+	if s.Blocks[key] != string(code) {
+		s.Blocks[key] = string(code)
+		s.dirty = true
+	}
+}
+
+// withState loads the sync state for the duration of fn, saving it after,
+// and reports the merge conflicts left.
+func (o *options) withState(fn func() error) error {
+	state, err := loadSyncState(stateFilename)
+	if err != nil {
+		return err
+	}
+
+	o.state, o.conflicts = state, 0
+
+	err = errors.Join(fn(), state.save(stateFilename))
+	if err == nil && o.conflicts != 0 {
+		err = fmt.Errorf("%w: %d code block(s)", errConflict, o.conflicts)
+	}
+
+	return err
+}
+
+var (
+	errState    = errors.New("invalid sync state")
+	errConflict = errors.New("merge conflicts")
+)
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_reconcile(t *testing.T) {
+	t.Parallel()
+
+	opts := &options{state: &syncState{Blocks: make(map[string]string)}, stderr: io.Discard} //nolint:exhaustruct
+	block := &mdcode.Block{StartLine: 1}                                                     //nolint:exhaustruct
+
+	reconcile := func(target, source string) (string, bool) {
+		code, merged := opts.reconcile("doc.md", block, "x.txt", []byte(target), []byte(source), "doc.md", "x.txt")
+
+		return string(code), merged
+	}
+
+	code, merged := reconcile("a\nb\nc\nd\ne\n", "a\nb\nc\nd\ne\n")
+	require.Equal(t, "a\nb\nc\nd\ne\n", code)
+	require.False(t, merged)
+
+	// Only the target changed: it is kept.
+	code, merged = reconcile("A\nb\nc\nd\ne\n", "a\nb\nc\nd\ne\n")
+	require.Equal(t, "A\nb\nc\nd\ne\n", code)
+	require.False(t, merged)
+
+	// Only the source changed: it wins.
+	code, merged = reconcile("A\nb\nc\nd\ne\n", "A\nb\nc\nd\nE\n")
+	require.Equal(t, "A\nb\nc\nd\nE\n", code)
+	require.False(t, merged)
+
+	// Both sides changed: the merged code is the new baseline of both sides.
+	code, merged = reconcile("A\nB\nc\nd\nE\n", "A\nb\nc\nD\nE\n")
+	require.Equal(t, "A\nB\nc\nD\nE\n", code)
+	require.True(t, merged)
+	require.Equal(t, code, opts.state.Blocks[stateKey("doc.md", "x.txt", block)])
+
+	code, merged = reconcile("A\nB\nc\nD\nE\n", "A\nB\nc\nD\nE\n")
+	require.Equal(t, "A\nB\nc\nD\nE\n", code)
+	require.False(t, merged)
+	require.Zero(t, opts.conflicts)
+}
+
+func Test_stateKey(t *testing.T) {
+	t.Parallel()
+
+	block := &mdcode.Block{StartLine: 1} //nolint:exhaustruct
+
+	abs, err := filepath.Abs("README.md")
+	require.NoError(t, err)
+
+	key := stateKey("README.md", "x.txt", block)
+
+	require.Equal(t, key, stateKey("./README.md", "dir/../x.txt", block))
+	require.Equal(t, key, stateKey(abs, filepath.Join(filepath.Dir(abs), "x.txt"), block))
+	require.NotEqual(t, key, stateKey("docs/README.md", "x.txt", block))
+}
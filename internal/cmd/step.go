@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+type stepAction int
+
+const (
+	stepRun stepAction = iota
+	stepSkip
+	stepAbort
+)
+
+// stepper asks the user what to do with each code block in --step mode.
+type stepper struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func newStepper(in io.Reader, out io.Writer) *stepper {
+	return &stepper{in: bufio.NewReader(in), out: out}
+}
+
+func (s *stepper) ask(info *blockInfo, expanded string) (stepAction, error) {
+	for {
+		code, err := os.ReadFile(info.tempPath)
+		if err != nil {
+			return stepAbort, err
+		}
+
+		fmt.Fprintf(s.out, "%s\n$ %s\n", strings.TrimRight(string(code), "\n"), expanded)
+		fmt.Fprint(s.out, "[r]un, [s]kip, [e]dit, [a]bort? ")
+
+		line, err := s.in.ReadString('\n')
+		if err != nil && (!errors.Is(err, io.EOF) || len(line) == 0) {
+			if errors.Is(err, io.EOF) {
+				return stepAbort, nil
+			}
+
+			return stepAbort, err
+		}
+
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "", "r", "run":
+			return stepRun, nil
+		case "s", "skip":
+			return stepSkip, nil
+		case "a", "abort", "q", "quit":
+			return stepAbort, nil
+		case "e", "edit":
+			if err = s.edit(info.tempPath); err != nil {
+				return stepAbort, err
+			}
+		}
+	}
+}
+
+func (s *stepper) edit(filename string) error {
+	editor := os.Getenv("VISUAL")
+	if len(editor) == 0 {
+		editor = os.Getenv("EDITOR")
+	}
+
+	if len(editor) == 0 {
+		editor = "vi"
+	}
+
+	args := append(strings.Fields(editor), filename)
+
+	_, err := runProgram(args, ".", os.Stdin, os.Stdout, os.Stderr)
+
+	return err
+}
+
+var errAborted = errors.New("aborted")
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/spf13/cobra"
+)
+
+//go:embed help/normalize.md
+var normalizeHelp string
+
+const (
+	fenceBacktick = "backtick"
+	fenceTilde    = "tilde"
+)
+
+func normalizeCmd(opts *options) *cobra.Command {
+	var (
+		check bool
+		fence string
+	)
+
+	cmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "normalize [flags] [filename]",
+		Short: "Rewrite markdown code fences to a canonical style",
+		Long:  normalizeHelp,
+		Args:  checkargs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			opts.createStatus(cmd.ErrOrStderr())
+
+			if fence != fenceBacktick && fence != fenceTilde {
+				return fmt.Errorf("%w: %s", errFenceStyle, fence)
+			}
+
+			return opts.allBlocksFilter(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files, err := sources(args, opts)
+			if err != nil {
+				return err
+			}
+
+			char := byte('`')
+			if fence == fenceTilde {
+				char = '~'
+			}
+
+			var count int
+
+			err = opts.eachSource(files, func(file string) error {
+				n, err := normalizeRun(file, cmd.OutOrStdout(), opts, char, check)
+				count += n
+
+				return err
+			})
+			if err != nil {
+				return err
+			}
+
+			if check && count != 0 {
+				return fmt.Errorf("%w: %d code block(s), run mdcode normalize", errNotNormalized, count)
+			}
+
+			return nil
+		},
+
+		DisableAutoGenTag: true,
+	}
+
+	quietFlag(cmd, opts)
+
+	cmd.Flags().BoolVar(&check, "check", false, "report the code blocks not in canonical style instead of rewriting them")
+	cmd.Flags().StringVar(&fence, "fence", fenceBacktick, "fence character (backtick or tilde)")
+
+	return cmd
+}
+
+// normalizeRun rewrites the fences of the code blocks of the markdown
+// document in canonical style and returns the number of code blocks changed.
+// In check mode the code blocks are only reported.
+func normalizeRun(filename string, out io.Writer, opts *options, char byte, check bool) (count int, err error) {
+	opts.status("Normalizing code blocks in %s\n", filename)
+
+	if !check {
+		lock, lerr := lockDocument(filename)
+		if lerr != nil {
+			return 0, lerr
+		}
+
+		defer func() {
+			err = errors.Join(err, lock.unlock())
+		}()
+	}
+
+	src, format, err := opts.readDocument(filename)
+	if err != nil {
+		return 0, err
+	}
+
+	modified, res, err := walk(src, func(block *mdcode.Block) error {
+		if isCanonical(block, char, opts.aliases) {
+			return nil
+		}
+
+		count++
+
+		if check {
+			fmt.Fprintf(out, "%s: code block not in canonical style\n", opts.location(filename, block.StartLine))
+
+			return nil
+		}
+
+		block.Fence, block.Info = canonicalFence(block, char), canonicalInfo(block.Info, opts.aliases)
+
+		return nil
+	}, opts.filter)
+	if err != nil {
+		return count, err
+	}
+
+	if modified && !check {
+		return count, writeDocument(filename, res, format)
+	}
+
+	return count, nil
+}
+
+// isCanonical reports whether the fence and the info string of the code block
+// are in canonical style.
+func isCanonical(block *mdcode.Block, char byte, aliases langAliases) bool {
+	return canonicalFence(block, char) == block.Fence && canonicalInfo(block.Info, aliases) == block.Info
+}
+
+// canonicalFence returns the shortest fence of char that the code can't
+// close. Backticks can't be used if the info string contains a backtick.
+func canonicalFence(block *mdcode.Block, char byte) string {
+	if char == '`' && strings.Contains(block.Info, "`") {
+		char = '~'
+	}
+
+	return fenceOf(char, block.Code)
+}
+
+// canonicalInfo returns the info string with the canonical language name and
+// the metadata in name="value" format, in the order of the list command,
+// separated by single spaces. Info strings with structured JSON metadata
+// values or not parsable are kept.
+func canonicalInfo(info string, aliases langAliases) string {
+	lang, meta, err := mdcode.ParseInfo(info)
+	if err != nil {
+		return info
+	}
+
+	for _, value := range meta {
+		switch value.(type) {
+		case string, float64, bool:
+		default:
+			return info
+		}
+	}
+
+	if len(lang) != 0 {
+		lang = aliases.canonical(lang)
+	}
+
+	return formatInfo(lang, meta)
+}
+
+var (
+	errNotNormalized = errors.New("code blocks not in canonical style")
+	errFenceStyle    = errors.New("invalid fence style (use backtick or tilde)")
+)
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rodaine/table"
+)
+
+// failure is a failed code block (or batch) listed in the summary printed at
+// the end of exec.
+type failure struct {
+	document string
+	block    string
+	lines    string
+	lang     string
+	exitCode int
+}
+
+func (e *execOptions) blockFailed(filename string, info *blockInfo, exitCode int) {
+	e.failures = append(e.failures, failure{
+		document: filename,
+		block:    fmt.Sprint(info.index),
+		lines:    fmt.Sprintf("%d-%d", info.startLine, info.endLine),
+		lang:     info.lang,
+		exitCode: exitCode,
+	})
+}
+
+func (e *execOptions) batchFailed(filename string, entries []*blockInfo, exitCode int) {
+	e.failures = append(e.failures, failure{
+		document: filename,
+		block:    "batch",
+		lines:    fmt.Sprintf("%d-%d", entries[0].startLine, entries[len(entries)-1].endLine),
+		lang:     "",
+		exitCode: exitCode,
+	})
+}
+
+// printFailures prints the table of the failed code blocks, so that they can
+// be found without scrolling through the output.
+func printFailures(out io.Writer, failures []failure, color palette) {
+	if len(failures) == 0 {
+		return
+	}
+
+	fmt.Fprintf(out, "%s\n", color.fail(fmt.Sprintf("%d failed block(s):", len(failures))))
+
+	tbl := table.New("document", "block", "lines", "lang", "exit code").WithWriter(out)
+
+	tbl.WithHeaderFormatter(func(format string, vals ...interface{}) string {
+		return strings.ToUpper(fmt.Sprintf(format, vals...))
+	})
+
+	for _, f := range failures {
+		tbl.AddRow(f.document, f.block, f.lines, f.lang, f.exitCode)
+	}
+
+	tbl.Print()
+}
+
+// printSilentSummary prints the block counts of a --silent run, and where the
+// output of the blocks was written.
+func printSilentSummary(out io.Writer, rep *report, dir string) {
+	fmt.Fprintf(out, "%d passed, %d failed, %d skipped, output written to %s\n",
+		rep.count(reportPassed), rep.count(reportFailed), rep.count(reportSkipped)+rep.count(reportCached), dir)
+}
+
+const (
+	profileDuration = "duration"
+	profileOrder    = "order"
+)
+
+// timing is the wall-clock duration of the command of a code block (or
+// batch), listed in the --profile summary.
+type timing struct {
+	document string
+	block    string
+	lines    string
+	lang     string
+	duration time.Duration
+}
+
+func (e *execOptions) blockTimed(filename string, info *blockInfo, duration time.Duration) {
+	if len(e.profile) == 0 {
+		return
+	}
+
+	e.timings = append(e.timings, timing{
+		document: filename,
+		block:    fmt.Sprint(info.index),
+		lines:    fmt.Sprintf("%d-%d", info.startLine, info.endLine),
+		lang:     info.lang,
+		duration: duration,
+	})
+}
+
+func (e *execOptions) batchTimed(filename string, entries []*blockInfo, duration time.Duration) {
+	if len(e.profile) == 0 {
+		return
+	}
+
+	e.timings = append(e.timings, timing{
+		document: filename,
+		block:    "batch",
+		lines:    fmt.Sprintf("%d-%d", entries[0].startLine, entries[len(entries)-1].endLine),
+		lang:     "",
+		duration: duration,
+	})
+}
+
+// printProfile prints the table of the code block durations with their share
+// of the total, the slowest first (or in execution order), so the examples
+// dominating the execution time can be found.
+func printProfile(out io.Writer, timings []timing, order string) {
+	if len(timings) == 0 {
+		return
+	}
+
+	var total time.Duration
+
+	for _, t := range timings {
+		total += t.duration
+	}
+
+	if order == profileDuration {
+		sort.SliceStable(timings, func(i, j int) bool { return timings[i].duration > timings[j].duration })
+	}
+
+	tbl := table.New("document", "block", "lines", "lang", "duration", "share").WithWriter(out)
+
+	tbl.WithHeaderFormatter(func(format string, vals ...interface{}) string {
+		return strings.ToUpper(fmt.Sprintf(format, vals...))
+	})
+
+	for _, t := range timings {
+		share := 100.0
+		if total != 0 {
+			share = 100 * float64(t.duration) / float64(total) //nolint:gomnd
+		}
+
+		tbl.AddRow(t.document, t.block, t.lines, t.lang, t.duration.Round(time.Millisecond), fmt.Sprintf("%.1f%%", share))
+	}
+
+	tbl.Print()
+
+	fmt.Fprintf(out, "total: %d command(s) in %s\n", len(timings), total.Round(time.Millisecond))
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+)
+
+const metaTags = "tags"
+
+// blockTags returns the tags of the code block, given as a comma (or space)
+// separated string or as a JSON array in the tags metadata.
+func blockTags(block *mdcode.Block) map[string]struct{} {
+	tags := make(map[string]struct{})
+
+	var values []string
+
+	switch value := block.Meta[metaTags].(type) {
+	case nil:
+	case []any:
+		for _, v := range value {
+			values = append(values, fmt.Sprint(v))
+		}
+	default:
+		values = strings.FieldsFunc(fmt.Sprint(value), func(r rune) bool { return r == ',' || r == ' ' })
+	}
+
+	for _, v := range values {
+		if v = strings.TrimSpace(v); len(v) != 0 {
+			tags[v] = struct{}{}
+		}
+	}
+
+	return tags
+}
+
+// hasAnyTag reports whether one of the comma separated tags is in tags.
+func hasAnyTag(tags map[string]struct{}, list string) bool {
+	for _, tag := range strings.Split(list, ",") {
+		if _, has := tags[strings.TrimSpace(tag)]; has {
+			return true
+		}
+	}
+
+	return false
+}
+
+// tagged returns the filter selecting the code blocks by their tags. Each
+// --tags value is a comma separated list of tags, one of them must be present
+// (OR), and every --tags value must be satisfied (AND). Code blocks having
+// any of the --skip-tags tags are dropped.
+func tagged(filter filterFunc, include []string, skip []string) filterFunc {
+	if len(include) == 0 && len(skip) == 0 {
+		return filter
+	}
+
+	return func(block *mdcode.Block) bool {
+		tags := blockTags(block)
+
+		for _, list := range include {
+			if !hasAnyTag(tags, list) {
+				return false
+			}
+		}
+
+		for _, list := range skip {
+			if hasAnyTag(tags, list) {
+				return false
+			}
+		}
+
+		return filter(block)
+	}
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// tailOutput holds back the output of the commands for --tail: the output of
+// a failed command is shown limited to its last lines, the full output being
+// written to a log file. The output of the successful commands is dropped.
+type tailOutput struct {
+	lines   int
+	out     io.Writer
+	output  syncBuffer
+	logName string
+	name    string
+}
+
+//nolint:gochecknoglobals
+var tailLogDir = filepath.Join(stateDir, "logs")
+
+func newTailOutput(lines int, out io.Writer) *tailOutput {
+	if lines <= 0 {
+		return nil
+	}
+
+	return &tailOutput{lines: lines, out: out} //nolint:exhaustruct
+}
+
+// start prepares the capture of the next command. The logName is the name of
+// its --output-dir log files (if any), name the name of the log file written
+// otherwise.
+func (t *tailOutput) start(logName, filename string, opts *options, name string) {
+	if t == nil {
+		return
+	}
+
+	t.logName = logName
+	t.name = filepath.Join(perDocument(tailLogDir, filename, opts), name+".log")
+}
+
+// writers returns the writers capturing the output of the command instead of
+// the standard output and error.
+func (t *tailOutput) writers(stdout, stderr io.Writer) (io.Writer, io.Writer) {
+	if t == nil {
+		return stdout, stderr
+	}
+
+	t.output.Reset()
+
+	return &t.output, &t.output
+}
+
+// finish shows the last lines of the output of a failed command.
+func (t *tailOutput) finish(exitCode int) error {
+	if t == nil || exitCode == 0 {
+		return nil
+	}
+
+	output := []byte(t.output.String())
+
+	lines := bytes.SplitAfter(output, []byte("\n"))
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+
+	if len(lines) <= t.lines {
+		_, err := t.out.Write(output)
+
+		return err
+	}
+
+	full := t.logName + ".out, " + t.logName + ".err"
+
+	if len(t.logName) == 0 {
+		if err := os.MkdirAll(filepath.Dir(t.name), dirMode); err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(t.name, output, fileMode); err != nil {
+			return err
+		}
+
+		full = t.name
+	}
+
+	fmt.Fprintf(t.out, "... %d line(s) omitted, full output in %s\n", len(lines)-t.lines, full)
+
+	_, err := t.out.Write(bytes.Join(lines[len(lines)-t.lines:], nil))
+
+	return err
+}
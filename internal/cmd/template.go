@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"text/template"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/ezerfernandes/mdcode/internal/textenc"
+	"github.com/spf13/cobra"
+)
+
+//go:embed help/template.md
+var templateHelp string
+
+// metaTemplate opts a code block in to template rendering, other code blocks
+// (like Go or Helm templates written for the reader) are copied unchanged.
+const metaTemplate = "template"
+
+type templateOptions struct {
+	data  []string
+	set   map[string]string
+	prose bool
+}
+
+func templateCmd(opts *options) *cobra.Command {
+	topts := new(templateOptions)
+
+	cmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "template [flags] [filename]",
+		Short: "Render template expressions of the code blocks into a generated document",
+		Long:  templateHelp,
+		Args:  checkargs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			opts.createStatus(cmd.ErrOrStderr())
+
+			return opts.allBlocksFilter(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vars, err := topts.vars()
+			if err != nil {
+				return err
+			}
+
+			out, err := openOutput(opts.out, cmd)
+			if err != nil {
+				return err
+			}
+
+			if err = templateRun(opts.source(args), out, opts, vars, topts.prose); err != nil {
+				return err
+			}
+
+			return closeOutput(out)
+		},
+
+		DisableAutoGenTag: true,
+	}
+
+	cmd.Flags().StringArrayVar(&topts.data, "data", nil, "YAML or JSON file of template variables (repeatable, later files override earlier ones)")
+	cmd.Flags().StringToStringVar(&topts.set, "set", nil, "template variable (overrides the --data files)")
+	cmd.Flags().BoolVar(&topts.prose, "prose", false, "render the template expressions of the text outside of the code blocks too")
+
+	outputFlag(cmd, opts)
+
+	cobra.CheckErr(cmd.MarkFlagFilename("data", "yaml", "yml", "json"))
+
+	return cmd
+}
+
+// vars returns the template variables of the --data files and the --set
+// flags.
+func (t *templateOptions) vars() (map[string]any, error) {
+	vars := make(map[string]any)
+
+	for _, filename := range t.data {
+		data, err := loadTemplateData(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		for key, value := range data {
+			vars[key] = value
+		}
+	}
+
+	for key, value := range t.set {
+		vars[key] = value
+	}
+
+	return vars, nil
+}
+
+// templateRun renders the code of the filtered code blocks having
+// template=true metadata as Go templates with the variables, and writes the
+// generated document to out. With prose the whole document is rendered: the
+// codes are replaced by placeholders while rendering, so only the code of the
+// rendered code blocks is rendered, but template actions may enclose code
+// blocks.
+func templateRun(filename string, out io.Writer, opts *options, vars map[string]any, prose bool) error {
+	src, format, err := opts.readDocument(filename)
+	if err != nil {
+		return err
+	}
+
+	codes := make(map[string][]byte)
+
+	modified, res, err := mdcode.Walk(src, func(block *mdcode.Block) error {
+		if block.Virtual || len(block.Meta.Get(mdcode.MetaInclude)) != 0 {
+			return nil
+		}
+
+		code := block.Code
+
+		if block.Meta.Get(metaTemplate) == "true" && opts.filter(block) {
+			rendered, err := renderTemplate(opts.location(filename, block.StartLine), block.Code, vars)
+			if err != nil {
+				return err
+			}
+
+			code = rendered
+		}
+
+		if prose {
+			placeholder := fmt.Sprintf(templatePlaceholder, len(codes))
+			codes[placeholder] = code
+			code = []byte(placeholder)
+		}
+
+		block.Code = code
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !modified {
+		res = src
+	}
+
+	if prose {
+		if res, err = renderProse(filename, res, codes, vars); err != nil {
+			return err
+		}
+	}
+
+	data, err := textenc.Encode(res, format)
+	if err != nil {
+		return err
+	}
+
+	_, err = out.Write(data)
+
+	return err
+}
+
+// templatePlaceholder stands for the rendered code of a code block while
+// rendering the text of the document.
+const templatePlaceholder = "\x00mdcode-template-%d\x00\n"
+
+// renderProse renders the text of the document, and replaces the code block
+// placeholders with the codes.
+func renderProse(filename string, src []byte, codes map[string][]byte, vars map[string]any) ([]byte, error) {
+	text, err := renderTemplate(filename, src, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	modified, res, err := mdcode.Walk(text, func(block *mdcode.Block) error {
+		if code, has := codes[string(block.Code)]; has {
+			block.Code = code
+		}
+
+		return nil
+	})
+	if err != nil || !modified {
+		return text, err
+	}
+
+	return res, nil
+}
+
+// renderTemplate executes the text as a Go template with the variables.
+// Missing variables are errors, so typos don't generate empty values.
+func renderTemplate(name string, text []byte, vars map[string]any) ([]byte, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errTemplate, err)
+	}
+
+	var buff bytes.Buffer
+
+	if err = tmpl.Execute(&buff, vars); err != nil {
+		return nil, fmt.Errorf("%w: %w", errTemplate, err)
+	}
+
+	return buff.Bytes(), nil
+}
+
+var errTemplate = errors.New("template error")
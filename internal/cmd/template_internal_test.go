@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_templateRun_opt_in(t *testing.T) {
+	dir := t.TempDir()
+
+	src := "```sh template=true\necho v{{ .Version }}\n```\n\n```go\nfmt.Println(\"{{ .Name }}\")\n```\n"
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "doc.md"), []byte(src), 0o600))
+
+	out, err := runMdcode(t, dir, "template", "--set", "Version=1.2.3", "doc.md")
+	require.NoError(t, err, out)
+	require.Equal(t, "```sh template=true\necho v1.2.3\n```\n\n```go\nfmt.Println(\"{{ .Name }}\")\n```\n", out)
+
+	src = "<!-- mdcode: defaults template=true -->\n\n```sh\necho v{{ .Version }}\n```\n\n```go template=false\n{{ .Name }}\n```\n"
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "doc.md"), []byte(src), 0o600))
+
+	out, err = runMdcode(t, dir, "template", "--set", "Version=1.2.3", "doc.md")
+	require.NoError(t, err, out)
+	require.Contains(t, out, "echo v1.2.3\n")
+	require.Contains(t, out, "{{ .Name }}\n")
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loadTemplateData reads the variables of the template command from a JSON
+// (.json extension) or YAML document.
+func loadTemplateData(filename string) (map[string]any, error) {
+	data, err := os.ReadFile(filepath.Clean(filename))
+	if err != nil {
+		return nil, err
+	}
+
+	var vars map[string]any
+
+	if strings.EqualFold(filepath.Ext(filename), ".json") {
+		err = json.Unmarshal(data, &vars)
+	} else {
+		vars, err = parseYAMLData(data)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	if vars == nil {
+		vars = make(map[string]any)
+	}
+
+	return vars, nil
+}
+
+// parseYAMLData parses the YAML document of template variables, which must
+// be a mapping. The true and false values are booleans, other scalars (like
+// version numbers) are strings, with their text as written.
+func parseYAMLData(data []byte) (map[string]any, error) {
+	var doc yaml.Node
+
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %s", errTemplateData, strings.TrimPrefix(err.Error(), "yaml: "))
+	}
+
+	if doc.Kind == 0 {
+		return nil, nil
+	}
+
+	value, err := yamlData(doc.Content[0])
+	if err != nil {
+		return nil, err
+	}
+
+	vars, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: the document is not a mapping", errTemplateData)
+	}
+
+	return vars, nil
+}
+
+// yamlData converts the YAML node to template data.
+func yamlData(node *yaml.Node) (any, error) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		mapping := make(map[string]any, len(node.Content)/2) //nolint:gomnd
+
+		for idx := 0; idx+1 < len(node.Content); idx += 2 {
+			key := node.Content[idx]
+			if _, has := mapping[key.Value]; has {
+				return nil, fmt.Errorf("%w: line %d: duplicate key %s", errTemplateData, key.Line, key.Value)
+			}
+
+			value, err := yamlData(node.Content[idx+1])
+			if err != nil {
+				return nil, err
+			}
+
+			mapping[key.Value] = value
+		}
+
+		return mapping, nil
+	case yaml.SequenceNode:
+		items := make([]any, 0, len(node.Content))
+
+		for _, item := range node.Content {
+			value, err := yamlData(item)
+			if err != nil {
+				return nil, err
+			}
+
+			items = append(items, value)
+		}
+
+		return items, nil
+	case yaml.AliasNode:
+		return yamlData(node.Alias)
+	case yaml.ScalarNode:
+		switch node.ShortTag() {
+		case "!!bool":
+			var value bool
+
+			return value, node.Decode(&value)
+		case "!!null":
+			return "", nil
+		default:
+			return node.Value, nil
+		}
+	default:
+		return nil, fmt.Errorf("%w: line %d: unexpected node", errTemplateData, node.Line)
+	}
+}
+
+var errTemplateData = errors.New("invalid template data")
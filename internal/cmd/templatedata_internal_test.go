@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseYAMLData(t *testing.T) {
+	t.Parallel()
+
+	src := "# variables\n" +
+		"Version: \"1.2\" # pinned\n" +
+		"Go: 1.10\n" +
+		"Beta: true\n" +
+		"Name: 'it''s' # quoted\n" +
+		"Empty:\n" +
+		"b: [1, 2]\n" +
+		"c: {x: y}\n" +
+		"Servers:\n" +
+		"  - host: a\n" +
+		"    port: 80\n" +
+		"  - b\n" +
+		"Text: |\n" +
+		"  line # not a comment\n"
+
+	vars, err := parseYAMLData([]byte(src))
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]any{
+		"Version": "1.2",
+		"Go":      "1.10",
+		"Beta":    true,
+		"Name":    "it's",
+		"Empty":   "",
+		"b":       []any{"1", "2"},
+		"c":       map[string]any{"x": "y"},
+		"Servers": []any{map[string]any{"host": "a", "port": "80"}, "b"},
+		"Text":    "line # not a comment\n",
+	}, vars)
+}
+
+func Test_parseYAMLData_invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, src := range []string{"a: 1\na: 2\n", "a: 1\n b: 2\n", "- a\n- b\n", "a: [1, 2\n"} {
+		_, err := parseYAMLData([]byte(src))
+		require.ErrorIs(t, err, errTemplateData, src)
+	}
+
+	vars, err := parseYAMLData([]byte("# nothing\n"))
+	require.NoError(t, err)
+	require.Empty(t, vars)
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// trustFilename is the file of the trusted markdown documents, in the mdcode
+// directory of the user configuration directory: a trust file in the
+// repository could be committed by the authors of the untrusted documents.
+const trustFilename = "trusted"
+
+// trust holds the hashes of the markdown documents (path and content) the
+// user agreed to execute. Like direnv, a document not seen before or
+// modified since is executed only after confirmation.
+type trust struct {
+	filename string
+	store    *execCache
+	yes      bool
+	in       *bufio.Reader
+	prompt   bool
+	out      io.Writer
+}
+
+// loadTrust loads the trusted documents. With yes, the documents are trusted
+// without confirmation. The confirmation is asked only if in is a terminal.
+func loadTrust(yes bool, in io.Reader, out io.Writer) (*trust, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	filename := filepath.Join(dir, appname, trustFilename)
+
+	store, err := loadExecCache(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return &trust{
+		filename: filename,
+		store:    store,
+		yes:      yes,
+		in:       bufio.NewReader(in),
+		prompt:   isTerminal(in),
+		out:      out,
+	}, nil
+}
+
+func trustKey(filename string, src []byte) string {
+	hash := sha256.New()
+
+	if abs, err := filepath.Abs(filename); err == nil {
+		filename = abs
+	}
+
+	hash.Write([]byte(filename))
+	hash.Write([]byte{0})
+	hash.Write(src)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// check refuses to execute the document if it is not trusted and the user
+// doesn't confirm. Confirmed documents are recorded as trusted.
+func (t *trust) check(filename string, src []byte) error {
+	key := trustKey(filename, src)
+	if t.store.has(key) {
+		return nil
+	}
+
+	if !t.yes {
+		if !t.prompt {
+			return fmt.Errorf("%w: %s, review it and run with --yes", errUntrusted, filename)
+		}
+
+		fmt.Fprintf(t.out, "%s is new or modified since it was last trusted, execute its code blocks? [y/N] ", filename)
+
+		line, err := t.in.ReadString('\n')
+		if errors.Is(err, io.EOF) {
+			fmt.Fprintln(t.out)
+		} else if err != nil {
+			return err
+		}
+
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "y", "yes":
+		default:
+			return fmt.Errorf("%w: %s", errUntrusted, filename)
+		}
+	}
+
+	t.store.add(key)
+
+	if err := os.MkdirAll(filepath.Dir(t.filename), dirMode); err != nil {
+		return err
+	}
+
+	return t.store.save(t.filename)
+}
+
+// enforceTrust refuses to execute untrusted markdown documents. It is not
+// enforced with --only-approved, the code blocks are checked one by one then.
+func (e *execOptions) enforceTrust(filename string, src []byte) error {
+	if e.trust == nil {
+		return nil
+	}
+
+	return e.trust.check(filename, src)
+}
+
+var errUntrusted = errors.New("document not trusted")
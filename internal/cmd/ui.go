@@ -0,0 +1,453 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/spf13/cobra"
+)
+
+//go:embed help/ui.md
+var uiHelp string
+
+// uiKeys is the help of the line mode.
+const uiKeys = `commands:
+  l              list the code blocks (matching the filter)
+  /text          filter the code blocks by document, language, file or code (/ clears)
+  N, p N         preview code block N
+  r N            run code block N by the built-in runner of its language
+  d N            diff code block N with its file
+  s N            sync code block N from its file
+  c N            copy code block N to the clipboard
+  q              quit
+`
+
+func uiCmd(opts *options) *cobra.Command {
+	var yes bool
+
+	cmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "ui [flags] [filename]",
+		Short: "Browse the markdown code blocks interactively",
+		Long:  uiHelp,
+		Args:  checkargs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			opts.createStatus(cmd.ErrOrStderr())
+
+			return opts.allBlocksFilter(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files, err := sources(args, opts)
+			if err != nil {
+				return err
+			}
+
+			in, out := cmd.InOrStdin(), cmd.OutOrStdout()
+			b := &browser{in: bufio.NewReader(in), out: out, opts: opts, files: files} //nolint:exhaustruct
+
+			// The confirmation shares the input of the browser, which is
+			// interactive by nature.
+			if b.trust, err = loadTrust(yes, b.in, b.out); err != nil {
+				return err
+			}
+
+			b.trust.prompt = true
+
+			if !isTerminal(in) || !isTerminal(out) {
+				return b.loop()
+			}
+
+			b.terminal = &uiTerminal{in: int(in.(*os.File).Fd()), out: int(out.(*os.File).Fd())} //nolint:forcetypeassert,exhaustruct
+
+			return b.interact()
+		},
+
+		DisableAutoGenTag: true,
+	}
+
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "run documents not run before (or modified since) without confirmation, and trust them")
+
+	return cmd
+}
+
+// uiEntry is a code block listed by the browser.
+type uiEntry struct {
+	number   int
+	document string
+	block    *mdcode.Block
+}
+
+// browser is the interactive code block browser. On terminals, it runs in
+// full screen mode reading single keys, otherwise (like from scripts) it
+// lists the code blocks and reads line oriented commands from the input.
+type browser struct {
+	in       *bufio.Reader
+	out      io.Writer
+	opts     *options
+	files    []string
+	entries  []*uiEntry
+	query    string
+	trust    *trust
+	terminal *uiTerminal
+	screen   screen
+}
+
+func (b *browser) load() error {
+	b.entries = nil
+
+	for _, file := range b.files {
+		src, _, err := b.opts.readDocument(file)
+		if err != nil {
+			return err
+		}
+
+		_, _, err = walk(src, func(block *mdcode.Block) error {
+			b.entries = append(b.entries, &uiEntry{number: len(b.entries) + 1, document: file, block: block})
+
+			return nil
+		}, b.opts.filter)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *browser) loop() error {
+	if err := b.load(); err != nil {
+		return err
+	}
+
+	b.list()
+
+	for {
+		fmt.Fprint(b.out, "mdcode> ")
+
+		line, err := b.in.ReadString('\n')
+		if err != nil && (!errors.Is(err, io.EOF) || len(line) == 0) {
+			if errors.Is(err, io.EOF) {
+				fmt.Fprintln(b.out)
+
+				return nil
+			}
+
+			return err
+		}
+
+		quit, err := b.command(strings.TrimSpace(line))
+		if err != nil {
+			fmt.Fprintf(b.out, "error: %v\n", err)
+		}
+
+		if quit {
+			return nil
+		}
+	}
+}
+
+func (b *browser) command(line string) (bool, error) {
+	if strings.HasPrefix(line, "/") {
+		b.query = strings.TrimSpace(line[1:])
+		b.list()
+
+		return false, nil
+	}
+
+	verb, arg, _ := strings.Cut(line, " ")
+	if _, err := strconv.Atoi(verb); err == nil {
+		verb, arg = "p", verb
+	}
+
+	switch verb {
+	case "q", "quit", "exit":
+		return true, nil
+	case "", "l", "ls", "list":
+		b.list()
+
+		return false, nil
+	case "?", "h", "help":
+		fmt.Fprint(b.out, uiKeys)
+
+		return false, nil
+	}
+
+	entry, err := b.entry(arg)
+	if err != nil {
+		return false, err
+	}
+
+	switch verb {
+	case "p", "preview":
+		b.preview(entry)
+	case "r", "run":
+		return false, b.run(entry)
+	case "d", "diff":
+		return false, b.diff(entry)
+	case "s", "sync":
+		return false, b.sync(entry)
+	case "c", "copy":
+		b.copy(entry)
+	default:
+		return false, fmt.Errorf("%w: %s (? for help)", errUICommand, verb)
+	}
+
+	return false, nil
+}
+
+func (b *browser) entry(arg string) (*uiEntry, error) {
+	number, err := strconv.Atoi(strings.TrimSpace(arg))
+	if err != nil || number < 1 || number > len(b.entries) {
+		return nil, fmt.Errorf("%w: %s", errUIBlock, arg)
+	}
+
+	return b.entries[number-1], nil
+}
+
+func (b *browser) matches(entry *uiEntry) bool {
+	if len(b.query) == 0 {
+		return true
+	}
+
+	query := strings.ToLower(b.query)
+
+	for _, s := range []string{entry.document, entry.block.Lang, entry.block.Meta.Get(metaFile), string(entry.block.Code)} {
+		if strings.Contains(strings.ToLower(s), query) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (b *browser) list() {
+	count := 0
+
+	for _, entry := range b.entries {
+		if !b.matches(entry) {
+			continue
+		}
+
+		count++
+
+		fmt.Fprintf(b.out, "%3d  %s:%d  %s  %s\n", entry.number, entry.document, entry.block.StartLine,
+			langLabel(entry.block.Lang), entry.block.Meta.Get(metaFile))
+	}
+
+	fmt.Fprintf(b.out, "%d of %d code blocks", count, len(b.entries))
+
+	if len(b.query) != 0 {
+		fmt.Fprintf(b.out, " matching %q", b.query)
+	}
+
+	fmt.Fprint(b.out, ", ? for help\n")
+}
+
+func (b *browser) preview(entry *uiEntry) {
+	fmt.Fprintf(b.out, "%s\n", b.opts.color.header(fmt.Sprintf("--- %d (%s) : %s:%d ---", entry.number,
+		langLabel(entry.block.Lang), entry.document, entry.block.StartLine)))
+	fmt.Fprintf(b.out, "%s\n", strings.TrimRight(string(entry.block.Code), "\n"))
+}
+
+// run writes the code block to a temporary directory and runs it by the
+// built-in runner of its language.
+func (b *browser) run(entry *uiEntry) error {
+	lang := b.opts.aliases.canonical(entry.block.Lang)
+
+	runner, has := langRunners[lang]
+	if !has {
+		return fmt.Errorf("%w: %s", errNoRunner, langLabel(entry.block.Lang))
+	}
+
+	src, _, err := b.opts.readDocument(entry.document)
+	if err != nil {
+		return err
+	}
+
+	if err := b.trust.check(entry.document, src); err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "mdcode-ui-")
+	if err != nil {
+		return err
+	}
+
+	defer os.RemoveAll(dir)
+
+	name := "main" + langExtension(lang)
+	if err := os.WriteFile(filepath.Join(dir, name), entry.block.Code, fileMode); err != nil {
+		return err
+	}
+
+	command := strings.ReplaceAll(runner, "{}", name)
+	fmt.Fprintf(b.out, "$ %s\n", command)
+
+	exitCode, err := runCommand(command, dir, os.Stdin, b.out, b.out)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(b.out, "%s\n", exitStatus(exitCode, b.opts.color))
+
+	return nil
+}
+
+// fileCode loads the content of the file (or region) of the code block.
+func (b *browser) fileCode(entry *uiEntry) ([]byte, error) {
+	if len(entry.block.Meta.Get(metaFile)) == 0 {
+		return nil, fmt.Errorf("%w: %d", errUINoFile, entry.number)
+	}
+
+	dir := filepath.Dir(entry.document)
+
+	if err := b.opts.confineTo(dir, entry.block); err != nil {
+		return nil, err
+	}
+
+	probe := *entry.block
+
+	if err := load(&probe, dir, nostatus); err != nil {
+		return nil, err
+	}
+
+	return probe.Code, nil
+}
+
+func (b *browser) diff(entry *uiEntry) error {
+	code, err := b.fileCode(entry)
+	if err != nil {
+		return err
+	}
+
+	if bytes.Equal(code, entry.block.Code) {
+		fmt.Fprintf(b.out, "code block %d is in sync with %s\n", entry.number, entry.block.Meta.Get(metaFile))
+
+		return nil
+	}
+
+	fmt.Fprintf(b.out, "--- %s:%d\n+++ %s\n", entry.document, entry.block.StartLine, entry.block.Meta.Get(metaFile))
+
+	for _, line := range lineDiff(splitLines(entry.block.Code), splitLines(code)) {
+		fmt.Fprintln(b.out, line)
+	}
+
+	return nil
+}
+
+// sync updates the code block in its markdown document from its file.
+func (b *browser) sync(entry *uiEntry) (err error) {
+	code, err := b.fileCode(entry)
+	if err != nil {
+		return err
+	}
+
+	lock, err := lockDocument(entry.document)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err = errors.Join(err, lock.unlock())
+	}()
+
+	src, format, err := b.opts.readDocument(entry.document)
+	if err != nil {
+		return err
+	}
+
+	modified, res, err := mdcode.Walk(src, func(block *mdcode.Block) error {
+		if block.StartLine == entry.block.StartLine {
+			block.Code = convertEOL(code, b.opts.lineEnding(src))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if modified {
+		if err := writeDocument(entry.document, res, format); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(b.out, "code block %d synced from %s\n", entry.number, entry.block.Meta.Get(metaFile))
+
+	return b.load()
+}
+
+// copy copies the code block to the clipboard and reports it.
+func (b *browser) copy(entry *uiEntry) {
+	b.clipboard(entry)
+	fmt.Fprintf(b.out, "code block %d copied to the clipboard\n", entry.number)
+}
+
+// clipboard copies the code block to the clipboard with the OSC 52 terminal
+// escape sequence, supported by most terminal emulators (also over SSH).
+func (b *browser) clipboard(entry *uiEntry) {
+	fmt.Fprintf(b.out, "\033]52;c;%s\a", base64.StdEncoding.EncodeToString(entry.block.Code))
+}
+
+func splitLines(code []byte) []string {
+	if len(code) == 0 {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(string(code), "\n"), "\n")
+}
+
+// lineDiff returns the lines of a minimal diff turning a into b, prefixed
+// with "-", "+" or " ".
+func lineDiff(a, b []string) []string {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []string
+
+	i, j := 0, 0
+
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, " "+a[i])
+			i, j = i+1, j+1
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, "-"+a[i])
+			i++
+		default:
+			lines = append(lines, "+"+b[j])
+			j++
+		}
+	}
+
+	return lines
+}
+
+var (
+	errUICommand = errors.New("unknown command")
+	errUIBlock   = errors.New("no such code block")
+	errUINoFile  = errors.New("code block has no file metadata")
+)
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_readKey(t *testing.T) {
+	t.Parallel()
+
+	in := bufio.NewReader(strings.NewReader("\033[Aj\r\033[6~é\x7f\033"))
+
+	for _, expected := range []string{keyUp, "j", keyEnter, keyPageDown, "é", keyDelete, keyEsc} {
+		key, err := readKey(in)
+		require.NoError(t, err)
+		require.Equal(t, expected, key)
+	}
+}
+
+func Test_browser_press(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+
+	b := &browser{out: &out, opts: &options{}, screen: screen{width: 60, height: 12}} //nolint:exhaustruct
+	b.entries = []*uiEntry{
+		{number: 1, document: "doc.md", block: &mdcode.Block{Lang: "go", Code: []byte("package main\n"), StartLine: 1}}, //nolint:exhaustruct
+		{number: 2, document: "doc.md", block: &mdcode.Block{Lang: "sh", Code: []byte("echo hi\n"), StartLine: 5}},      //nolint:exhaustruct
+	}
+
+	b.press("j")
+	b.press("j")
+	require.Equal(t, 1, b.screen.selected)
+
+	b.draw()
+	require.Contains(t, out.String(), ">   2  doc.md:5  sh")
+	require.Contains(t, out.String(), "echo hi")
+
+	for _, key := range []string{"/", "g", "o", "x", keyDelete, keyEnter} {
+		require.False(t, b.press(key))
+	}
+
+	require.Equal(t, "go", b.query)
+	require.Equal(t, 0, b.screen.selected)
+
+	out.Reset()
+	b.draw()
+	require.Contains(t, out.String(), "1 of 2 code blocks matching \"go\"")
+	require.Contains(t, out.String(), ">   1  doc.md:1  go")
+	require.NotContains(t, out.String(), "echo hi")
+
+	b.press("/")
+	b.press("z")
+	b.press(keyEsc)
+	require.Equal(t, "go", b.query)
+
+	b.press("d")
+	require.Contains(t, b.screen.message, errUINoFile.Error())
+
+	require.True(t, b.press("q"))
+}
+
+func Test_browser_diff_base(t *testing.T) {
+	dir := t.TempDir()
+	docs := filepath.Join(dir, "docs")
+
+	require.NoError(t, os.Mkdir(docs, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "secret.go"), []byte("package main\n"), 0o600))
+	require.NoError(t, os.Symlink(filepath.Join(dir, "secret.go"), filepath.Join(docs, "main.go")))
+	require.NoError(t, os.WriteFile(filepath.Join(docs, "doc.go"), []byte("package main\n"), 0o600))
+
+	src := "```go file=main.go\npackage main\n```\n\n```go file=doc.go\npackage main\n```\n"
+	require.NoError(t, os.WriteFile(filepath.Join(docs, "doc.md"), []byte(src), 0o600))
+
+	out, err := runMdcodeInput(t, dir, "d 1\nd 2\nq\n", "ui", "docs/doc.md")
+	require.NoError(t, err, out)
+	require.Contains(t, out, errOutside.Error())
+	require.Contains(t, out, "code block 2 is in sync with doc.go")
+}
@@ -0,0 +1,478 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+const uiScreenKeys = `keys:
+  j, down        select the next code block
+  k, up          select the previous code block
+  pgdn, pgup     select the code block a page below or above
+  g, G           select the first or last code block
+  /              filter the code blocks by document, language, file or code (enter applies, esc cancels)
+  r              run the code block by the built-in runner of its language
+  d              diff the code block with its file
+  s              sync the code block from its file
+  c              copy the code block to the clipboard
+  ?              show the keys
+  q              quit
+`
+
+const uiScreenHint = "j/k move  / filter  r run  d diff  s sync  c copy  ? help  q quit"
+
+// Names of the special keys read by readKey, the other keys are read as the
+// string of their character.
+const (
+	keyUp       = "up"
+	keyDown     = "down"
+	keyPageUp   = "pgup"
+	keyPageDown = "pgdn"
+	keyHome     = "home"
+	keyEnd      = "end"
+	keyEnter    = "enter"
+	keyEsc      = "esc"
+	keyDelete   = "backspace"
+	keyCtrlC    = "ctrl-c"
+)
+
+// Default size of the screen, if the size of the terminal is unknown.
+const (
+	screenWidth  = 80
+	screenHeight = 24
+)
+
+// screen is the state of the full screen mode of the browser: the code
+// blocks are listed in the upper half, the selected one is previewed in the
+// lower half and single keys act on it.
+type screen struct {
+	width    int
+	height   int
+	selected int      // index of the selected code block among the listed ones
+	offset   int      // index of the first code block listed on the screen
+	editing  bool     // the filter is being typed
+	previous string   // the filter before editing
+	message  string   // status line replacing the key hints
+	pane     []string // lines shown instead of the preview (diff, help)
+}
+
+// rows returns the number of code blocks listed on the screen.
+func (s *screen) rows() int {
+	return max(1, (s.height-3)/2) //nolint:gomnd
+}
+
+// uiTerminal switches the terminal of the full screen mode between the raw
+// mode, reading single keys, and the normal mode, running code blocks.
+type uiTerminal struct {
+	in    int
+	out   int
+	state *term.State
+}
+
+func (t *uiTerminal) raw() error {
+	state, err := term.MakeRaw(t.in)
+	if err != nil {
+		return err
+	}
+
+	t.state = state
+
+	return nil
+}
+
+func (t *uiTerminal) restore() error {
+	if t.state == nil {
+		return nil
+	}
+
+	state := t.state
+	t.state = nil
+
+	return term.Restore(t.in, state)
+}
+
+func (t *uiTerminal) size() (int, int) {
+	width, height, err := term.GetSize(t.out)
+	if err != nil || width <= 0 || height <= 0 {
+		return screenWidth, screenHeight
+	}
+
+	return width, height
+}
+
+// interact runs the full screen mode until q is pressed.
+func (b *browser) interact() (err error) {
+	if err := b.load(); err != nil {
+		return err
+	}
+
+	if err := b.enter(); err != nil {
+		return err
+	}
+
+	defer func() {
+		err = errors.Join(err, b.leave())
+	}()
+
+	for {
+		b.draw()
+
+		key, err := readKey(b.in)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if b.press(key) {
+			return nil
+		}
+	}
+}
+
+// enter switches to the raw mode and the alternate screen of the terminal.
+func (b *browser) enter() error {
+	if b.terminal != nil {
+		if err := b.terminal.raw(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprint(b.out, "\033[?1049h\033[?25l")
+
+	return nil
+}
+
+// leave restores the normal mode and screen of the terminal.
+func (b *browser) leave() error {
+	fmt.Fprint(b.out, "\033[?25h\033[?1049l")
+
+	if b.terminal != nil {
+		return b.terminal.restore()
+	}
+
+	return nil
+}
+
+// listed returns the code blocks matching the filter.
+func (b *browser) listed() []*uiEntry {
+	var listed []*uiEntry
+
+	for _, entry := range b.entries {
+		if b.matches(entry) {
+			listed = append(listed, entry)
+		}
+	}
+
+	return listed
+}
+
+// press handles a key of the full screen mode, it reports whether to quit.
+func (b *browser) press(key string) bool {
+	s := &b.screen
+
+	if s.editing {
+		b.edit(key)
+
+		return false
+	}
+
+	s.message, s.pane = "", nil
+	listed := b.listed()
+
+	switch key {
+	case "q", keyCtrlC:
+		return true
+	case "j", keyDown:
+		s.selected++
+	case "k", keyUp:
+		s.selected--
+	case keyPageDown:
+		s.selected += s.rows()
+	case keyPageUp:
+		s.selected -= s.rows()
+	case "g", keyHome:
+		s.selected = 0
+	case "G", keyEnd:
+		s.selected = len(listed) - 1
+	case "/":
+		s.editing, s.previous = true, b.query
+	case "?":
+		s.pane = splitLines([]byte(uiScreenKeys))
+	case "r", "d", "s", "c":
+		if len(listed) == 0 {
+			s.message = "no code blocks"
+
+			break
+		}
+
+		b.act(key, listed[min(max(s.selected, 0), len(listed)-1)])
+	}
+
+	s.selected = min(s.selected, len(b.listed())-1)
+	s.selected = max(s.selected, 0)
+
+	return false
+}
+
+// edit handles a key typing the filter, the listed code blocks follow it.
+func (b *browser) edit(key string) {
+	s := &b.screen
+
+	switch key {
+	case keyEnter:
+		s.editing = false
+	case keyEsc, keyCtrlC:
+		s.editing, b.query = false, s.previous
+	case keyDelete:
+		_, size := utf8.DecodeLastRuneInString(b.query)
+		b.query = b.query[:len(b.query)-size]
+	default:
+		if r, size := utf8.DecodeRuneInString(key); size == len(key) && unicode.IsPrint(r) {
+			b.query += key
+		}
+	}
+
+	s.selected, s.offset = 0, 0
+}
+
+// act runs the action of the key on the code block.
+func (b *browser) act(key string, entry *uiEntry) {
+	s := &b.screen
+
+	var (
+		lines []string
+		err   error
+	)
+
+	switch key {
+	case "r":
+		err = b.suspend(func() error { return b.run(entry) })
+	case "d":
+		lines, err = b.capture(func() error { return b.diff(entry) })
+		s.pane = lines
+	case "s":
+		lines, err = b.capture(func() error { return b.sync(entry) })
+		if len(lines) != 0 {
+			s.message = lines[len(lines)-1]
+		}
+	case "c":
+		b.clipboard(entry)
+		s.message = fmt.Sprintf("code block %d copied to the clipboard", entry.number)
+	}
+
+	if err != nil {
+		s.message = b.opts.color.fail("error: " + err.Error())
+	}
+}
+
+// suspend runs the action in the normal mode of the terminal, so its output
+// stays visible (and the trust confirmation can be answered) until a key is
+// pressed.
+func (b *browser) suspend(action func() error) error {
+	if err := b.leave(); err != nil {
+		return err
+	}
+
+	err := action()
+	if err != nil {
+		fmt.Fprintf(b.out, "error: %v\n", err)
+	}
+
+	fmt.Fprint(b.out, "press any key to return")
+
+	if err := b.enter(); err != nil {
+		return err
+	}
+
+	if _, err := readKey(b.in); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	return nil
+}
+
+// capture returns the output lines of the action of the line mode, to show
+// them on the screen.
+func (b *browser) capture(action func() error) ([]string, error) {
+	out := b.out
+
+	defer func() {
+		b.out = out
+	}()
+
+	var buf bytes.Buffer
+
+	b.out = &buf
+	err := action()
+
+	return splitLines(buf.Bytes()), err
+}
+
+// draw redraws the whole screen.
+func (b *browser) draw() {
+	s := &b.screen
+
+	if b.terminal != nil {
+		s.width, s.height = b.terminal.size()
+	}
+
+	listed := b.listed()
+	rows := s.rows()
+
+	if s.selected < s.offset {
+		s.offset = s.selected
+	}
+
+	if s.selected >= s.offset+rows {
+		s.offset = s.selected - rows + 1
+	}
+
+	header := fmt.Sprintf("mdcode ui: %d of %d code blocks", len(listed), len(b.entries))
+	if len(b.query) != 0 {
+		header += fmt.Sprintf(" matching %q", b.query)
+	}
+
+	lines := []string{b.opts.color.header(fit(header, s.width))}
+
+	for i := s.offset; i < s.offset+rows; i++ {
+		if i >= len(listed) {
+			lines = append(lines, "")
+
+			continue
+		}
+
+		entry := listed[i]
+		line := fmt.Sprintf("%3d  %s:%d  %s  %s", entry.number, entry.document, entry.block.StartLine,
+			langLabel(entry.block.Lang), entry.block.Meta.Get(metaFile))
+
+		if i == s.selected {
+			lines = append(lines, b.opts.color.paint("7", fit("> "+line, s.width)))
+		} else {
+			lines = append(lines, fit("  "+line, s.width))
+		}
+	}
+
+	pane := s.pane
+
+	if pane == nil && len(listed) != 0 {
+		entry := listed[s.selected]
+		pane = append([]string{fmt.Sprintf("--- %d (%s) : %s:%d ---", entry.number,
+			langLabel(entry.block.Lang), entry.document, entry.block.StartLine)}, splitLines(entry.block.Code)...)
+	}
+
+	for i := 0; i < s.height-rows-2; i++ {
+		if i < len(pane) {
+			lines = append(lines, fit(pane[i], s.width))
+		} else {
+			lines = append(lines, "")
+		}
+	}
+
+	switch {
+	case s.editing:
+		lines = append(lines, fit("/"+b.query+"_", s.width))
+	case len(s.message) != 0:
+		lines = append(lines, s.message)
+	default:
+		lines = append(lines, fit(uiScreenHint, s.width))
+	}
+
+	fmt.Fprint(b.out, "\033[H"+strings.Join(lines, "\033[K\r\n")+"\033[K\033[J")
+}
+
+// fit expands the tabs of the line and cuts it to the width of the screen.
+func fit(line string, width int) string {
+	line = strings.ReplaceAll(line, "\t", "    ")
+
+	if utf8.RuneCountInString(line) <= width {
+		return line
+	}
+
+	return string([]rune(line)[:max(width, 0)])
+}
+
+// readKey reads a key pressed in the raw mode of the terminal: the escape
+// sequences of the special keys are returned by name.
+func readKey(in *bufio.Reader) (string, error) {
+	char, err := in.ReadByte()
+	if err != nil {
+		return "", err
+	}
+
+	switch char {
+	case '\r', '\n':
+		return keyEnter, nil
+	case 0x7f, '\b':
+		return keyDelete, nil
+	case 0x03:
+		return keyCtrlC, nil
+	case 0x1b:
+		return readEscape(in)
+	}
+
+	if err := in.UnreadByte(); err != nil {
+		return "", err
+	}
+
+	r, _, err := in.ReadRune()
+	if err != nil {
+		return "", err
+	}
+
+	return string(r), nil
+}
+
+// readEscape reads the rest of an escape sequence. The terminal sends the
+// sequences at once, so a lone escape is the esc key.
+func readEscape(in *bufio.Reader) (string, error) {
+	if in.Buffered() == 0 {
+		return keyEsc, nil
+	}
+
+	if next, err := in.ReadByte(); err != nil || (next != '[' && next != 'O') {
+		return keyEsc, err
+	}
+
+	var seq []byte
+
+	for {
+		char, err := in.ReadByte()
+		if err != nil {
+			return "", err
+		}
+
+		seq = append(seq, char)
+
+		if char >= 0x40 && char <= 0x7e {
+			break
+		}
+	}
+
+	switch string(seq) {
+	case "A":
+		return keyUp, nil
+	case "B":
+		return keyDown, nil
+	case "5~":
+		return keyPageUp, nil
+	case "6~":
+		return keyPageDown, nil
+	case "H", "1~":
+		return keyHome, nil
+	case "F", "4~":
+		return keyEnd, nil
+	}
+
+	return "", nil
+}
@@ -0,0 +1,336 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+//go:embed help/undo.md
+var undoHelp string
+
+// maxBackups is the number of previous versions kept per markdown document.
+const maxBackups = 10
+
+const backupJournal = "journal.jsonl"
+
+// backupEntry records a rewrite of a markdown document: the backup of its
+// previous content and the hash of the written content.
+type backupEntry struct {
+	Time     time.Time `json:"time"`
+	Document string    `json:"document"`
+	Backup   string    `json:"backup"`
+	Written  string    `json:"written"`
+}
+
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// backupDir returns the backup store of the markdown document: the
+// .mdcode/backup directory of its git repository, or of its directory
+// outside of repositories, so undo works from any directory.
+func backupDir(abs string) string {
+	root := filepath.Dir(abs)
+
+	if out, err := gitOutput("-C", root, "rev-parse", "--show-toplevel"); err == nil {
+		if top := strings.TrimSpace(string(out)); len(top) != 0 {
+			root = filepath.FromSlash(top)
+		}
+	}
+
+	return filepath.Join(root, stateDir, "backup")
+}
+
+// lockJournal takes the lock of the backup journal, so concurrent rewrites
+// of documents sharing the backup store don't lose entries.
+func lockJournal(dir string) (*documentLock, error) {
+	if err := os.MkdirAll(dir, dirMode); err != nil {
+		return nil, err
+	}
+
+	return lockDocument(filepath.Join(dir, backupJournal))
+}
+
+// backupWrite writes the markdown document, keeping its previous content in
+// the backup store, so that the rewrite can be undone.
+func backupWrite(filename string, data []byte) (err error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return err
+	}
+
+	prev, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return os.WriteFile(filename, data, fileMode)
+		}
+
+		return err
+	}
+
+	dir := backupDir(abs)
+
+	lock, err := lockJournal(dir)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err = errors.Join(err, lock.unlock())
+	}()
+
+	now := time.Now()
+	backup := strconv.FormatInt(now.UnixNano(), 10) + "-" + contentHash([]byte(abs))[:12] + filepath.Ext(filename)
+
+	if err := os.WriteFile(filepath.Join(dir, backup), prev, fileMode); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filename, data, fileMode); err != nil {
+		return errors.Join(err, os.Remove(filepath.Join(dir, backup)))
+	}
+
+	entries, err := loadBackups(dir)
+	if err != nil {
+		return err
+	}
+
+	entries = append(entries, &backupEntry{Time: now.UTC(), Document: abs, Backup: backup, Written: contentHash(data)})
+
+	return saveBackups(dir, pruneBackups(dir, entries, abs))
+}
+
+// pruneBackups removes the oldest backups of the document above maxBackups.
+func pruneBackups(dir string, entries []*backupEntry, document string) []*backupEntry {
+	count := 0
+
+	for _, entry := range entries {
+		if entry.Document == document {
+			count++
+		}
+	}
+
+	kept := entries[:0]
+
+	for _, entry := range entries {
+		if entry.Document == document && count > maxBackups {
+			count--
+
+			_ = os.Remove(filepath.Join(dir, entry.Backup))
+
+			continue
+		}
+
+		kept = append(kept, entry)
+	}
+
+	return kept
+}
+
+func loadBackups(dir string) ([]*backupEntry, error) {
+	journal := filepath.Join(dir, backupJournal)
+
+	file, err := os.Open(journal)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	defer file.Close()
+
+	var entries []*backupEntry
+
+	dec := json.NewDecoder(file)
+
+	for {
+		entry := new(backupEntry)
+
+		if err := dec.Decode(entry); err != nil {
+			if errors.Is(err, io.EOF) {
+				return entries, nil
+			}
+
+			return nil, fmt.Errorf("%w: %s: %w", errBackupJournal, journal, err)
+		}
+
+		entries = append(entries, entry)
+	}
+}
+
+func saveBackups(dir string, entries []*backupEntry) error {
+	var data []byte
+
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+
+		data = append(append(data, line...), '\n')
+	}
+
+	return os.WriteFile(filepath.Join(dir, backupJournal), data, fileMode)
+}
+
+func undoCmd(opts *options) *cobra.Command {
+	var force, list bool
+
+	cmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "undo [flags] [filename]",
+		Short: "Restore the previous version of a rewritten markdown document",
+		Long:  undoHelp,
+		Args:  checkargs,
+		PreRun: func(cmd *cobra.Command, _ []string) {
+			opts.createStatus(cmd.ErrOrStderr())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filename := opts.source(args)
+
+			if list {
+				return undoList(filename, cmd.OutOrStdout())
+			}
+
+			return undoRun(filename, force, opts)
+		},
+
+		DisableAutoGenTag: true,
+	}
+
+	quietFlag(cmd, opts)
+
+	cmd.Flags().BoolVar(&force, "force", false, "restore even if the document was modified after the last rewrite")
+	cmd.Flags().BoolVar(&list, "list", false, "list the previous versions of the document available for undo")
+
+	return cmd
+}
+
+// documentBackups returns the indexes of the backup entries of the document,
+// the most recent last.
+func documentBackups(entries []*backupEntry, abs string) []int {
+	var found []int
+
+	for idx, entry := range entries {
+		if entry.Document == abs {
+			found = append(found, idx)
+		}
+	}
+
+	return found
+}
+
+func undoList(filename string, out io.Writer) error {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return err
+	}
+
+	dir := backupDir(abs)
+
+	entries, err := loadBackups(dir)
+	if err != nil {
+		return err
+	}
+
+	found := documentBackups(entries, abs)
+
+	for i := len(found) - 1; i >= 0; i-- {
+		entry := entries[found[i]]
+
+		fmt.Fprintf(out, "%s  %s\n", entry.Time.Local().Format(time.DateTime), filepath.Join(dir, entry.Backup))
+	}
+
+	return nil
+}
+
+// undoRun restores the content of the document before its last rewrite,
+// and drops the backup, so that repeated undos go further back. The document
+// and the journal are locked meanwhile.
+func undoRun(filename string, force bool, opts *options) (err error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return err
+	}
+
+	lock, err := lockDocument(filename)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err = errors.Join(err, lock.unlock())
+	}()
+
+	dir := backupDir(abs)
+
+	journalLock, err := lockJournal(dir)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err = errors.Join(err, journalLock.unlock())
+	}()
+
+	entries, err := loadBackups(dir)
+	if err != nil {
+		return err
+	}
+
+	found := documentBackups(entries, abs)
+
+	if len(found) == 0 {
+		return fmt.Errorf("%w: %s", errNoBackup, filename)
+	}
+
+	idx := found[len(found)-1]
+	entry := entries[idx]
+
+	current, err := os.ReadFile(filename)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if !force && contentHash(current) != entry.Written {
+		return fmt.Errorf("%w: %s (use --force to restore anyway)", errModifiedSince, filename)
+	}
+
+	prev, err := os.ReadFile(filepath.Join(dir, entry.Backup))
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filename, prev, fileMode); err != nil {
+		return err
+	}
+
+	opts.status("Restored %s from %s\n", filename, entry.Time.Local().Format(time.DateTime))
+
+	if err := os.Remove(filepath.Join(dir, entry.Backup)); err != nil {
+		return err
+	}
+
+	return saveBackups(dir, append(entries[:idx], entries[idx+1:]...))
+}
+
+var (
+	errBackupJournal = errors.New("invalid backup journal")
+	errNoBackup      = errors.New("no previous version to restore")
+	errModifiedSince = errors.New("markdown document modified after the last rewrite")
+)
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_undoRun_other_directory(t *testing.T) {
+	dir := t.TempDir()
+	doc := filepath.Join(dir, "docs", "doc.md")
+	other := filepath.Join(dir, "other")
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(doc), 0o750))
+	require.NoError(t, os.MkdirAll(other, 0o750))
+	require.NoError(t, os.WriteFile(doc, []byte("first\n"), 0o600))
+
+	require.NoError(t, backupWrite(doc, []byte("second\n")))
+	require.NoError(t, backupWrite(doc, []byte("third\n")))
+
+	require.DirExists(t, backupDir(doc))
+	require.NoDirExists(t, filepath.Join(other, stateDir))
+
+	out, err := runMdcode(t, other, "undo", doc)
+	require.NoError(t, err, out)
+
+	data, err := os.ReadFile(doc)
+	require.NoError(t, err)
+	require.Equal(t, "second\n", string(data))
+
+	out, err = runMdcode(t, filepath.Dir(doc), "undo", "doc.md")
+	require.NoError(t, err, out)
+
+	data, err = os.ReadFile(doc)
+	require.NoError(t, err)
+	require.Equal(t, "first\n", string(data))
+
+	require.NoDirExists(t, filepath.Join(other, stateDir))
+	require.NoFileExists(t, filepath.Join(backupDir(doc), "."+backupJournal+lockSuffix))
+}
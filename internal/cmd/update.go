@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"bytes"
 	_ "embed"
 	"errors"
 	"fmt"
@@ -26,7 +27,18 @@ func updateCmd(opts *options) *cobra.Command {
 			opts.createStatus(cmd.ErrOrStderr())
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return updateRun(source(args), opts)
+			files, err := sources(args, opts)
+			if err != nil {
+				return err
+			}
+
+			return opts.withState(func() error {
+				return opts.eachSource(files, func(file string) error {
+					documentDir(cmd, opts, file)
+
+					return updateRun(file, opts)
+				})
+			})
 		},
 
 		DisableAutoGenTag: true,
@@ -38,37 +50,87 @@ func updateCmd(opts *options) *cobra.Command {
 	return cmd
 }
 
-func updateRun(filename string, opts *options) error {
+func updateRun(filename string, opts *options) (err error) {
 	opts.status("Updating code blocks in %s\n", filename)
 
-	src, err := os.ReadFile(filename)
+	lock, err := lockDocument(filename)
 	if err != nil {
 		return err
 	}
 
+	defer func() {
+		err = errors.Join(err, lock.unlock())
+	}()
+
+	src, format, err := opts.readDocument(filename)
+	if err != nil {
+		return err
+	}
+
+	eol := opts.lineEnding(src)
+
 	modified, res, e := walk(src, func(block *mdcode.Block) error {
-		return load(block, opts.dir, opts.status)
+		code := block.Code
+
+		if err := opts.confine(block); err != nil {
+			return err
+		}
+
+		if err := load(block, opts.dir, opts.status); err != nil {
+			return err
+		}
+
+		block.Code = convertEOL(block.Code, eol)
+
+		if file := block.Meta.Get(metaFile); len(file) != 0 && !isPatch(block.Lang) {
+			var merged bool
+
+			block.Code, merged = opts.reconcile(filename, block, rel(opts.dir, filepath.FromSlash(file)), code, block.Code,
+				filename, file)
+
+			// The file gets the changes of the document too.
+			if merged {
+				if err := save(block, opts.dir, nostatus); err != nil {
+					return err
+				}
+			}
+		}
+
+		if file := block.Meta.Get(metaFile); len(file) != 0 {
+			opts.event("block loaded", "document", filename, "line", block.StartLine, "file", file,
+				"updated", !bytes.Equal(code, block.Code))
+		}
+
+		return nil
 	}, opts.filter)
 	if e != nil {
 		return e
 	}
 
 	if modified {
-		return os.WriteFile(filename, res, fileMode)
+		return writeDocument(filename, res, format)
 	}
 
 	return nil
 }
 
 func load(block *mdcode.Block, dir string, status statusFunc) error {
+	return loadWith(block, dir, status, os.ReadFile)
+}
+
+// readFunc reads the content of a file, from the disk or from the git index.
+type readFunc func(filename string) ([]byte, error)
+
+// loadWith loads the code block from its file read by read.
+func loadWith(block *mdcode.Block, dir string, status statusFunc, read readFunc) error {
 	filename := block.Meta.Get(metaFile)
-	if len(filename) == 0 {
+	if len(filename) == 0 || isPatch(block.Lang) {
 		return nil
 	}
 
 	filename = rel(dir, filepath.FromSlash(filename))
 
-	code, err := os.ReadFile(filename)
+	code, err := read(filename)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ezerfernandes/mdcode/internal/mdcode"
+	"github.com/ezerfernandes/mdcode/internal/validate"
+	"github.com/spf13/cobra"
+)
+
+//go:embed help/validate.md
+var validateHelp string
+
+func validateCmd(opts *options) *cobra.Command {
+	cmd := &cobra.Command{ //nolint:exhaustruct
+		Use:   "validate [flags] [filename]",
+		Short: "Check the syntax of JSON, YAML, TOML and XML code blocks",
+		Long:  validateHelp,
+		Args:  checkargs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			opts.createStatus(cmd.ErrOrStderr())
+
+			return opts.allBlocksFilter(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files, err := sources(args, opts)
+			if err != nil {
+				return err
+			}
+
+			var count int
+
+			err = opts.eachSource(files, func(file string) error {
+				n, err := validateRun(file, cmd.OutOrStdout(), opts)
+				count += n
+
+				return err
+			})
+			if err != nil {
+				return err
+			}
+
+			if count != 0 {
+				return fmt.Errorf("%w: %d code block(s)", errInvalidBlock, count)
+			}
+
+			return nil
+		},
+
+		DisableAutoGenTag: true,
+	}
+
+	quietFlag(cmd, opts)
+
+	return cmd
+}
+
+// validateRun checks the syntax of the structured data code blocks of the
+// markdown document, reports the syntax errors with the document line and
+// returns the number of invalid code blocks.
+func validateRun(filename string, out io.Writer, opts *options) (int, error) {
+	opts.status("Validating code blocks in %s\n", filename)
+
+	src, _, err := opts.readDocument(filename)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+
+	_, _, err = walk(src, func(block *mdcode.Block) error {
+		lang := opts.aliases.canonical(block.Lang)
+		if !validate.Supported(lang) {
+			return nil
+		}
+
+		var verr *validate.Error
+
+		err := validate.Check(lang, block.Code)
+		if errors.As(err, &verr) {
+			count++
+
+			fmt.Fprintf(out, "%s: %s: %s\n", opts.location(filename, block.StartLine+verr.Line), lang, verr.Msg)
+			opts.event("block invalid", "document", filename, "line", block.StartLine+verr.Line, "lang", lang, "error", verr.Msg)
+
+			return nil
+		}
+
+		return err
+	}, opts.filter)
+
+	return count, err
+}
+
+var errInvalidBlock = errors.New("invalid code blocks")
@@ -0,0 +1,166 @@
+// Package diff3 merges the changes of two versions of a text made from a
+// common base version, line by line, like the diff3 and git merge-file
+// programs do.
+//
+// Where both versions changed the same lines differently, the result holds
+// both changes between conflict markers.
+package diff3
+
+import "bytes"
+
+// Conflict markers of the merged text.
+const (
+	MarkerOurs   = "<<<<<<<"
+	MarkerSep    = "======="
+	MarkerTheirs = ">>>>>>>"
+)
+
+// Merge merges the changes made in ours and theirs to base. The labels are
+// written after the opening (ours) and closing (theirs) conflict markers.
+// It reports whether the result has conflicts.
+func Merge(base, ours, theirs []byte, oursLabel, theirsLabel string) ([]byte, bool) {
+	baseLines, oursLines, theirsLines := split(base), split(ours), split(theirs)
+
+	matchOurs := match(baseLines, oursLines)
+	matchTheirs := match(baseLines, theirsLines)
+
+	var (
+		out      bytes.Buffer
+		conflict bool
+	)
+
+	idx, ourIdx, theirIdx := 0, 0, 0
+
+	for idx < len(baseLines) || ourIdx < len(oursLines) || theirIdx < len(theirsLines) {
+		if idx < len(baseLines) && matchOurs[idx] == ourIdx && matchTheirs[idx] == theirIdx {
+			out.Write(baseLines[idx])
+
+			idx, ourIdx, theirIdx = idx+1, ourIdx+1, theirIdx+1
+
+			continue
+		}
+
+		// unstable chunk up to the next base line kept by both versions
+		end, ourEnd, theirEnd := idx, len(oursLines), len(theirsLines)
+
+		for ; end < len(baseLines); end++ {
+			if matchOurs[end] >= 0 && matchTheirs[end] >= 0 {
+				ourEnd, theirEnd = matchOurs[end], matchTheirs[end]
+
+				break
+			}
+		}
+
+		baseChunk := baseLines[idx:end]
+		ourChunk := oursLines[ourIdx:ourEnd]
+		theirChunk := theirsLines[theirIdx:theirEnd]
+
+		switch {
+		case equal(ourChunk, baseChunk), equal(ourChunk, theirChunk):
+			write(&out, theirChunk)
+		case equal(theirChunk, baseChunk):
+			write(&out, ourChunk)
+		default:
+			conflict = true
+
+			marker(&out, MarkerOurs, oursLabel)
+			write(&out, ourChunk)
+			marker(&out, MarkerSep, "")
+			write(&out, theirChunk)
+			marker(&out, MarkerTheirs, theirsLabel)
+		}
+
+		idx, ourIdx, theirIdx = end, ourEnd, theirEnd
+	}
+
+	return out.Bytes(), conflict
+}
+
+// split splits the text into lines, keeping their line endings.
+func split(text []byte) [][]byte {
+	if len(text) == 0 {
+		return nil
+	}
+
+	lines := bytes.SplitAfter(text, []byte("\n"))
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// match returns, for each line of base, the index of the matching line of
+// other by a longest common subsequence, or -1 if the line was removed.
+func match(base, other [][]byte) []int {
+	lcs := make([][]int, len(base)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(other)+1)
+	}
+
+	for i := len(base) - 1; i >= 0; i-- {
+		for j := len(other) - 1; j >= 0; j-- {
+			if bytes.Equal(base[i], other[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	matches := make([]int, len(base))
+
+	i, j := 0, 0
+
+	for i < len(base) {
+		switch {
+		case j < len(other) && bytes.Equal(base[i], other[j]):
+			matches[i] = j
+			i, j = i+1, j+1
+		case j < len(other) && lcs[i][j+1] >= lcs[i+1][j]:
+			j++
+		default:
+			matches[i] = -1
+			i++
+		}
+	}
+
+	return matches
+}
+
+func equal(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for idx := range a {
+		if !bytes.Equal(a[idx], b[idx]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// write writes the lines of a chunk.
+func write(out *bytes.Buffer, lines [][]byte) {
+	for _, line := range lines {
+		out.Write(line)
+	}
+}
+
+// marker writes a conflict marker line, completing the missing line ending
+// of the previous line, so that the marker starts on its own line.
+func marker(out *bytes.Buffer, marker, label string) {
+	if out.Len() != 0 && out.Bytes()[out.Len()-1] != '\n' {
+		out.WriteByte('\n')
+	}
+
+	out.WriteString(marker)
+
+	if len(label) != 0 {
+		out.WriteString(" " + label)
+	}
+
+	out.WriteByte('\n')
+}
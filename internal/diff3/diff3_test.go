@@ -0,0 +1,87 @@
+package diff3_test
+
+import (
+	"testing"
+
+	"github.com/ezerfernandes/mdcode/internal/diff3"
+	"github.com/stretchr/testify/require"
+)
+
+const base = "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n"
+
+func Test_Merge(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		ours   string
+		theirs string
+		want   string
+	}{
+		{
+			name:   "unchanged",
+			ours:   base,
+			theirs: base,
+			want:   base,
+		},
+		{
+			name:   "ours",
+			ours:   "// Package main.\n" + base,
+			theirs: base,
+			want:   "// Package main.\n" + base,
+		},
+		{
+			name:   "theirs",
+			ours:   base,
+			theirs: "package main\n\nfunc main() {\n\tprintln(\"hello, world\")\n}\n",
+			want:   "package main\n\nfunc main() {\n\tprintln(\"hello, world\")\n}\n",
+		},
+		{
+			name:   "both",
+			ours:   "// Package main.\n" + base,
+			theirs: "package main\n\nfunc main() {\n\tprintln(\"hello, world\")\n}\n",
+			want:   "// Package main.\npackage main\n\nfunc main() {\n\tprintln(\"hello, world\")\n}\n",
+		},
+		{
+			name:   "same change",
+			ours:   "package main\n\nfunc main() {\n}\n",
+			theirs: "package main\n\nfunc main() {\n}\n",
+			want:   "package main\n\nfunc main() {\n}\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, conflict := diff3.Merge([]byte(base), []byte(test.ours), []byte(test.theirs), "ours", "theirs")
+
+			require.False(t, conflict)
+			require.Equal(t, test.want, string(got))
+		})
+	}
+}
+
+func Test_Merge_conflict(t *testing.T) {
+	t.Parallel()
+
+	ours := "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n"
+	theirs := "package main\n\nfunc main() {\n\tprintln(\"bye\")\n}\n"
+
+	got, conflict := diff3.Merge([]byte(base), []byte(ours), []byte(theirs), "README.md", "main.go")
+
+	require.True(t, conflict)
+	require.Equal(t, "package main\n\nfunc main() {\n"+
+		"<<<<<<< README.md\n\tprintln(\"hi\")\n=======\n\tprintln(\"bye\")\n>>>>>>> main.go\n}\n", string(got))
+}
+
+func Test_Merge_no_eol(t *testing.T) {
+	t.Parallel()
+
+	got, conflict := diff3.Merge([]byte("a\nb"), []byte("a\nc"), []byte("a\nd"), "ours", "theirs")
+
+	require.True(t, conflict)
+	require.Equal(t, "a\n<<<<<<< ours\nc\n=======\nd\n>>>>>>> theirs\n", string(got))
+}
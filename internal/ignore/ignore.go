@@ -0,0 +1,166 @@
+// Package ignore matches paths against ignore files written in gitignore syntax.
+package ignore
+
+import (
+	"bufio"
+	"bytes"
+	"path"
+	"regexp"
+	"strings"
+)
+
+type rule struct {
+	base    string
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// List is an ordered set of ignore rules collected from one or more ignore
+// files. Rules added later take precedence over earlier ones.
+type List struct {
+	rules []*rule
+}
+
+// Add parses the content of an ignore file located in the base directory.
+// The base directory is a slash separated path relative to the root of the
+// walked tree ("" or "." for the root itself).
+func (l *List) Add(base string, data []byte) error {
+	base = strings.Trim(path.Clean("/"+base), "/")
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for scanner.Scan() {
+		r, err := parseLine(base, scanner.Text())
+		if err != nil {
+			return err
+		}
+
+		if r != nil {
+			l.rules = append(l.rules, r)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// Ignored reports whether the slash separated path (relative to the root of
+// the walked tree) is ignored.
+func (l *List) Ignored(name string, isDir bool) bool {
+	ignored := false
+
+	for _, r := range l.rules {
+		rel := name
+
+		if len(r.base) != 0 {
+			var ok bool
+
+			if rel, ok = strings.CutPrefix(name, r.base+"/"); !ok {
+				continue
+			}
+		}
+
+		if r.dirOnly && !isDir {
+			continue
+		}
+
+		if r.re.MatchString(rel) {
+			ignored = !r.negate
+		}
+	}
+
+	return ignored
+}
+
+func parseLine(base, line string) (*rule, error) {
+	line = trimTrailingSpace(line)
+
+	if len(line) == 0 || line[0] == '#' {
+		return nil, nil //nolint:nilnil
+	}
+
+	r := &rule{base: base, re: nil, negate: false, dirOnly: false}
+
+	if line[0] == '!' {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+
+	if len(line) == 0 {
+		return nil, nil //nolint:nilnil
+	}
+
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	expr := translate(line)
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return nil, err
+	}
+
+	r.re = re
+
+	return r, nil
+}
+
+func trimTrailingSpace(line string) string {
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+
+	return line
+}
+
+// translate converts a gitignore pattern to a regular expression.
+func translate(pattern string) string {
+	var buff strings.Builder
+
+	for idx := 0; idx < len(pattern); idx++ {
+		switch char := pattern[idx]; {
+		case strings.HasPrefix(pattern[idx:], "**/"):
+			buff.WriteString("(?:.*/)?")
+			idx += 2
+		case strings.HasPrefix(pattern[idx:], "**"):
+			buff.WriteString(".*")
+			idx++
+		case char == '*':
+			buff.WriteString("[^/]*")
+		case char == '?':
+			buff.WriteString("[^/]")
+		case char == '[':
+			end := strings.IndexByte(pattern[idx+1:], ']')
+			if end < 0 {
+				buff.WriteString(`\[`)
+
+				continue
+			}
+
+			class := pattern[idx+1 : idx+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+
+			buff.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			idx += end + 1
+		case char == '\\' && idx+1 < len(pattern):
+			idx++
+			buff.WriteString(regexp.QuoteMeta(pattern[idx : idx+1]))
+		default:
+			buff.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+
+	return buff.String()
+}
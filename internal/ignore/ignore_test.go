@@ -0,0 +1,60 @@
+package ignore_test
+
+import (
+	"testing"
+
+	"github.com/ezerfernandes/mdcode/internal/ignore"
+	"github.com/stretchr/testify/require"
+)
+
+const rules = `
+# generated documents
+CHANGELOG.md
+/build/
+docs/api/*.md
+**/third_party/**
+*.gen.md
+!keep.gen.md
+\#hash.md
+`
+
+func Test_List_Ignored(t *testing.T) {
+	t.Parallel()
+
+	var list ignore.List
+
+	require.NoError(t, list.Add("", []byte(rules)))
+	require.NoError(t, list.Add("sub", []byte("local.md\n")))
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{path: "README.md", want: false},
+		{path: "CHANGELOG.md", want: true},
+		{path: "nested/CHANGELOG.md", want: true},
+		{path: "build", isDir: true, want: true},
+		{path: "nested/build", isDir: true, want: false},
+		{path: "build", isDir: false, want: false},
+		{path: "docs/api/index.md", want: true},
+		{path: "docs/api/v1/index.md", want: false},
+		{path: "a/third_party/b/c.md", want: true},
+		{path: "third_party/c.md", want: true},
+		{path: "x.gen.md", want: true},
+		{path: "dir/keep.gen.md", want: false},
+		{path: "#hash.md", want: true},
+		{path: "sub/local.md", want: true},
+		{path: "other/local.md", want: false},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.path, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, test.want, list.Ignored(test.path, test.isDir))
+		})
+	}
+}
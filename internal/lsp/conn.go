@@ -0,0 +1,157 @@
+// Package lsp implements the JSON-RPC transport and the subset of the
+// Language Server Protocol used by the mdcode language server.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"sync"
+)
+
+// Message is a JSON-RPC 2.0 request, notification or response.
+type Message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *ResponseError   `json:"error,omitempty"`
+}
+
+// IsRequest reports whether the message expects a response.
+func (m *Message) IsRequest() bool {
+	return m.ID != nil && len(m.Method) != 0
+}
+
+// IsNotification reports whether the message is a notification.
+func (m *Message) IsNotification() bool {
+	return m.ID == nil && len(m.Method) != 0
+}
+
+// ResponseError is the error object of a JSON-RPC response.
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.Code)
+}
+
+// JSON-RPC error codes.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Conn reads and writes Content-Length framed JSON-RPC messages.
+type Conn struct {
+	reader *textproto.Reader
+	writer io.Writer
+	mu     sync.Mutex
+	nextID int
+}
+
+// NewConn creates a connection reading from r and writing to w.
+func NewConn(r io.Reader, w io.Writer) *Conn {
+	return &Conn{reader: textproto.NewReader(bufio.NewReader(r)), writer: w} //nolint:exhaustruct
+}
+
+// Read returns the next message from the connection.
+func (c *Conn) Read() (*Message, error) {
+	header, err := c.reader.ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("%w: %q", ErrContentLength, header.Get("Content-Length"))
+	}
+
+	body := make([]byte, length)
+
+	if _, err = io.ReadFull(c.reader.R, body); err != nil {
+		return nil, err
+	}
+
+	msg := new(Message)
+
+	if err = json.Unmarshal(body, msg); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+func (c *Conn) write(msg *Message) error {
+	msg.JSONRPC = "2.0"
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err = fmt.Fprintf(c.writer, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+
+	_, err = c.writer.Write(body)
+
+	return err
+}
+
+// Reply sends the result of the request with the given id.
+func (c *Conn) Reply(id *json.RawMessage, result any) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	return c.write(&Message{ID: id, Result: data}) //nolint:exhaustruct
+}
+
+// ReplyError sends an error response to the request with the given id.
+func (c *Conn) ReplyError(id *json.RawMessage, code int, message string) error {
+	return c.write(&Message{ID: id, Error: &ResponseError{Code: code, Message: message}}) //nolint:exhaustruct
+}
+
+// Notify sends a notification to the peer.
+func (c *Conn) Notify(method string, params any) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	return c.write(&Message{Method: method, Params: data}) //nolint:exhaustruct
+}
+
+// Call sends a request to the peer without waiting for the response.
+func (c *Conn) Call(method string, params any) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.nextID++
+	id := json.RawMessage(strconv.Itoa(c.nextID))
+	c.mu.Unlock()
+
+	return c.write(&Message{ID: &id, Method: method, Params: data}) //nolint:exhaustruct
+}
+
+// ErrContentLength is returned by [Conn.Read] when the message header has no
+// valid Content-Length field.
+var ErrContentLength = errors.New("invalid Content-Length header")
@@ -0,0 +1,69 @@
+package lsp_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/ezerfernandes/mdcode/internal/lsp"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Conn_roundtrip(t *testing.T) {
+	t.Parallel()
+
+	var buff bytes.Buffer
+
+	conn := lsp.NewConn(&buff, &buff)
+
+	require.NoError(t, conn.Notify("window/logMessage", lsp.ShowMessageParams{Type: lsp.MessageLog, Message: "hello"}))
+	require.NoError(t, conn.Call("workspace/applyEdit", map[string]string{}))
+
+	id := json.RawMessage("42")
+	require.NoError(t, conn.Reply(&id, []int{1, 2}))
+	require.NoError(t, conn.ReplyError(&id, lsp.CodeMethodNotFound, "nope"))
+
+	msg, err := conn.Read()
+	require.NoError(t, err)
+	require.True(t, msg.IsNotification())
+	require.Equal(t, "window/logMessage", msg.Method)
+	require.JSONEq(t, `{"type":4,"message":"hello"}`, string(msg.Params))
+
+	msg, err = conn.Read()
+	require.NoError(t, err)
+	require.True(t, msg.IsRequest())
+	require.Equal(t, "1", string(*msg.ID))
+
+	msg, err = conn.Read()
+	require.NoError(t, err)
+	require.Equal(t, "[1,2]", string(msg.Result))
+
+	msg, err = conn.Read()
+	require.NoError(t, err)
+	require.NotNil(t, msg.Error)
+	require.Equal(t, lsp.CodeMethodNotFound, msg.Error.Code)
+}
+
+func Test_Conn_invalid_header(t *testing.T) {
+	t.Parallel()
+
+	conn := lsp.NewConn(strings.NewReader("Content-Length: x\r\n\r\n{}"), nil)
+
+	_, err := conn.Read()
+	require.ErrorIs(t, err, lsp.ErrContentLength)
+}
+
+func Test_URI(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+
+	uri := lsp.PathToURI("/tmp/some dir/README.md")
+
+	require.Equal(t, "file:///tmp/some%20dir/README.md", uri)
+	require.Equal(t, "/tmp/some dir/README.md", lsp.URIToPath(uri))
+}
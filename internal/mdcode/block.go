@@ -8,6 +8,38 @@ type Block struct {
 	Code      []byte
 	StartLine int
 	EndLine   int
+	// Hidden is set for invisible code blocks wrapped in a <script> element
+	// or an <!-- mdcode --> HTML comment.
+	Hidden bool
+	// Fence is the opening fence (e.g. ``` or ~~~~) and Info the info string
+	// of the code block, as written in the document. The walker may modify
+	// them to rewrite the fence lines.
+	Fence string
+	Info  string
+	// Virtual is set for the code block standing for an
+	// <!-- mdcode:include --> directive. It has no fences in the document,
+	// its modifications are ignored, except removal and inserted text,
+	// which apply to the directive.
+	Virtual bool
+	// Headings is the heading path of the code block: the text of the
+	// enclosing section headings, from the top level down.
+	Headings []string
+
+	removed bool
+	after   []byte
+}
+
+// Remove marks the code block for removal by [Walk], along with its fences
+// (and the HTML element wrapping an invisible code block).
+func (b *Block) Remove() {
+	b.removed = true
+}
+
+// InsertAfter appends markdown text to be inserted by [Walk] after the code
+// block (after its closing fence). The text is inserted verbatim, it should
+// end with a newline.
+func (b *Block) InsertAfter(text []byte) {
+	b.after = append(b.after, text...)
 }
 
 // Blocks is a slice of code blocks extracted from a Markdown document.
@@ -0,0 +1,102 @@
+package mdcode
+
+import (
+	"bytes"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	defaultLang       = "lang"
+	defaultFilePrefix = "file-prefix"
+
+	frontMatterKey = "mdcode:"
+)
+
+var reFrontMatter = regexp.MustCompile(`^---\r?\n`)
+
+// frontMatterDefaults returns the defaults given in the front matter of the
+// document with the mdcode key, in the same format as the info string
+// metadata:
+//
+//	---
+//	title: Examples
+//	mdcode: lang=go file-prefix=examples/
+//	---
+func frontMatterDefaults(source []byte) (Meta, error) {
+	loc := reFrontMatter.FindIndex(source)
+	if loc == nil {
+		return nil, nil
+	}
+
+	for offset := loc[1]; offset < len(source); offset = lineEnd(source, offset) {
+		line := bytes.TrimRight(source[offset:lineEnd(source, offset)], "\r\n")
+
+		if string(line) == "---" || string(line) == "..." {
+			break
+		}
+
+		if value, found := strings.CutPrefix(string(line), frontMatterKey); found {
+			return parseMeta([]byte(unquote(strings.TrimSpace(value))))
+		}
+	}
+
+	return nil, nil
+}
+
+func unquote(value string) string {
+	if len(value) > 1 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+
+	return value
+}
+
+// mergeDefaults returns the defaults with the values of later overriding
+// the values of earlier.
+func mergeDefaults(earlier, later Meta) Meta {
+	merged := make(Meta, len(earlier)+len(later))
+
+	for k, v := range earlier {
+		merged[k] = v
+	}
+
+	for k, v := range later {
+		merged[k] = v
+	}
+
+	return merged
+}
+
+// applyDefaults completes the language and metadata of the block with the
+// document defaults. Values given in the info string take precedence.
+func applyDefaults(block *Block, defaults Meta) {
+	if len(defaults) == 0 {
+		return
+	}
+
+	if block.Meta == nil {
+		block.Meta = make(Meta)
+	}
+
+	if len(block.Lang) == 0 {
+		block.Lang = defaults.Get(defaultLang)
+	}
+
+	for key, value := range defaults {
+		if key == defaultLang || key == defaultFilePrefix {
+			continue
+		}
+
+		if _, has := block.Meta[key]; !has {
+			block.Meta[key] = value
+		}
+	}
+
+	if prefix := defaults.Get(defaultFilePrefix); len(prefix) != 0 {
+		if file := block.Meta.Get("file"); len(file) != 0 && !path.IsAbs(file) {
+			block.Meta["file"] = path.Join(prefix, file)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package mdcode
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+const (
+	directiveDefaults = "defaults"
+	directiveSkipNext = "skip-next"
+	directiveCmd      = "cmd"
+	directiveEnd      = "end"
+	directiveInclude  = "include"
+
+	// MetaSkip is the metadata set on the code block following a
+	// <!-- mdcode:skip-next --> directive.
+	MetaSkip = "skip"
+	// MetaCmd is the metadata holding the command of a
+	// <!-- mdcode:cmd ... --> directive, set on the code blocks up to the
+	// next <!-- mdcode:end --> directive.
+	MetaCmd = "cmd"
+	// MetaInclude is the metadata referencing the code block included in
+	// place of the code (document#name). It is set on the virtual code block
+	// of an <!-- mdcode:include document#name --> directive.
+	MetaInclude = "include"
+)
+
+var reDirective = regexp.MustCompile(`^\s*<!--\s*mdcode:\s*([\w-]+)(\s.*?)?\s*-->\s*$`)
+
+// directives holds the state of the in-document directives, which modify the
+// handling of the code blocks below them.
+type directives struct {
+	defaults Meta
+	skipNext bool
+	cmd      string
+}
+
+// parseDirective returns the name and the argument of an
+// <!-- mdcode:name argument --> directive comment. The last result is false
+// if node is not a directive.
+func parseDirective(node ast.Node, entering bool, source []byte) (string, string, bool) {
+	if entering || node.Kind() != ast.KindHTMLBlock {
+		return "", "", false
+	}
+
+	html, ok := node.(*ast.HTMLBlock)
+	if !ok || html.Lines().Len() != 1 {
+		return "", "", false
+	}
+
+	seg := html.Lines().At(0)
+
+	all := reDirective.FindSubmatch(seg.Value(source))
+	if all == nil {
+		return "", "", false
+	}
+
+	return string(all[1]), strings.TrimSpace(string(all[2])), true
+}
+
+func (d *directives) apply(name, arg string) error {
+	switch name {
+	case directiveDefaults:
+		meta, err := parseMeta([]byte(arg))
+		if err != nil {
+			return err
+		}
+
+		d.defaults = mergeDefaults(d.defaults, meta)
+	case directiveSkipNext:
+		d.skipNext = true
+	case directiveCmd:
+		if len(arg) == 0 {
+			return fmt.Errorf("%w: %s requires a command", ErrDirective, name)
+		}
+
+		d.cmd = arg
+	case directiveEnd:
+		d.cmd = ""
+	case directiveInclude:
+		if len(arg) == 0 {
+			return fmt.Errorf("%w: %s requires a document#name reference", ErrDirective, name)
+		}
+	default:
+		return fmt.Errorf("%w: %s", ErrDirective, name)
+	}
+
+	return nil
+}
+
+// block applies the directives to the code block following them.
+func (d *directives) block(block *Block) {
+	applyDefaults(block, d.defaults)
+
+	if !d.skipNext && len(d.cmd) == 0 {
+		return
+	}
+
+	if block.Meta == nil {
+		block.Meta = make(Meta)
+	}
+
+	if d.skipNext {
+		block.Meta[MetaSkip] = "true"
+		d.skipNext = false
+	}
+
+	if _, has := block.Meta[MetaCmd]; !has && len(d.cmd) != 0 {
+		block.Meta[MetaCmd] = d.cmd
+	}
+}
+
+// walkInclude calls the walker with the virtual code block of an
+// <!-- mdcode:include --> directive. The walker may remove the directive or
+// insert text after it, the changes are returned.
+func walkInclude(d *directives, ref string, node ast.Node, source []byte, walker Walker, headings []string) ([]*change, error) {
+	seg := node.Lines().At(0)
+
+	block := &Block{ //nolint:exhaustruct
+		Meta:      Meta{MetaInclude: ref},
+		StartLine: lineAt(source, seg.Start),
+		EndLine:   lineAt(source, seg.Start),
+		Virtual:   true,
+		Headings:  headings,
+	}
+
+	d.block(block)
+
+	if err := walker(block); err != nil {
+		return nil, err
+	}
+
+	if !block.removed && len(block.after) == 0 {
+		return nil, nil
+	}
+
+	start, stop := seg.Start, seg.Stop
+	if !block.removed {
+		start = stop
+	}
+
+	return []*change{{start: start, stop: stop, data: block.after}}, nil
+}
+
+// ErrDirective is returned for an unknown or invalid <!-- mdcode:... -->
+// directive.
+var ErrDirective = errors.New("invalid mdcode directive")
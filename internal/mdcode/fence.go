@@ -0,0 +1,62 @@
+package mdcode
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// openingFence returns the offset of the opening fence of the code block
+// (after the indentation, list marker or blockquote marker on its line).
+func openingFence(fcb *ast.FencedCodeBlock, source []byte) (int, bool) {
+	var start int
+
+	switch lines := fcb.Lines(); {
+	case fcb.Info != nil:
+		start = lineStart(source, fcb.Info.Segment.Start)
+	case lines.Len() != 0:
+		start = lineStart(source, lineStart(source, lines.At(0).Start)-1)
+	default:
+		return 0, false
+	}
+
+	line := source[start:lineEnd(source, start)]
+
+	idx := bytes.IndexAny(line, "`~")
+	if idx < 0 {
+		return 0, false
+	}
+
+	return start + idx, true
+}
+
+// refence places the changes rewriting the opening fence line with the fence
+// and info string of the block, and the closing fence with the same fence,
+// around the code changes.
+func refence(changes []*change, block *Block, fcb *ast.FencedCodeBlock, source []byte) []*change {
+	start, ok := openingFence(fcb, source)
+	if !ok || len(block.Fence) == 0 {
+		return changes
+	}
+
+	stop := lineEnd(source, start)
+	stop = start + len(bytes.TrimRight(source[start:stop], "\r\n"))
+
+	refenced := append([]*change{{start: start, stop: stop, data: []byte(block.Fence + block.Info)}}, changes...)
+
+	_, blockStop, err := blockBounds(fcb, fcb, source)
+	if err != nil {
+		return refenced
+	}
+
+	if _, codeStop := codeBounds(fcb); blockStop > codeStop {
+		closing := source[codeStop:blockStop]
+		if idx := bytes.IndexAny(closing, "`~"); idx >= 0 {
+			refenced = append(refenced, &change{
+				start: codeStop + idx, stop: codeStop + idx + fenceRun(closing[idx:], closing[idx]), data: []byte(block.Fence),
+			})
+		}
+	}
+
+	return refenced
+}
@@ -0,0 +1,140 @@
+package mdcode
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gobwas/glob"
+)
+
+// BlockPredicate reports whether a code block is selected. Predicates can be
+// composed with [And], [Or] and [Not].
+type BlockPredicate func(block *Block) bool
+
+// metaFile is the metadata key of the file name, whose patterns don't match
+// path separators with a single *.
+const metaFile = "file"
+
+// All is the predicate selecting every code block.
+func All(*Block) bool {
+	return true
+}
+
+// IsHidden is the predicate selecting the invisible code blocks.
+func IsHidden(block *Block) bool {
+	return block.Hidden
+}
+
+// IsSkipped is the predicate selecting the code blocks with true [MetaSkip]
+// metadata (set by the <!-- mdcode:skip-next --> directive).
+func IsSkipped(block *Block) bool {
+	return block.Meta.Get(MetaSkip) == "true"
+}
+
+// ByLang returns the predicate selecting the code blocks with a language
+// matching one of the glob patterns. Without patterns every code block is
+// selected.
+func ByLang(patterns ...string) (BlockPredicate, error) {
+	match, err := compileGlobs(false, patterns...)
+	if err != nil || match == nil {
+		return All, err
+	}
+
+	return func(block *Block) bool {
+		return match.Match(block.Lang)
+	}, nil
+}
+
+// ByMeta returns the predicate selecting the code blocks having the key
+// metadata with a value matching one of the glob patterns. In the patterns of
+// the file key, * doesn't match path separators (** does). Without patterns
+// every code block is selected.
+func ByMeta(key string, patterns ...string) (BlockPredicate, error) {
+	match, err := compileGlobs(key == metaFile, patterns...)
+	if err != nil || match == nil {
+		return All, err
+	}
+
+	return func(block *Block) bool {
+		value, has := block.Meta[key]
+
+		return has && match.Match(fmt.Sprint(value))
+	}, nil
+}
+
+// ByHeading returns the predicate selecting the code blocks in a section
+// with a heading matching one of the glob patterns (case-insensitively).
+// The headings of the enclosing sections match too. Without patterns every
+// code block is selected.
+func ByHeading(patterns ...string) (BlockPredicate, error) {
+	lower := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		lower = append(lower, strings.ToLower(pattern))
+	}
+
+	match, err := compileGlobs(false, lower...)
+	if err != nil || match == nil {
+		return All, err
+	}
+
+	return func(block *Block) bool {
+		for _, heading := range block.Headings {
+			if match.Match(strings.ToLower(heading)) {
+				return true
+			}
+		}
+
+		return false
+	}, nil
+}
+
+// And returns the predicate selecting the code blocks selected by all the
+// predicates.
+func And(preds ...BlockPredicate) BlockPredicate {
+	return func(block *Block) bool {
+		for _, pred := range preds {
+			if !pred(block) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// Or returns the predicate selecting the code blocks selected by any of the
+// predicates.
+func Or(preds ...BlockPredicate) BlockPredicate {
+	return func(block *Block) bool {
+		for _, pred := range preds {
+			if pred(block) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// Not returns the predicate selecting the code blocks not selected by pred.
+func Not(pred BlockPredicate) BlockPredicate {
+	return func(block *Block) bool {
+		return !pred(block)
+	}
+}
+
+// compileGlobs compiles the alternatives of the patterns, a nil matcher is
+// returned without patterns.
+func compileGlobs(path bool, patterns ...string) (glob.Glob, error) { //nolint:ireturn
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+
+	var separators []rune
+
+	if path {
+		separators = append(separators, '/', '\\')
+	}
+
+	return glob.Compile(fmt.Sprintf("{%s}", strings.Join(patterns, ",")), separators...)
+}
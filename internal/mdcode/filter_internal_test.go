@@ -0,0 +1,64 @@
+package mdcode
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_BlockPredicate(t *testing.T) {
+	t.Parallel()
+
+	src := "<!-- mdcode:skip-next -->\n\n```sh\nskipped\n```\n\n" +
+		"# Install\n\n## Linux\n\n```sh file=scripts/install.sh\nmake\n```\n\n" +
+		"# Usage\n\n```go file=main.go\npackage main\n```\n"
+
+	var blocks Blocks
+
+	_, _, err := Walk([]byte(src), func(block *Block) error {
+		blocks = append(blocks, block)
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, blocks, 3)
+
+	selected := func(pred BlockPredicate) []int {
+		var lines []int
+
+		for _, block := range blocks {
+			if pred(block) {
+				lines = append(lines, block.StartLine)
+			}
+		}
+
+		return lines
+	}
+
+	byLang, err := ByLang("s?")
+	require.NoError(t, err)
+	require.Equal(t, []int{3, 11}, selected(byLang))
+
+	byFile, err := ByMeta("file", "*.sh")
+	require.NoError(t, err)
+	require.Empty(t, selected(byFile))
+
+	byFile, err = ByMeta("file", "**.sh", "main.go")
+	require.NoError(t, err)
+	require.Equal(t, []int{11, 17}, selected(byFile))
+
+	byHeading, err := ByHeading("install")
+	require.NoError(t, err)
+	require.Equal(t, []int{11}, selected(byHeading))
+
+	require.Equal(t, []int{11}, selected(And(byLang, Not(IsSkipped))))
+	require.Equal(t, []int{3, 17}, selected(Or(IsSkipped, Not(byLang))))
+	require.Equal(t, []int{3, 11, 17}, selected(And()))
+
+	all, err := ByLang()
+	require.NoError(t, err)
+	require.Len(t, selected(all), 3)
+
+	_, err = ByMeta("file", "[")
+	require.Error(t, err)
+}
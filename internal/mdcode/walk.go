@@ -2,6 +2,8 @@ package mdcode
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"regexp"
 
 	"github.com/yuin/goldmark"
@@ -12,64 +14,214 @@ import (
 var reInfo = regexp.MustCompile(`\s*(\w+)\s*(.*)\s*`)
 
 // Walker is a callback invoked for each fenced code block found in a Markdown
-// document. The walker may modify block.Code in place; any changes are written
-// back into the document by [Walk].
+// document. The walker may modify block.Code in place, remove the block with
+// [Block.Remove] or insert text after it with [Block.InsertAfter]; any changes
+// are written back into the document by [Walk].
 type Walker func(block *Block) error
 
+// change replaces the source bytes between start and stop with data.
 type change struct {
-	fcb   *ast.FencedCodeBlock
-	block *Block
+	start int
+	stop  int
+	data  []byte
 }
 
-func (c *change) bounds() (int, int) {
-	lines := c.fcb.Lines()
+func (c *change) sizeIncrement() int {
+	return len(c.data) - (c.stop - c.start)
+}
+
+// codeBounds returns the offsets of the code lines of the fenced code block.
+func codeBounds(fcb *ast.FencedCodeBlock) (int, int) {
+	lines := fcb.Lines()
 	if lines.Len() == 0 {
-		return c.fcb.Info.Segment.Stop + 1, c.fcb.Info.Segment.Stop + 1
+		return fcb.Info.Segment.Stop + 1, fcb.Info.Segment.Stop + 1
 	}
 
 	return lines.At(0).Start, lines.At(lines.Len() - 1).Stop
 }
 
-func (c *change) sizeIncrement() int {
-	start, stop := c.bounds()
+// blockBounds returns the offsets of the whole code block, from the start of
+// the opening fence line to the end of the closing fence line. For invisible
+// code blocks the bounds of the wrapping HTML element are returned.
+func blockBounds(fcb *ast.FencedCodeBlock, node ast.Node, source []byte) (int, int, error) {
+	if html, ok := node.(*ast.HTMLBlock); ok {
+		lines := html.Lines()
+		stop := lines.At(lines.Len() - 1).Stop
+
+		if html.HasClosure() {
+			stop = html.ClosureLine.Stop
+		}
+
+		return lineStart(source, lines.At(0).Start), stop, nil
+	}
 
-	return len(c.block.Code) - (stop - start)
+	var start, stop int
+
+	switch lines := fcb.Lines(); {
+	case lines.Len() != 0:
+		start = lineStart(source, lineStart(source, lines.At(0).Start)-1)
+		stop = lines.At(lines.Len() - 1).Stop
+	case fcb.Info != nil:
+		start = lineStart(source, fcb.Info.Segment.Start)
+		stop = lineEnd(source, fcb.Info.Segment.Stop)
+	default:
+		return 0, 0, ErrNoBounds
+	}
+
+	// Include the closing fence line, unless the fence is left open at the
+	// end of the document or its container.
+	if closing := source[stop:lineEnd(source, stop)]; reFences.Match(bytes.TrimLeft(closing, " \t>")) {
+		stop += len(closing)
+	}
+
+	return start, stop, nil
+}
+
+func blockChanges(block *Block, code []byte, refenced bool, fcb *ast.FencedCodeBlock, node ast.Node, source []byte) ([]*change, error) {
+	var changes []*change
+
+	if !block.removed && !bytes.Equal(code, block.Code) {
+		start, stop := codeBounds(fcb)
+		changes = append(changes, &change{start: start, stop: stop, data: indent(block.Code, fcb, source)})
+
+		if !refenced {
+			changes = escalate(changes, block.Code, fcb, source)
+		}
+	}
+
+	if !block.removed && refenced {
+		changes = refence(changes, block, fcb, source)
+	}
+
+	if !block.removed && len(block.after) == 0 {
+		return changes, nil
+	}
+
+	start, stop, err := blockBounds(fcb, node, source)
+	if err != nil {
+		return nil, err
+	}
+
+	if !block.removed {
+		start = stop
+	}
+
+	return append(changes, &change{start: start, stop: stop, data: block.after}), nil
 }
 
 // Walk parses a Markdown document and calls walker for every fenced code block.
-// If the walker modifies any block's Code, Walk returns true and the updated
-// document. When no blocks are modified, it returns false and a nil slice.
+// If the walker modifies any block's Code, removes a block or inserts text,
+// Walk returns true and the updated document. When no blocks are modified, it
+// returns false and a nil slice.
+//
+// The code of blocks nested in list items or blockquotes is passed to the
+// walker without the indentation and blockquote markers, which are re-applied
+// to every line of the modified code.
+// If the modified code contains a fence that would close the code block
+// early, the fences of the code block are lengthened.
+//
+// Default metadata can be given with the mdcode key of the front matter or an
+// <!-- mdcode: defaults ... --> directive, it is inherited by the blocks below.
+// The lang key supplies a missing language and the file-prefix key is joined
+// with the file metadata.
+//
+// If the walker modifies the Fence or Info of the block, the opening and
+// closing fence lines are rewritten. The fence of modified code is not
+// lengthened in this case, the walker has to choose a long enough fence.
+//
+// The <!-- mdcode:skip-next --> directive sets the [MetaSkip] metadata of the
+// next block, the <!-- mdcode:cmd command --> directive sets the [MetaCmd]
+// metadata of the blocks up to the <!-- mdcode:end --> directive.
+//
+// The [Block.Headings] of the blocks is the path of the section headings
+// enclosing them.
+//
+// The <!-- mdcode:include document#name --> directive is passed to the walker
+// as a [Block.Virtual] code block with [MetaInclude] metadata. The code of
+// the blocks with [MetaInclude] metadata is resolved by the caller, so its
+// modifications are ignored. The walker may remove the directive or insert
+// text after it, though.
 func Walk(source []byte, walker Walker) (bool, []byte, error) {
 	parser := goldmark.DefaultParser()
 	reader := text.NewReader(source)
 	root := parser.Parse(reader).OwnerDocument()
 
-	var changes []*change
+	defaults, err := frontMatterDefaults(source)
+	if err != nil {
+		return false, nil, fmt.Errorf("%w: front matter: %w", ErrParse, err)
+	}
+
+	state := &directives{defaults: defaults}
+
+	var (
+		changes  []*change
+		headings []string
+	)
+
+	err = ast.Walk(root, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if heading, ok := node.(*ast.Heading); ok && entering {
+			headings = headingPath(headings, heading, source)
+
+			return ast.WalkContinue, nil
+		}
 
-	err := ast.Walk(root, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
-		node = transformCommentedCodeBlock(node, entering, source)
+		if name, arg, ok := parseDirective(node, entering, source); ok {
+			line := lineAt(source, node.Lines().At(0).Start)
 
-		fcb := asFencedCodeBlock(node, entering)
+			if derr := state.apply(name, arg); derr != nil {
+				return ast.WalkContinue, fmt.Errorf("%w: line %d: %w", ErrParse, line, derr)
+			}
+
+			if name == directiveInclude {
+				includeChanges, ierr := walkInclude(state, arg, node, source, walker, headings)
+				changes = append(changes, includeChanges...)
+
+				return ast.WalkContinue, ierr
+			}
+
+			return ast.WalkContinue, nil
+		}
+
+		transformed := transformCommentedCodeBlock(node, entering, source)
+
+		fcb := asFencedCodeBlock(transformed, entering)
 		if fcb == nil {
 			return ast.WalkContinue, nil
 		}
 
 		block, berr := extractBlock(fcb, source)
 		if berr != nil {
-			return ast.WalkContinue, berr
+			line, _ := extractLines(fcb, source)
+
+			return ast.WalkContinue, fmt.Errorf("%w: line %d: %w", ErrParse, line, berr)
 		}
 
-		code := block.Code
+		block.Hidden = transformed != node
+		block.Headings = headings
+
+		state.block(block)
+
+		code, fence, info := block.Code, block.Fence, block.Info
+		include := block.Meta.Get(MetaInclude)
 
 		berr = walker(block)
 		if berr != nil {
 			return ast.WalkContinue, berr
 		}
 
-		if !bytes.Equal(code, block.Code) {
-			changes = append(changes, &change{fcb: fcb, block: block})
+		if len(include) != 0 {
+			code = block.Code
+		}
+
+		refenced := block.Fence != fence || block.Info != info
+
+		blockChanges, berr := blockChanges(block, code, refenced, fcb, node, source)
+		if berr != nil {
+			return ast.WalkContinue, berr
 		}
 
+		changes = append(changes, blockChanges...)
+
 		return ast.WalkContinue, nil
 	})
 	if err != nil {
@@ -83,6 +235,156 @@ func Walk(source []byte, walker Walker) (bool, []byte, error) {
 	return true, applyChanges(changes, source), nil
 }
 
+// headingPath returns the heading path below the heading: the path is cut
+// above the level of the heading, and the heading text is appended. A new
+// slice is returned, the previous path may be shared by code blocks.
+func headingPath(path []string, heading *ast.Heading, source []byte) []string {
+	level := heading.Level
+	if level > len(path)+1 {
+		level = len(path) + 1
+	}
+
+	var title []byte
+
+	lines := heading.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		seg := lines.At(i)
+		title = append(title, seg.Value(source)...)
+		title = append(title, ' ')
+	}
+
+	next := make([]string, level)
+	copy(next, path[:level-1])
+	next[level-1] = string(bytes.TrimSpace(title))
+
+	return next
+}
+
+// escalate lengthens the fences of the code block when the modified code
+// contains a line that would otherwise close the block early. The code change
+// is the last element of changes, the fence changes are placed around it.
+func escalate(changes []*change, code []byte, fcb *ast.FencedCodeBlock, source []byte) []*change {
+	start, stop, err := blockBounds(fcb, fcb, source)
+	if err != nil {
+		return changes
+	}
+
+	opening := source[start:lineEnd(source, start)]
+
+	idx := bytes.IndexAny(opening, "`~")
+	if idx < 0 {
+		return changes
+	}
+
+	char := opening[idx]
+	size := fenceRun(opening[idx:], char)
+
+	longest := 0
+
+	for _, line := range bytes.Split(code, []byte{'\n'}) {
+		if run := fenceRun(bytes.TrimLeft(line, " \t"), char); run > longest {
+			longest = run
+		}
+	}
+
+	if longest < size {
+		return changes
+	}
+
+	fence := bytes.Repeat([]byte{char}, longest+1)
+	last := len(changes) - 1
+
+	escalated := append([]*change(nil), changes[:last]...)
+	escalated = append(escalated, &change{start: start + idx, stop: start + idx + size, data: fence}, changes[last])
+
+	if _, codeStop := codeBounds(fcb); stop > codeStop {
+		closing := source[codeStop:stop]
+		if idx = bytes.IndexByte(closing, char); idx >= 0 {
+			escalated = append(escalated, &change{
+				start: codeStop + idx, stop: codeStop + idx + fenceRun(closing[idx:], char), data: fence,
+			})
+		}
+	}
+
+	return escalated
+}
+
+// fenceRun returns the number of leading fence characters in line.
+func fenceRun(line []byte, char byte) int {
+	run := 0
+	for run < len(line) && line[run] == char {
+		run++
+	}
+
+	return run
+}
+
+// indent re-applies the structural prefix of the code lines (the indentation
+// of list items or the markers of blockquotes), which is stripped from the
+// code passed to the walker, to the modified code of the fenced code block.
+func indent(code []byte, fcb *ast.FencedCodeBlock, source []byte) []byte {
+	var (
+		prefix []byte
+		first  bool
+	)
+
+	if lines := fcb.Lines(); lines.Len() != 0 {
+		// The prefix of the first line precedes the replaced bytes.
+		start := lines.At(0).Start
+		prefix = source[lineStart(source, start):start]
+	} else if fcb.Info != nil {
+		// Empty blocks are inserted at the start of the closing fence line,
+		// derive the prefix from the opening fence line without the list marker.
+		line := source[lineStart(source, fcb.Info.Segment.Start):fcb.Info.Segment.Start]
+		prefix = bytes.Map(func(r rune) rune {
+			if r == '>' || r == '\t' {
+				return r
+			}
+
+			return ' '
+		}, line[:bytes.IndexAny(line, "`~")])
+		first = true
+	}
+
+	if len(prefix) == 0 || len(code) == 0 {
+		return code
+	}
+
+	blank := bytes.TrimRight(prefix, " \t")
+
+	var buff bytes.Buffer
+
+	for idx, line := range bytes.SplitAfter(code, []byte{'\n'}) {
+		if len(line) == 0 {
+			break
+		}
+
+		switch {
+		case idx == 0 && !first:
+		case len(bytes.TrimRight(line, "\r\n")) == 0:
+			buff.Write(blank)
+		default:
+			buff.Write(prefix)
+		}
+
+		buff.Write(line)
+	}
+
+	return buff.Bytes()
+}
+
+func lineStart(source []byte, offset int) int {
+	return bytes.LastIndexByte(source[:offset], '\n') + 1
+}
+
+func lineEnd(source []byte, offset int) int {
+	if idx := bytes.IndexByte(source[offset:], '\n'); idx >= 0 {
+		return offset + idx + 1
+	}
+
+	return len(source)
+}
+
 func asFencedCodeBlock(node ast.Node, entering bool) *ast.FencedCodeBlock {
 	if entering || node.Kind() != ast.KindFencedCodeBlock {
 		return nil
@@ -104,6 +406,15 @@ func extractBlock(fcb *ast.FencedCodeBlock, source []byte) (*Block, error) {
 	block := &Block{Lang: lang, Meta: meta, Code: extractCode(fcb, source)}
 	block.StartLine, block.EndLine = extractLines(fcb, source)
 
+	if fcb.Info != nil {
+		block.Info = string(bytes.TrimSpace(fcb.Info.Segment.Value(source)))
+	}
+
+	if start, ok := openingFence(fcb, source); ok {
+		line := source[start:lineEnd(source, start)]
+		block.Fence = string(line[:fenceRun(line, line[0])])
+	}
+
 	return block, nil
 }
 
@@ -170,6 +481,12 @@ func extractInfo(fcb *ast.FencedCodeBlock, source []byte) (string, Meta, error)
 	return parseInfo(fcb.Info.Text(source))
 }
 
+// ParseInfo parses the info string of a code block into its language and
+// metadata, without the document defaults.
+func ParseInfo(info string) (string, Meta, error) {
+	return parseInfo([]byte(info))
+}
+
 func parseInfo(text []byte) (string, Meta, error) {
 	all := reInfo.FindSubmatch(text)
 	if all == nil {
@@ -186,6 +503,14 @@ func parseInfo(text []byte) (string, Meta, error) {
 		lang = string(all[1])
 	}
 
+	// An info string starting with metadata has no language (it may be
+	// supplied by the document defaults).
+	if len(all) > 2 && bytes.HasPrefix(all[2], []byte("=")) {
+		meta, err = parseMeta(bytes.TrimSpace(text))
+
+		return "", meta, err
+	}
+
 	if len(all) <= 2 { //nolint:gomnd
 		return lang, meta, nil
 	}
@@ -207,15 +532,13 @@ func applyChanges(changes []*change, source []byte) []byte {
 	var srcIdx, resIdx int
 
 	for _, change := range changes {
-		start, stop := change.bounds()
+		copy(result[resIdx:], source[srcIdx:change.start])
+		resIdx += (change.start - srcIdx)
 
-		copy(result[resIdx:], source[srcIdx:start])
-		resIdx += (start - srcIdx)
+		copy(result[resIdx:], change.data)
+		resIdx += len(change.data)
 
-		copy(result[resIdx:], change.block.Code)
-		resIdx += len(change.block.Code)
-
-		srcIdx = stop
+		srcIdx = change.stop
 	}
 
 	copy(result[resIdx:], source[srcIdx:])
@@ -223,9 +546,17 @@ func applyChanges(changes []*change, source []byte) []byte {
 	return result
 }
 
+// ErrParse is returned (wrapping the cause) if the info string of a code
+// block, the front matter defaults or a directive can't be parsed.
+var ErrParse = errors.New("markdown parse error")
+
+// ErrNoBounds is returned when an empty code block without info string is
+// removed or followed by inserted text, as its position is unknown.
+var ErrNoBounds = errors.New("cannot locate code block without content and info string")
+
 var (
-	reCommentedCodeBlock = regexp.MustCompile(`^\s*(<!--)?\s*<script\s*type=["']text/markdown["']\s*>\s*$`)
-	reFences             = regexp.MustCompile("^\\s*```")
+	reCommentedCodeBlock = regexp.MustCompile(`^\s*((<!--)?\s*<script\s*type=["']text/markdown["']\s*>|<!--\s*mdcode)\s*$`)
+	reFences             = regexp.MustCompile("^\\s*(`{3,}|~{3,})")
 )
 
 func transformCommentedCodeBlock(node ast.Node, entering bool, source []byte) ast.Node { //nolint:ireturn
@@ -255,18 +586,23 @@ func transformCommentedCodeBlock(node ast.Node, entering bool, source []byte) as
 	seg = lines.At(1)
 	line = seg.Value(source)
 
-	loc := reFences.FindIndex(line)
+	loc := reFences.FindSubmatchIndex(line)
 	if loc == nil {
 		return node
 	}
 
+	fence := line[loc[2]:loc[3]]
+
 	info := ast.NewTextSegment(text.NewSegment(seg.Start+loc[1], seg.Stop-1))
 	fcb := ast.NewFencedCodeBlock(info)
 
 	seg = lines.At(lines.Len() - 1)
 	line = seg.Value(source)
 
-	if !reFences.Match(line) {
+	// The closing fence must use the same character and be at least as long
+	// as the opening one, like in CommonMark.
+	closing := reFences.FindSubmatch(line)
+	if closing == nil || closing[1][0] != fence[0] || len(closing[1]) < len(fence) {
 		return node
 	}
 
@@ -115,3 +115,323 @@ func Test_Walk_mod(t *testing.T) {
 
 	require.Equal(t, testdocmod, got)
 }
+
+func Test_Walk_fences(t *testing.T) {
+	t.Parallel()
+
+	src := "~~~js file=tilde.js\nold\n~~~\n\n" +
+		"````md file=nested.md\n```sh\nls\n```\n````\n\n" +
+		"<script type=\"text/markdown\">\n~~~js file=script.js\nold\n~~~\n</script>\n\n" +
+		"<script type=\"text/markdown\">\n````md file=unclosed.md\n```\n</script>\n"
+
+	want := "~~~js file=tilde.js\nnew\n~~~\n\n" +
+		"````md file=nested.md\nnew\n````\n\n" +
+		"<script type=\"text/markdown\">\n~~~js file=script.js\nnew\n~~~\n</script>\n\n" +
+		"<script type=\"text/markdown\">\n````md file=unclosed.md\n```\n</script>\n"
+
+	var files []string
+
+	mod, got, err := Walk([]byte(src), func(block *Block) error {
+		files = append(files, block.Meta.Get("file"))
+
+		if block.Meta.Get("file") == "nested.md" {
+			require.Equal(t, "```sh\nls\n```\n", string(block.Code))
+		}
+
+		block.Code = []byte("new\n")
+
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.True(t, mod)
+	require.Equal(t, []string{"tilde.js", "nested.md", "script.js"}, files)
+	require.Equal(t, want, string(got))
+}
+
+func Test_Walk_nested(t *testing.T) {
+	t.Parallel()
+
+	src := "- item\n\n  ```js file=list.js\n  one\n\n  two\n  ```\n\n" +
+		"> quote\n>\n> ```js file=quote.js\n> one\n>\n> two\n> ```\n\n" +
+		"> ```js file=empty.js\n> ```\n\n" +
+		"1. ```sh file=empty.sh\n   ```\n"
+
+	want := "- item\n\n  ```js file=list.js\n  x\n\n  y\n  ```\n\n" +
+		"> quote\n>\n> ```js file=quote.js\n> x\n>\n> y\n> ```\n\n" +
+		"> ```js file=empty.js\n> x\n>\n> y\n> ```\n\n" +
+		"1. ```sh file=empty.sh\n   x\n\n   y\n   ```\n"
+
+	mod, got, err := Walk([]byte(src), func(block *Block) error {
+		if len(block.Code) != 0 {
+			require.Equal(t, "one\n\ntwo\n", string(block.Code))
+		}
+
+		block.Code = []byte("x\n\ny\n")
+
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.True(t, mod)
+	require.Equal(t, want, string(got))
+}
+
+func Test_Walk_hidden(t *testing.T) {
+	t.Parallel()
+
+	src := "```js file=visible.js\n```\n\n" +
+		"<!-- mdcode\n```js file=comment.js\nold\n```\n-->\n\n" +
+		"<script type=\"text/markdown\">\n```js file=script.js\n```\n</script>\n"
+
+	hidden := make(map[string]bool)
+
+	mod, got, err := Walk([]byte(src), func(block *Block) error {
+		hidden[block.Meta.Get("file")] = block.Hidden
+
+		if block.Meta.Get("file") == "comment.js" {
+			block.Code = []byte("new\n")
+		}
+
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.True(t, mod)
+	require.Equal(t, map[string]bool{"visible.js": false, "comment.js": true, "script.js": true}, hidden)
+	require.Equal(t, strings.Replace(src, "old", "new", 1), string(got))
+}
+
+func Test_Walk_remove_insert(t *testing.T) {
+	t.Parallel()
+
+	src := "# Title\n\n```sh action=remove\nls\n```\n\ntext\n\n" +
+		"```sh action=insert\nls\n```\n\n" +
+		"<!-- mdcode\n```sh action=remove\n```\n-->\n\n" +
+		"> ```sh action=replace\n> ```\n\n" +
+		"```sh action=remove\nunclosed\n"
+
+	want := "# Title\n\n\ntext\n\n" +
+		"```sh action=insert\nls\n```\noutput\n\n\n" +
+		"> new\n\n"
+
+	mod, got, err := Walk([]byte(src), func(block *Block) error {
+		switch {
+		case block.Meta.Get("action") == "remove":
+			block.Remove()
+		case block.Meta.Get("action") == "insert":
+			block.InsertAfter([]byte("output\n"))
+		case block.Meta.Get("action") == "replace":
+			block.Remove()
+			block.InsertAfter([]byte("> new\n"))
+		}
+
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.True(t, mod)
+	require.Equal(t, want, string(got))
+}
+
+func Test_Walk_escalate(t *testing.T) {
+	t.Parallel()
+
+	src := "```md file=a.md\nold\n```\n\n" +
+		"~~~md file=b.md\nold\n~~~\n\n" +
+		"> ```md file=c.md\n> ```\n"
+
+	want := "`````md file=a.md\n# Doc\n\n````sh\nls\n````\n`````\n\n" +
+		"~~~md file=b.md\n# Doc\n\n````sh\nls\n````\n~~~\n\n" +
+		"> `````md file=c.md\n> # Doc\n>\n> ````sh\n> ls\n> ````\n> `````\n"
+
+	mod, got, err := Walk([]byte(src), func(block *Block) error {
+		block.Code = []byte("# Doc\n\n````sh\nls\n````\n")
+
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.True(t, mod)
+	require.Equal(t, want, string(got))
+}
+
+func Test_Walk_defaults(t *testing.T) {
+	t.Parallel()
+
+	src := "---\ntitle: Examples\nmdcode: \"owner=docs\"\n---\n\n" +
+		"```\nplain\n```\n\n" +
+		"<!-- mdcode: defaults lang=go file-prefix=examples/ -->\n\n" +
+		"```file=main.go\n```\n\n" +
+		"```sh file=run.sh owner=ops\n```\n\n" +
+		"<!-- mdcode: defaults owner=team -->\n\n" +
+		"```\n```\n"
+
+	type result struct {
+		Lang  string
+		File  string
+		Owner string
+	}
+
+	var got []result
+
+	_, _, err := Walk([]byte(src), func(block *Block) error {
+		got = append(got, result{block.Lang, block.Meta.Get("file"), block.Meta.Get("owner")})
+
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, []result{
+		{"", "", "docs"},
+		{"go", "examples/main.go", "docs"},
+		{"sh", "examples/run.sh", "ops"},
+		{"go", "", "team"},
+	}, got)
+}
+
+func Test_Walk_directives(t *testing.T) {
+	t.Parallel()
+
+	src := "```sh name=first\n```\n\n" +
+		"<!-- mdcode:skip-next -->\n" +
+		"```sh name=skipped\n```\n\n" +
+		"<!-- mdcode:cmd go test ./... -->\n\n" +
+		"```go name=tested\n```\n\n" +
+		"```go name=own cmd=\"go run {}\"\n```\n\n" +
+		"<!-- mdcode:end -->\n\n" +
+		"```sh name=last\n```\n"
+
+	got := make(map[string][2]string)
+
+	_, _, err := Walk([]byte(src), func(block *Block) error {
+		got[block.Meta.Get("name")] = [2]string{block.Meta.Get(MetaSkip), block.Meta.Get(MetaCmd)}
+
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, map[string][2]string{
+		"first":   {"", ""},
+		"skipped": {"true", ""},
+		"tested":  {"", "go test ./..."},
+		"own":     {"", "go run {}"},
+		"last":    {"", ""},
+	}, got)
+
+	_, _, err = Walk([]byte("<!-- mdcode:unknown -->\n"), func(*Block) error { return nil })
+
+	require.ErrorIs(t, err, ErrDirective)
+}
+
+func Test_Walk_parse_error(t *testing.T) {
+	t.Parallel()
+
+	_, _, err := Walk([]byte("text\n\n```js {\"file\":\n```\n"), func(*Block) error { return nil })
+
+	require.ErrorIs(t, err, ErrParse)
+	require.ErrorContains(t, err, "line 3")
+}
+
+func Test_Walk_refence(t *testing.T) {
+	t.Parallel()
+
+	src := "~~~~ golang   file=a.go\nold\n~~~~~\n\n" +
+		"- ```sh\n  ls\n  ```\n\n" +
+		"<!-- mdcode\n```js\n```\n-->\n\n" +
+		"```\nkeep\n```\n"
+
+	want := "```go file=a.go\nnew\n```\n\n" +
+		"- ~~~sh name=list\n  ls\n  ~~~\n\n" +
+		"<!-- mdcode\n````js\n````\n-->\n\n" +
+		"```\nkeep\n```\n"
+
+	mod, got, err := Walk([]byte(src), func(block *Block) error {
+		switch block.Lang {
+		case "golang":
+			block.Fence, block.Info, block.Code = "```", "go file=a.go", []byte("new\n")
+		case "sh":
+			block.Fence, block.Info = "~~~", "sh name=list"
+		case "js":
+			block.Fence = "````"
+		}
+
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.True(t, mod)
+	require.Equal(t, want, string(got))
+}
+
+func Test_Walk_include(t *testing.T) {
+	t.Parallel()
+
+	src := "```sh include=common.md#setup\nold\n```\n\n" +
+		"<!-- mdcode:include #local -->\n\n" +
+		"```sh name=local\nls\n```\n"
+
+	var virtual []int
+
+	mod, got, err := Walk([]byte(src), func(block *Block) error {
+		if block.Virtual {
+			virtual = append(virtual, block.StartLine)
+			require.Equal(t, "#local", block.Meta.Get(MetaInclude))
+		}
+
+		block.Code = []byte("new\n")
+
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.True(t, mod)
+	require.Equal(t, []int{5}, virtual)
+	require.Equal(t, strings.Replace(src, "ls", "new", 1), string(got))
+
+	_, _, err = Walk([]byte("<!-- mdcode:include -->\n"), func(*Block) error { return nil })
+
+	require.ErrorIs(t, err, ErrDirective)
+}
+
+func Test_Walk_include_replace(t *testing.T) {
+	t.Parallel()
+
+	src := "# Title\n\n<!-- mdcode:include #setup -->\n\ntext\n"
+
+	mod, got, err := Walk([]byte(src), func(block *Block) error {
+		block.Remove()
+		block.InsertAfter([]byte("```sh\nls\n```\n"))
+
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.True(t, mod)
+	require.Equal(t, "# Title\n\n```sh\nls\n```\n\ntext\n", string(got))
+}
+
+func Test_Walk_headings(t *testing.T) {
+	t.Parallel()
+
+	src := "```sh\ntop\n```\n\n" +
+		"# Install\n\n## Linux\n\n```sh\nlinux\n```\n\n" +
+		"Usage\n-----\n\n```sh\nusage\n```\n\n" +
+		"#### Deep `flag`\n\n<!-- mdcode:include #setup -->\n"
+
+	var paths [][]string
+
+	_, _, err := Walk([]byte(src), func(block *Block) error {
+		paths = append(paths, block.Headings)
+
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, [][]string{
+		nil,
+		{"Install", "Linux"},
+		{"Install", "Usage"},
+		{"Install", "Usage", "Deep `flag`"},
+	}, paths)
+}
@@ -0,0 +1,207 @@
+// Package patch applies unified diffs to file contents.
+//
+// The diffs of documentation are often written by hand, so the line counts
+// of the hunk headers are ignored (the hunks end at the first line that is
+// not part of a hunk) and the hunks are located by their context, starting
+// at the line given in the header, like the patch program does.
+package patch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+var reHunk = regexp.MustCompile(`^@@ -(\d+)(?:,\d+)? \+\d+(?:,\d+)? @@`)
+
+// hunk is a change of a diff. The lines keep their +, - or space prefix, line
+// is the line of the hunk header in the diff.
+type hunk struct {
+	line     int
+	oldStart int
+	lines    []string
+}
+
+func parse(diff []byte) ([]*hunk, error) {
+	var (
+		hunks   []*hunk
+		current *hunk
+	)
+
+	for idx, line := range bytes.Split(bytes.TrimSuffix(diff, []byte("\n")), []byte("\n")) {
+		text := string(bytes.TrimSuffix(line, []byte("\r")))
+
+		if all := reHunk.FindStringSubmatch(text); all != nil {
+			start, err := strconv.Atoi(all[1])
+			if err != nil {
+				return nil, fmt.Errorf("%w: line %d: %w", ErrMalformed, idx+1, err)
+			}
+
+			current = &hunk{line: idx + 1, oldStart: start, lines: nil}
+			hunks = append(hunks, current)
+
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+
+		switch {
+		case len(text) == 0:
+			// Editors strip the trailing space of empty context lines.
+			current.lines = append(current.lines, " ")
+		case text[0] == ' ' || text[0] == '+' || text[0] == '-':
+			current.lines = append(current.lines, text)
+		case text[0] == '\\':
+		default:
+			current = nil
+		}
+	}
+
+	if len(hunks) == 0 {
+		return nil, fmt.Errorf("%w: no hunk found", ErrMalformed)
+	}
+
+	return hunks, nil
+}
+
+// sides returns the lines of the hunk before and after the change.
+func (h *hunk) sides() ([]string, []string) {
+	var before, after []string
+
+	for _, line := range h.lines {
+		switch line[0] {
+		case ' ':
+			before = append(before, line[1:])
+			after = append(after, line[1:])
+		case '-':
+			before = append(before, line[1:])
+		case '+':
+			after = append(after, line[1:])
+		}
+	}
+
+	return before, after
+}
+
+// Apply applies the unified diff to the content. It fails with
+// [ErrConflict] if the context of a hunk is not found.
+func Apply(content, diff []byte) ([]byte, error) {
+	return apply(content, diff, false)
+}
+
+// Applied reports whether the diff is already applied to the content, that
+// is the reverse diff applies cleanly.
+func Applied(content, diff []byte) bool {
+	_, err := apply(content, diff, true)
+
+	return err == nil
+}
+
+func apply(content, diff []byte, reverse bool) ([]byte, error) {
+	hunks, err := parse(diff)
+	if err != nil {
+		return nil, err
+	}
+
+	eol := []byte("\n")
+	if bytes.Contains(content, []byte("\r\n")) {
+		eol = []byte("\r\n")
+	}
+
+	lines := splitLines(content)
+
+	var (
+		result bytes.Buffer
+		pos    int
+		offset int
+	)
+
+	for idx, hunk := range hunks {
+		before, after := hunk.sides()
+		if reverse {
+			before, after = after, before
+		}
+
+		expected := hunk.oldStart - 1 + offset
+		if len(before) == 0 {
+			expected++
+		}
+
+		at, found := locate(lines, before, pos, expected)
+		if !found {
+			return nil, fmt.Errorf("%w: hunk %d (line %d of the diff)", ErrConflict, idx+1, hunk.line)
+		}
+
+		for _, line := range lines[pos:at] {
+			result.WriteString(line)
+		}
+
+		for _, line := range after {
+			result.WriteString(line)
+			result.Write(eol)
+		}
+
+		pos = at + len(before)
+		offset = at - (hunk.oldStart - 1)
+	}
+
+	for _, line := range lines[pos:] {
+		result.WriteString(line)
+	}
+
+	return result.Bytes(), nil
+}
+
+// locate returns the position of the lines closest to expected, not before
+// pos.
+func locate(lines, want []string, pos, expected int) (int, bool) {
+	expected = max(pos, min(expected, len(lines)))
+
+	for distance := 0; expected-distance >= pos || expected+distance <= len(lines); distance++ {
+		for _, at := range []int{expected - distance, expected + distance} {
+			if at >= pos && at+len(want) <= len(lines) && matches(lines[at:at+len(want)], want) {
+				return at, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+func matches(lines, want []string) bool {
+	for idx, line := range lines {
+		if string(bytes.TrimRight([]byte(line), " \t\r\n")) != string(bytes.TrimRight([]byte(want[idx]), " \t\r")) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// splitLines splits the content into lines, keeping the line endings.
+func splitLines(content []byte) []string {
+	var lines []string
+
+	for len(content) != 0 {
+		idx := bytes.IndexByte(content, '\n') + 1
+		if idx == 0 {
+			idx = len(content)
+		}
+
+		lines = append(lines, string(content[:idx]))
+		content = content[idx:]
+	}
+
+	return lines
+}
+
+var (
+	// ErrMalformed is returned if the diff has no valid hunk.
+	ErrMalformed = errors.New("malformed diff")
+	// ErrConflict is returned if a hunk does not apply.
+	ErrConflict = errors.New("diff does not apply")
+)
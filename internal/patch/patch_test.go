@@ -0,0 +1,67 @@
+package patch_test
+
+import (
+	"testing"
+
+	"github.com/ezerfernandes/mdcode/internal/patch"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	before = "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hello\")\n}\n"
+	after  = "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hello, world\")\n\tfmt.Println(\"bye\")\n}\n"
+)
+
+func Test_Apply(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		diff string
+	}{
+		{
+			name: "git",
+			diff: "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n" +
+				"@@ -5,3 +5,4 @@\n func main() {\n-\tfmt.Println(\"hello\")\n+\tfmt.Println(\"hello, world\")\n+\tfmt.Println(\"bye\")\n }\n",
+		},
+		{
+			name: "offset and wrong counts",
+			diff: "@@ -1,2 +1,2 @@\n func main() {\n-\tfmt.Println(\"hello\")\n+\tfmt.Println(\"hello, world\")\n+\tfmt.Println(\"bye\")\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := patch.Apply([]byte(before), []byte(test.diff))
+
+			require.NoError(t, err)
+			require.Equal(t, after, string(got))
+
+			require.True(t, patch.Applied(got, []byte(test.diff)))
+			require.False(t, patch.Applied([]byte(before), []byte(test.diff)))
+		})
+	}
+}
+
+func Test_Apply_insert(t *testing.T) {
+	t.Parallel()
+
+	got, err := patch.Apply(nil, []byte("--- /dev/null\n+++ b/a.txt\n@@ -0,0 +1,2 @@\n+one\n+two\n"))
+
+	require.NoError(t, err)
+	require.Equal(t, "one\ntwo\n", string(got))
+}
+
+func Test_Apply_errors(t *testing.T) {
+	t.Parallel()
+
+	_, err := patch.Apply([]byte(before), []byte("just text\n"))
+	require.ErrorIs(t, err, patch.ErrMalformed)
+
+	_, err = patch.Apply([]byte(before), []byte("@@ -1 +1 @@\n-package other\n+package main\n"))
+	require.ErrorIs(t, err, patch.ErrConflict)
+}
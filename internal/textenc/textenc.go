@@ -0,0 +1,185 @@
+// Package textenc converts documents between legacy text encodings and UTF-8.
+//
+// Documents are decoded to UTF-8 without byte order mark for processing and
+// encoded back to their original [Format] when written, so that a round-trip
+// leaves unmodified content byte for byte identical.
+package textenc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Supported encoding names.
+const (
+	UTF8    = "utf-8"
+	Latin1  = "latin-1"
+	UTF16   = "utf-16"
+	UTF16LE = "utf-16le"
+	UTF16BE = "utf-16be"
+)
+
+var (
+	bomUTF8    = []byte{0xef, 0xbb, 0xbf}
+	bomUTF16LE = []byte{0xff, 0xfe}
+	bomUTF16BE = []byte{0xfe, 0xff}
+)
+
+// Format is the encoding of a document along with the presence of a byte
+// order mark.
+type Format struct {
+	Encoding string
+	BOM      bool
+}
+
+// Normalize returns the canonical name of the encoding name, accepting the
+// common aliases. An empty name stands for automatic detection.
+func Normalize(name string) (string, error) {
+	switch strings.ToLower(strings.ReplaceAll(name, "_", "-")) {
+	case "":
+		return "", nil
+	case "utf-8", "utf8":
+		return UTF8, nil
+	case "latin-1", "latin1", "iso-8859-1", "iso8859-1":
+		return Latin1, nil
+	case "utf-16", "utf16":
+		return UTF16, nil
+	case "utf-16le", "utf16le":
+		return UTF16LE, nil
+	case "utf-16be", "utf16be":
+		return UTF16BE, nil
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrUnknownEncoding, name)
+}
+
+// Decode converts data from the named encoding to UTF-8 and strips the byte
+// order mark. An empty name detects UTF-16 and UTF-8 by their byte order
+// mark, falling back to UTF-8. The returned Format restores the original
+// encoding with [Encode].
+func Decode(data []byte, name string) ([]byte, Format, error) {
+	name, err := Normalize(name)
+	if err != nil {
+		return nil, Format{}, err
+	}
+
+	switch {
+	case bytes.HasPrefix(data, bomUTF8) && (name == "" || name == UTF8):
+		return data[len(bomUTF8):], Format{Encoding: UTF8, BOM: true}, nil
+	case bytes.HasPrefix(data, bomUTF16LE) && (name == "" || name == UTF16 || name == UTF16LE):
+		text, err := decodeUTF16(data[len(bomUTF16LE):], binary.LittleEndian)
+
+		return text, Format{Encoding: UTF16LE, BOM: true}, err
+	case bytes.HasPrefix(data, bomUTF16BE) && (name == "" || name == UTF16 || name == UTF16BE):
+		text, err := decodeUTF16(data[len(bomUTF16BE):], binary.BigEndian)
+
+		return text, Format{Encoding: UTF16BE, BOM: true}, err
+	}
+
+	switch name {
+	case Latin1:
+		return decodeLatin1(data), Format{Encoding: Latin1, BOM: false}, nil
+	case UTF16, UTF16LE:
+		text, err := decodeUTF16(data, binary.LittleEndian)
+
+		return text, Format{Encoding: UTF16LE, BOM: false}, err
+	case UTF16BE:
+		text, err := decodeUTF16(data, binary.BigEndian)
+
+		return text, Format{Encoding: UTF16BE, BOM: false}, err
+	}
+
+	return data, Format{Encoding: UTF8, BOM: false}, nil
+}
+
+// Encode converts UTF-8 text to the given format.
+func Encode(text []byte, format Format) ([]byte, error) {
+	var (
+		data []byte
+		bom  []byte
+		err  error
+	)
+
+	switch format.Encoding {
+	case Latin1:
+		data, err = encodeLatin1(text)
+	case UTF16LE, UTF16:
+		data, bom = encodeUTF16(text, binary.LittleEndian), bomUTF16LE
+	case UTF16BE:
+		data, bom = encodeUTF16(text, binary.BigEndian), bomUTF16BE
+	default:
+		data, bom = text, bomUTF8
+	}
+
+	if err != nil || !format.BOM || bom == nil {
+		return data, err
+	}
+
+	return append(append(make([]byte, 0, len(bom)+len(data)), bom...), data...), nil
+}
+
+func decodeLatin1(data []byte) []byte {
+	var buff bytes.Buffer
+
+	buff.Grow(len(data))
+
+	for _, b := range data {
+		buff.WriteRune(rune(b))
+	}
+
+	return buff.Bytes()
+}
+
+func encodeLatin1(text []byte) ([]byte, error) {
+	data := make([]byte, 0, len(text))
+
+	for len(text) != 0 {
+		r, size := utf8.DecodeRune(text)
+		if r > 0xff || (r == utf8.RuneError && size == 1) {
+			return nil, fmt.Errorf("%w: %q", ErrUnrepresentable, r)
+		}
+
+		data = append(data, byte(r))
+		text = text[size:]
+	}
+
+	return data, nil
+}
+
+func decodeUTF16(data []byte, order binary.ByteOrder) ([]byte, error) {
+	if len(data)%2 != 0 {
+		return nil, ErrOddLength
+	}
+
+	units := make([]uint16, len(data)/2) //nolint:gomnd
+	for idx := range units {
+		units[idx] = order.Uint16(data[2*idx:])
+	}
+
+	return []byte(string(utf16.Decode(units))), nil
+}
+
+func encodeUTF16(text []byte, order binary.ByteOrder) []byte {
+	units := utf16.Encode([]rune(string(text)))
+	data := make([]byte, 2*len(units)) //nolint:gomnd
+
+	for idx, unit := range units {
+		order.PutUint16(data[2*idx:], unit)
+	}
+
+	return data
+}
+
+var (
+	// ErrUnknownEncoding is returned for unsupported encoding names.
+	ErrUnknownEncoding = errors.New("unknown encoding (use utf-8, latin-1, utf-16, utf-16le or utf-16be)")
+	// ErrUnrepresentable is returned when text cannot be encoded in the target encoding.
+	ErrUnrepresentable = errors.New("character not representable in encoding")
+	// ErrOddLength is returned when UTF-16 data has an odd number of bytes.
+	ErrOddLength = errors.New("odd number of bytes in UTF-16 data")
+)
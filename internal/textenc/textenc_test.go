@@ -0,0 +1,75 @@
+package textenc_test
+
+import (
+	"testing"
+
+	"github.com/ezerfernandes/mdcode/internal/textenc"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_roundtrip(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		data   []byte
+		enc    string
+		text   string
+		format textenc.Format
+	}{
+		{
+			name: "utf-8", data: []byte("héllo\n"), enc: "",
+			text: "héllo\n", format: textenc.Format{Encoding: textenc.UTF8, BOM: false},
+		},
+		{
+			name: "utf-8 bom", data: []byte("\xef\xbb\xbfhello\n"), enc: "",
+			text: "hello\n", format: textenc.Format{Encoding: textenc.UTF8, BOM: true},
+		},
+		{
+			name: "latin-1", data: []byte("h\xe9llo\n"), enc: "latin1",
+			text: "héllo\n", format: textenc.Format{Encoding: textenc.Latin1, BOM: false},
+		},
+		{
+			name: "utf-16le bom", data: []byte("\xff\xfeh\x00\xe9\x00\n\x00"), enc: "",
+			text: "hé\n", format: textenc.Format{Encoding: textenc.UTF16LE, BOM: true},
+		},
+		{
+			name: "utf-16be bom", data: []byte("\xfe\xff\x00h\x00\xe9\x00\n"), enc: "utf-16",
+			text: "hé\n", format: textenc.Format{Encoding: textenc.UTF16BE, BOM: true},
+		},
+		{
+			name: "utf-16be", data: []byte("\x00h\x00\xe9"), enc: "UTF-16BE",
+			text: "hé", format: textenc.Format{Encoding: textenc.UTF16BE, BOM: false},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			text, format, err := textenc.Decode(test.data, test.enc)
+			require.NoError(t, err)
+			require.Equal(t, test.text, string(text))
+			require.Equal(t, test.format, format)
+
+			data, err := textenc.Encode(text, format)
+			require.NoError(t, err)
+			require.Equal(t, test.data, data)
+		})
+	}
+}
+
+func Test_errors(t *testing.T) {
+	t.Parallel()
+
+	_, _, err := textenc.Decode(nil, "ebcdic")
+	require.ErrorIs(t, err, textenc.ErrUnknownEncoding)
+
+	_, _, err = textenc.Decode([]byte("abc"), textenc.UTF16)
+	require.ErrorIs(t, err, textenc.ErrOddLength)
+
+	_, err = textenc.Encode([]byte("€"), textenc.Format{Encoding: textenc.Latin1, BOM: false})
+	require.ErrorIs(t, err, textenc.ErrUnrepresentable)
+}
@@ -0,0 +1,40 @@
+package validate
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+func checkJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	var value any
+
+	err := dec.Decode(&value)
+	if errors.Is(err, io.EOF) {
+		return &Error{Line: 1, Msg: "empty document"}
+	}
+
+	if err == nil {
+		if _, err = dec.Token(); errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err == nil {
+			return &Error{Line: lineAt(data, dec.InputOffset()), Msg: "unexpected data after the top-level value"}
+		}
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return &Error{Line: lineAt(data, syntaxErr.Offset), Msg: syntaxErr.Error()}
+	}
+
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return &Error{Line: lineAt(data, int64(len(data))), Msg: "unexpected end of JSON input"}
+	}
+
+	return &Error{Line: lineAt(data, dec.InputOffset()), Msg: err.Error()}
+}
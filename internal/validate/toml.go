@@ -0,0 +1,595 @@
+package validate
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	reTOMLNumber = regexp.MustCompile(`^[+-]?(` +
+		`0x[0-9A-Fa-f](_?[0-9A-Fa-f])*|0o[0-7](_?[0-7])*|0b[01](_?[01])*|` +
+		`inf|nan|` +
+		`(0|[1-9](_?[0-9])*)(\.[0-9](_?[0-9])*)?([eE][+-]?[0-9](_?[0-9])*)?)$`)
+	reTOMLDateTime = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}([Tt ]\d{2}:\d{2}(:\d{2}(\.\d+)?)?([Zz]|[+-]\d{2}:\d{2})?)?|` +
+		`\d{2}:\d{2}(:\d{2}(\.\d+)?)?)$`)
+)
+
+// tomlParser checks the syntax of a TOML document: comments, table headers,
+// key/value pairs and the value types, including multi-line strings and
+// arrays. Redefined keys and tables are detected too.
+type tomlParser struct {
+	data string
+	pos  int
+	line int
+
+	// root is the document table, current the table of the key/value pairs.
+	root    *tomlTable
+	current *tomlTable
+}
+
+// tomlKind is how a key of a TOML table was defined.
+type tomlKind int
+
+const (
+	tomlValue    tomlKind = iota // key/value pair (including inline tables)
+	tomlImplicit                 // super-table of a table header, like a in [a.b]
+	tomlHeader                   // table defined by a table header
+	tomlDotted                   // table defined by a dotted key, like a in a.b = 1
+	tomlArray                    // array of tables
+)
+
+// tomlTable is a defined key and, for tables, the defined keys inside.
+type tomlTable struct {
+	kind tomlKind
+	keys map[string]*tomlTable
+
+	// last is the last table of an array of tables.
+	last *tomlTable
+}
+
+func newTOMLTable(kind tomlKind) *tomlTable {
+	return &tomlTable{kind: kind, keys: make(map[string]*tomlTable), last: nil}
+}
+
+func checkTOML(data []byte) error {
+	root := newTOMLTable(tomlHeader)
+	parser := &tomlParser{data: string(data), pos: 0, line: 1, root: root, current: root}
+
+	return parser.document()
+}
+
+func (p *tomlParser) errorf(format string, args ...any) error {
+	return &Error{Line: p.line, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.data)
+}
+
+func (p *tomlParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+
+	return p.data[p.pos]
+}
+
+func (p *tomlParser) rest() string {
+	return p.data[p.pos:]
+}
+
+func (p *tomlParser) advance(n int) {
+	p.line += strings.Count(p.data[p.pos:p.pos+n], "\n")
+	p.pos += n
+}
+
+func (p *tomlParser) spaces() {
+	for c := p.peek(); c == ' ' || c == '\t'; c = p.peek() {
+		p.pos++
+	}
+}
+
+// blank skips whitespace, newlines and comments (inside arrays).
+func (p *tomlParser) blank() {
+	for {
+		p.spaces()
+
+		switch {
+		case p.peek() == '#':
+			p.comment()
+		case strings.HasPrefix(p.rest(), "\n"), strings.HasPrefix(p.rest(), "\r\n"):
+			p.newline()
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) comment() {
+	idx := strings.IndexByte(p.rest(), '\n')
+	if idx < 0 {
+		idx = len(p.rest())
+	}
+
+	p.pos += idx
+	p.pos -= len(p.data[:p.pos]) - len(strings.TrimSuffix(p.data[:p.pos], "\r"))
+}
+
+func (p *tomlParser) newline() {
+	if p.peek() == '\r' {
+		p.pos++
+	}
+
+	p.advance(1)
+}
+
+// endOfLine expects optional whitespace and comment before the end of line.
+func (p *tomlParser) endOfLine() error {
+	p.spaces()
+
+	if p.peek() == '#' {
+		p.comment()
+	}
+
+	switch {
+	case p.eof():
+		return nil
+	case strings.HasPrefix(p.rest(), "\n"), strings.HasPrefix(p.rest(), "\r\n"):
+		p.newline()
+
+		return nil
+	default:
+		return p.errorf("unexpected %q after value", p.peek())
+	}
+}
+
+func (p *tomlParser) document() error {
+	for {
+		p.blank()
+
+		if p.eof() {
+			return nil
+		}
+
+		var err error
+
+		if p.peek() == '[' {
+			err = p.table()
+		} else {
+			err = p.keyValue()
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err = p.endOfLine(); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *tomlParser) table() error {
+	closing := "]"
+	p.pos++
+
+	if p.peek() == '[' {
+		closing = "]]"
+		p.pos++
+	}
+
+	p.spaces()
+
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+
+	p.spaces()
+
+	if !strings.HasPrefix(p.rest(), closing) {
+		return p.errorf("missing %s in table header", closing)
+	}
+
+	p.pos += len(closing)
+
+	return p.openTable(keys, closing == "]]")
+}
+
+// openTable defines the table (or the next table of the array of tables) of
+// the header and makes it the current table.
+func (p *tomlParser) openTable(keys []string, array bool) error {
+	name := strings.Join(keys, ".")
+	table := p.root
+
+	for _, key := range keys[:len(keys)-1] {
+		child, has := table.keys[key]
+
+		switch {
+		case !has:
+			child = newTOMLTable(tomlImplicit)
+			table.keys[key] = child
+		case child.kind == tomlValue:
+			return p.errorf("key %s redefined as a table", name)
+		case child.kind == tomlArray:
+			child = child.last
+		}
+
+		table = child
+	}
+
+	key := keys[len(keys)-1]
+	child, has := table.keys[key]
+
+	switch {
+	case array && !has:
+		child = newTOMLTable(tomlArray)
+		table.keys[key] = child
+	case array && child.kind != tomlArray:
+		return p.errorf("key %s redefined as an array of tables", name)
+	case array:
+	case !has:
+		child = newTOMLTable(tomlHeader)
+		table.keys[key] = child
+	case child.kind == tomlImplicit:
+		child.kind = tomlHeader
+	default:
+		return p.errorf("table %s redefined", name)
+	}
+
+	if array {
+		child.last = newTOMLTable(tomlHeader)
+		child = child.last
+	}
+
+	p.current = child
+
+	return nil
+}
+
+// define defines the dotted key of a key/value pair in the current table.
+func (p *tomlParser) define(keys []string) error {
+	name := strings.Join(keys, ".")
+	table := p.current
+
+	for _, key := range keys[:len(keys)-1] {
+		child, has := table.keys[key]
+
+		switch {
+		case !has:
+			child = newTOMLTable(tomlDotted)
+			table.keys[key] = child
+		case child.kind != tomlDotted:
+			return p.errorf("key %s redefined", name)
+		}
+
+		table = child
+	}
+
+	key := keys[len(keys)-1]
+	if _, has := table.keys[key]; has {
+		return p.errorf("duplicate key %s", name)
+	}
+
+	table.keys[key] = newTOMLTable(tomlValue)
+
+	return nil
+}
+
+func (p *tomlParser) keyValue() error {
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+
+	if err = p.define(keys); err != nil {
+		return err
+	}
+
+	p.spaces()
+
+	if p.peek() != '=' {
+		return p.errorf("expected = after key")
+	}
+
+	p.pos++
+	p.spaces()
+
+	return p.value()
+}
+
+// key parses a dotted key of bare or quoted parts and returns the parts.
+func (p *tomlParser) key() ([]string, error) {
+	var keys []string
+
+	for {
+		start := p.pos
+
+		switch c := p.peek(); {
+		case c == '"':
+			if err := p.basicString(); err != nil {
+				return nil, err
+			}
+
+			keys = append(keys, unquoteKey(p.data[start:p.pos]))
+		case c == '\'':
+			if err := p.literalString(); err != nil {
+				return nil, err
+			}
+
+			keys = append(keys, p.data[start+1:p.pos-1])
+		default:
+			for c = p.peek(); isBareKey(c); c = p.peek() {
+				p.pos++
+			}
+
+			if p.pos == start {
+				return nil, p.errorf("invalid key")
+			}
+
+			keys = append(keys, p.data[start:p.pos])
+		}
+
+		p.spaces()
+
+		if p.peek() != '.' {
+			return keys, nil
+		}
+
+		p.pos++
+		p.spaces()
+	}
+}
+
+// unquoteKey returns the value of a basic string key. The escapes of TOML
+// are close enough to the escapes of Go to compare the keys.
+func unquoteKey(quoted string) string {
+	if key, err := strconv.Unquote(quoted); err == nil {
+		return key
+	}
+
+	return quoted[1 : len(quoted)-1]
+}
+
+func isBareKey(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *tomlParser) value() error {
+	rest := p.rest()
+
+	switch {
+	case strings.HasPrefix(rest, `"""`):
+		return p.multilineString(`"""`)
+	case strings.HasPrefix(rest, `'''`):
+		return p.multilineString(`'''`)
+	case strings.HasPrefix(rest, `"`):
+		return p.basicString()
+	case strings.HasPrefix(rest, `'`):
+		return p.literalString()
+	case strings.HasPrefix(rest, "["):
+		return p.array()
+	case strings.HasPrefix(rest, "{"):
+		return p.inlineTable()
+	case strings.HasPrefix(rest, "true"):
+		p.pos += len("true")
+	case strings.HasPrefix(rest, "false"):
+		p.pos += len("false")
+	default:
+		return p.scalar()
+	}
+
+	return nil
+}
+
+// scalar parses a number or a date-time.
+func (p *tomlParser) scalar() error {
+	end := strings.IndexAny(p.rest(), ",]}#\r\n")
+	if end < 0 {
+		end = len(p.rest())
+	}
+
+	token := strings.TrimRight(p.rest()[:end], " \t")
+	if len(token) == 0 {
+		return p.errorf("missing value")
+	}
+
+	if !reTOMLNumber.MatchString(token) && !reTOMLDateTime.MatchString(token) {
+		return p.errorf("invalid value %s", token)
+	}
+
+	p.pos += len(token)
+
+	return nil
+}
+
+func (p *tomlParser) basicString() error {
+	p.pos++
+
+	for !p.eof() {
+		switch c := p.peek(); c {
+		case '"':
+			p.pos++
+
+			return nil
+		case '\\':
+			if err := p.escape(); err != nil {
+				return err
+			}
+		case '\n':
+			return p.errorf("unterminated string")
+		default:
+			p.pos++
+		}
+	}
+
+	return p.errorf("unterminated string")
+}
+
+func (p *tomlParser) escape() error {
+	p.pos++
+
+	switch c := p.peek(); c {
+	case 'b', 't', 'n', 'f', 'r', 'e', '"', '\\':
+		p.pos++
+	case 'u', 'U':
+		size := 4
+		if c == 'U' {
+			size = 8
+		}
+
+		p.pos++
+
+		for i := 0; i < size; i++ {
+			if !isHex(p.peek()) {
+				return p.errorf("invalid unicode escape")
+			}
+
+			p.pos++
+		}
+	default:
+		return p.errorf("invalid escape sequence \\%c", c)
+	}
+
+	return nil
+}
+
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+func (p *tomlParser) literalString() error {
+	p.pos++
+
+	end := strings.IndexAny(p.rest(), "'\n")
+	if end < 0 || p.rest()[end] != '\'' {
+		return p.errorf("unterminated string")
+	}
+
+	p.pos += end + 1
+
+	return nil
+}
+
+func (p *tomlParser) multilineString(delim string) error {
+	start := p.line
+	p.pos += len(delim)
+
+	for !p.eof() {
+		if strings.HasPrefix(p.rest(), delim) {
+			p.pos += len(delim)
+
+			// Up to two quotes are allowed right before the delimiter.
+			for i := 0; i < 2 && p.peek() == delim[0]; i++ {
+				p.pos++
+			}
+
+			return nil
+		}
+
+		if delim == `"""` && p.peek() == '\\' {
+			if next := strings.TrimLeft(p.rest()[1:], " \t"); strings.HasPrefix(next, "\n") || strings.HasPrefix(next, "\r\n") {
+				p.pos++
+
+				continue
+			}
+
+			if err := p.escape(); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		p.advance(1)
+	}
+
+	p.line = start
+
+	return p.errorf("unterminated multi-line string")
+}
+
+func (p *tomlParser) array() error {
+	start := p.line
+	p.pos++
+
+	for {
+		p.blank()
+
+		if p.eof() {
+			p.line = start
+
+			return p.errorf("unterminated array")
+		}
+
+		if p.peek() == ']' {
+			p.pos++
+
+			return nil
+		}
+
+		if err := p.value(); err != nil {
+			return err
+		}
+
+		p.blank()
+
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+			p.pos++
+
+			return nil
+		default:
+			if p.eof() {
+				p.line = start
+
+				return p.errorf("unterminated array")
+			}
+
+			return p.errorf("expected , or ] in array")
+		}
+	}
+}
+
+func (p *tomlParser) inlineTable() error {
+	// The keys of the inline table are defined in a table of its own.
+	current := p.current
+	p.current = newTOMLTable(tomlValue)
+
+	defer func() {
+		p.current = current
+	}()
+
+	p.pos++
+	p.spaces()
+
+	if p.peek() == '}' {
+		p.pos++
+
+		return nil
+	}
+
+	for {
+		if err := p.keyValue(); err != nil {
+			return err
+		}
+
+		p.spaces()
+
+		switch p.peek() {
+		case ',':
+			p.pos++
+			p.spaces()
+		case '}':
+			p.pos++
+
+			return nil
+		default:
+			return p.errorf("expected , or } in inline table")
+		}
+	}
+}
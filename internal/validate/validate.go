@@ -0,0 +1,69 @@
+// Package validate checks the syntax of structured data (JSON, YAML, TOML and
+// XML).
+//
+// The TOML check is lightweight: it detects the common syntax errors and
+// redefinitions of hand-written documents, not every violation of the
+// specification.
+package validate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// Supported language names.
+const (
+	JSON = "json"
+	YAML = "yaml"
+	TOML = "toml"
+	XML  = "xml"
+)
+
+// Error is a syntax error at a line (starting at 1) of the checked data.
+type Error struct {
+	Line int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// Supported reports whether the syntax of the language can be checked.
+func Supported(lang string) bool {
+	switch lang {
+	case JSON, YAML, TOML, XML:
+		return true
+	default:
+		return false
+	}
+}
+
+// Check checks the syntax of data in the given language. Syntax errors are
+// returned as [*Error].
+func Check(lang string, data []byte) error {
+	switch lang {
+	case JSON:
+		return checkJSON(data)
+	case YAML:
+		return checkYAML(data)
+	case TOML:
+		return checkTOML(data)
+	case XML:
+		return checkXML(data)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupported, lang)
+	}
+}
+
+func lineAt(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+
+	return 1 + bytes.Count(data[:offset], []byte{'\n'})
+}
+
+// ErrUnsupported is returned by [Check] for languages without syntax check.
+var ErrUnsupported = errors.New("unsupported language")
@@ -0,0 +1,94 @@
+package validate_test
+
+import (
+	"testing"
+
+	"github.com/ezerfernandes/mdcode/internal/validate"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Check(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		lang string
+		data string
+		line int
+	}{
+		{name: "json", lang: validate.JSON, data: "{\n  \"a\": [1, 2],\n  \"b\": null\n}\n"},
+		{name: "json error", lang: validate.JSON, data: "{\n  \"a\": 1,\n  \"b\" 2\n}\n", line: 3},
+		{name: "json trailing", lang: validate.JSON, data: "{}\n{}\n", line: 2},
+		{name: "json eof", lang: validate.JSON, data: "{\n  \"a\": [1,\n", line: 3},
+		{name: "xml", lang: validate.XML, data: "<?xml version=\"1.0\"?>\n<a>\n  <b x=\"1\"/>\n</a>\n"},
+		{name: "xml error", lang: validate.XML, data: "<a>\n  <b>\n</a>\n", line: 3},
+		{name: "xml roots", lang: validate.XML, data: "<a/>\n<b/>\n", line: 2},
+		{
+			name: "toml", lang: validate.TOML,
+			data: "# config\ntitle = \"x\" # comment\n[server]\nport = 8080\nhosts = [\n  \"a\",\n  'b', # c\n]\n" +
+				"[[items]]\nname.first = { a = 1, b = true }\ndate = 1979-05-27T07:32:00Z\ntext = \"\"\"\nmulti\nline\"\"\"\n",
+		},
+		{name: "toml missing =", lang: validate.TOML, data: "a = 1\nb 2\n", line: 2},
+		{name: "toml string", lang: validate.TOML, data: "a = \"x\nb = 1\n", line: 1},
+		{name: "toml value", lang: validate.TOML, data: "a = 1\nb = yes\n", line: 2},
+		{name: "toml header", lang: validate.TOML, data: "[a\nb = 1\n", line: 1},
+		{name: "toml array", lang: validate.TOML, data: "a = [\n  1,\n  2\n", line: 1},
+		{name: "toml escape", lang: validate.TOML, data: "a = \"\\q\"\n", line: 1},
+		{name: "toml duplicate key", lang: validate.TOML, data: "a = 1\na = 2\n", line: 2},
+		{name: "toml duplicate quoted key", lang: validate.TOML, data: "a = 1\n\"a\" = 2\n", line: 2},
+		{name: "toml duplicate dotted key", lang: validate.TOML, data: "a.b = 1\na.b = 2\n", line: 2},
+		{name: "toml dotted key value", lang: validate.TOML, data: "a = 1\na.b = 2\n", line: 2},
+		{name: "toml duplicate table", lang: validate.TOML, data: "[t]\na = 1\n[t]\nb = 2\n", line: 3},
+		{name: "toml table key", lang: validate.TOML, data: "t = 1\n[t]\n", line: 2},
+		{name: "toml table duplicate key", lang: validate.TOML, data: "[t]\na = 1\n[u]\n[t.v]\n[t]\n", line: 5},
+		{name: "toml inline duplicate", lang: validate.TOML, data: "a = { b = 1, b = 2 }\n", line: 1},
+		{name: "toml inline table", lang: validate.TOML, data: "a = { b = 1 }\n[a]\n", line: 2},
+		{
+			name: "toml tables", lang: validate.TOML,
+			data: "a = 1\n[t]\na = 1\n[t.u]\na = 1\n[v]\nt.a = 1\n[[items]]\na = 1\n[[items]]\na = 1\n[items.sub]\na = 1\n" +
+				"[x.y]\na = 1\n[x]\nb = 1\n",
+		},
+		{
+			name: "yaml", lang: validate.YAML,
+			data: "---\nname: x # comment\nitems:\n  - a: 1\n    b: 'it''s'\n  - [1, 2,\n     3]\ntext: |\n  a: b: c\n  x\n" +
+				"url: http://example.com\nlist:\n- one\n- two\n",
+		},
+		{name: "yaml tab", lang: validate.YAML, data: "a:\n\tb: 1\n", line: 2},
+		{name: "yaml dedent", lang: validate.YAML, data: "a:\n    b: 1\n  c: 2\n", line: 2},
+		{name: "yaml mapping", lang: validate.YAML, data: "a: b: c\n", line: 1},
+		{name: "yaml quote", lang: validate.YAML, data: "a: 1\nb: \"x\nc: 2\n", line: 2},
+		{name: "yaml flow", lang: validate.YAML, data: "a: [1, 2}\n", line: 1},
+		{name: "yaml duplicate", lang: validate.YAML, data: "a: 1\nb: 2\na: 3\n", line: 3},
+		{name: "yaml nested duplicate", lang: validate.YAML, data: "a:\n  b: 1\n  b: 2\n", line: 3},
+		{name: "yaml indented key", lang: validate.YAML, data: "a: 1\n b: 2\n", line: 2},
+		{name: "yaml documents", lang: validate.YAML, data: "a: 1\n---\na: 2\n"},
+		{name: "yaml second document", lang: validate.YAML, data: "a: 1\n---\na: 2\na: 3\n", line: 4},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validate.Check(test.lang, []byte(test.data))
+			if test.line == 0 {
+				require.NoError(t, err)
+
+				return
+			}
+
+			var verr *validate.Error
+
+			require.ErrorAs(t, err, &verr)
+			require.Equal(t, test.line, verr.Line, verr.Msg)
+		})
+	}
+}
+
+func Test_Check_unsupported(t *testing.T) {
+	t.Parallel()
+
+	require.False(t, validate.Supported("go"))
+	require.ErrorIs(t, validate.Check("go", nil), validate.ErrUnsupported)
+}
@@ -0,0 +1,59 @@
+package validate
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+func checkXML(data []byte) error {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = true
+	dec.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) { return input, nil }
+
+	roots := 0
+	depth := 0
+
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			var syntaxErr *xml.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				return &Error{Line: syntaxErr.Line, Msg: syntaxErr.Msg}
+			}
+
+			return &Error{Line: lineAt(data, dec.InputOffset()), Msg: err.Error()}
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				roots++
+			}
+
+			if roots > 1 {
+				return &Error{Line: lineAt(data, dec.InputOffset()), Msg: "multiple root elements"}
+			}
+
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			if depth == 0 && len(strings.TrimSpace(string(tok))) != 0 {
+				return &Error{Line: lineAt(data, dec.InputOffset()), Msg: "text outside of the root element"}
+			}
+		}
+	}
+
+	if roots == 0 {
+		return &Error{Line: 1, Msg: "missing root element"}
+	}
+
+	return nil
+}
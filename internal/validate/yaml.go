@@ -0,0 +1,55 @@
+package validate
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+var reYAMLError = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// checkYAML decodes every document of the YAML stream. Besides the syntax
+// errors, duplicate mapping keys are rejected.
+func checkYAML(data []byte) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+
+	for {
+		var value any
+
+		err := decoder.Decode(&value)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return yamlError(err)
+		}
+	}
+}
+
+// yamlError converts the first error reported by the YAML decoder to an
+// [*Error]. The errors without line are reported at the first line.
+func yamlError(err error) error {
+	msg := err.Error()
+
+	var terr *yaml.TypeError
+	if errors.As(err, &terr) && len(terr.Errors) != 0 {
+		msg = terr.Errors[0]
+	}
+
+	match := reYAMLError.FindStringSubmatch(msg)
+	if match == nil {
+		return &Error{Line: 1, Msg: msg}
+	}
+
+	line, convErr := strconv.Atoi(match[1])
+	if convErr != nil {
+		return &Error{Line: 1, Msg: msg}
+	}
+
+	return &Error{Line: line, Msg: match[2]}
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/ezerfernandes/mdcode/internal/cmd"
 	"github.com/ezerfernandes/mdcode/internal/region"
+	"github.com/spf13/cobra"
 	"github.com/spf13/cobra/doc"
 )
 
@@ -33,6 +34,23 @@ func fprintf(out io.Writer, format string, args ...any) {
 	checkerr(err)
 }
 
+func isTopic(cmd *cobra.Command) bool {
+	return !cmd.Runnable() && !cmd.HasSubCommands()
+}
+
+func genCommands(out io.Writer, parent *cobra.Command) {
+	for _, cmd := range parent.Commands() {
+		if strings.HasPrefix(cmd.Use, "help") || isTopic(cmd) {
+			continue
+		}
+
+		fprintf(out, "---\n")
+		checkerr(doc.GenMarkdownCustom(cmd, out, linkHandler))
+
+		genCommands(out, cmd)
+	}
+}
+
 func main() {
 	if len(os.Args) != 2 { //nolint:gomnd
 		fmt.Fprint(os.Stderr, "usage: gendoc filename")
@@ -48,7 +66,7 @@ func main() {
 	regions := map[string]string{}
 
 	for _, cmd := range root.Commands() {
-		if cmd.Runnable() {
+		if !isTopic(cmd) {
 			continue
 		}
 
@@ -62,14 +80,7 @@ func main() {
 
 	checkerr(doc.GenMarkdownCustom(root, &buff, linkHandler))
 
-	for _, cmd := range root.Commands() {
-		if strings.HasPrefix(cmd.Use, "help") || !cmd.Runnable() {
-			continue
-		}
-
-		fprintf(&buff, "---\n")
-		checkerr(doc.GenMarkdownCustom(cmd, &buff, linkHandler))
-	}
+	genCommands(&buff, root)
 
 	cli := buff.String()
 